@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"path/filepath"
+
 	"github.com/informaticon/dev.win.base.pbmanager/internal/backport"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +25,21 @@ var backportCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return backport.ConvertProjectToTarget(absoluteProjPath, verbose)
+		pbProj, err := backport.NewProject(absoluteProjPath)
+		if err != nil {
+			return err
+		}
+		profileName, _ := cmd.Flags().GetString("profile")
+		orderFrom, _ := cmd.Flags().GetString("order-from")
+		libraryOrder, err := getLibraryOrder(profileName, orderFrom, pbProj.Application.Name)
+		if err != nil {
+			return err
+		}
+		snapshotFile, err := takeSnapshot("backport", filepath.Dir(absoluteProjPath), snapshot.Path{Name: filepath.Dir(absoluteProjPath)})
+		if err != nil {
+			return err
+		}
+		return restoreHint(backport.ConvertProjectToTarget(absoluteProjPath, libraryOrder, verbose), snapshotFile)
 	},
 }
 
@@ -31,5 +48,7 @@ var verbose bool
 
 func init() {
 	rootCmd.AddCommand(backportCmd)
+	backportCmd.Flags().String("order-from", "", "take the library order from the library list of this target instead of the profile")
+	backportCmd.Flags().String("profile", "", "application profile defining the library ordering (see profile). If omitted, the profile of the application is used.")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 }
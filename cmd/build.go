@@ -3,9 +3,13 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/buildlog"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/spf13/cobra"
@@ -15,8 +19,14 @@ import (
 var buildCmd = &cobra.Command{
 	Use:   "build [options] <pbt path>",
 	Short: "Builds a PowerBuilder target",
-	Args:  cobra.ExactArgs(1),
+	Long: `Builds a PowerBuilder target with ORCA.
+The compiler messages can be written as JSON, JUnit XML or SARIF (see --report-format), so a CI can annotate the failing lines.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		err := checkReportFormat(false)
+		if err != nil {
+			return err
+		}
 		pbtFilePath := args[0]
 		if !filepath.IsAbs(pbtFilePath) {
 			pbtFilePath = filepath.Join(basePath, pbtFilePath)
@@ -41,17 +51,78 @@ var buildCmd = &cobra.Command{
 			return err
 		}
 		logs, err := Orca.FullBuildTarget(pbtFilePath)
+		reportVars.messages = buildlog.Parse(logs)
+		result.Data = reportVars.messages
 		if len(logs) > 0 {
-			log.Printf("Compiler Log:\n%v\n", logs)
+			log.Printf("Compiler Log:\n%s\n", strings.Join(logs, "\n"))
+		}
+		if rerr := writeReport(filepath.Base(pbtFilePath)); rerr != nil {
+			printWarn(fmt.Sprintf("could not write compiler report: %v", rerr))
 		}
 		if err != nil {
 			return err
 		}
-		fmt.Println("Build done")
+		if reportVars.format == "" || reportVars.file != "" {
+			// the report is the only output on stdout
+			fmt.Println("Build done")
+		}
 		return nil
 	},
 }
 
 func init() {
+	addReportFlags(buildCmd)
 	rootCmd.AddCommand(buildCmd)
 }
+
+// reportVars are the flags of the compiler report of build, import and upgrade and the collected messages.
+var reportVars struct {
+	format   string
+	file     string
+	messages []buildlog.Message
+}
+
+// addReportFlags adds the flags to write the compiler messages of a command.
+func addReportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportVars.format, "report-format", "", "write the compiler messages as [json|junit|sarif]")
+	cmd.Flags().StringVar(&reportVars.file, "report-file", "", "file to write the compiler messages to, default stdout")
+}
+
+// checkReportFormat checks --report-format. Commands which print their progress to stdout set needsFile, their
+// report would be mixed with the progress without --report-file.
+func checkReportFormat(needsFile bool) error {
+	if reportVars.format != "" && !slices.Contains(buildlog.Formats, reportVars.format) {
+		return fmt.Errorf("invalid report format %s, use one of %s", reportVars.format, strings.Join(buildlog.Formats, ", "))
+	}
+	if needsFile && reportVars.format != "" && reportVars.file == "" {
+		return fmt.Errorf("--report-format needs --report-file for this command, stdout is used by the progress output")
+	}
+	return nil
+}
+
+// writeReport writes the collected compiler messages in the format of --report-format, if set. name is the name
+// of the build, e.g. the target.
+func writeReport(name string) error {
+	if reportVars.format == "" {
+		return nil
+	}
+	if reportVars.file == "" {
+		return buildlog.Write(os.Stdout, reportVars.format, name, Version, reportVars.messages)
+	}
+	file := reportVars.file
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(basePath, file)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = buildlog.Write(f, reportVars.format, name, Version, reportVars.messages)
+	if err != nil {
+		return err
+	}
+	errors, warnings := buildlog.Count(reportVars.messages)
+	fmt.Printf("compiler report with %d errors and %d warnings written to %s\n", errors, warnings, file)
+	return nil
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/deps"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/profile"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/sarif"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Checks the sources for architecture violations",
+}
+
+// checkLayersCmd represents the check layers command
+var checkLayersCmd = &cobra.Command{
+	Use:   "layers <pbt/pbl path or source folder>",
+	Short: "Reports references from lower layers to higher layers",
+	Long: `The library order of the profile (or of the library list of another target, see --order-from) defines the layers
+of the libraries: the first group is the highest layer (e.g. the application packages), the last group the lowest one
+(e.g. lif). Objects may only reference objects of the same or a lower layer.
+check layers analyses the dependencies of all objects (see deps) and reports every reference to an object of a higher
+layer with file and line. Libraries which are not part of the library order are not checked.
+The command fails if there are violations, so it can be used to block commits in CI.
+Examples:
+	- pbmanager check layers C:/a3/lib/a3.pbt
+	- pbmanager check layers --profile a3 --format sarif ./src > layers.sarif`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		profileName, _ := cmd.Flags().GetString("profile")
+		orderFrom, _ := cmd.Flags().GetString("order-from")
+		if format != "text" && format != "sarif" {
+			return fmt.Errorf("invalid format %s, use text or sarif", format)
+		}
+		srcPath := args[0]
+		if !filepath.IsAbs(srcPath) {
+			srcPath = filepath.Join(basePath, srcPath)
+		}
+
+		var appName string
+		if filepath.Ext(srcPath) == ".pbt" {
+			pbtData, err := orca.NewPbtFromFile(srcPath)
+			if err != nil {
+				return err
+			}
+			appName = pbtData.AppName
+		}
+		order, err := getLibraryOrder(profileName, orderFrom, appName)
+		if err != nil {
+			return err
+		}
+		if len(order) == 0 {
+			return fmt.Errorf("no library order found, use --profile or --order-from to define the layers")
+		}
+
+		sources, err := readDepsSources(srcPath)
+		if err != nil {
+			return err
+		}
+		// stdout may be redirected into a SARIF file
+		graph := deps.Build(sources, printWarnStderr)
+		violations := graph.LayerViolations(func(library string) int {
+			return profile.Layer(order, library)
+		})
+
+		if jsonOutput() {
+			records := []layerViolationRecord{}
+			for _, v := range violations {
+				records = append(records, layerViolationRecord{
+					File: layerViolationFile(v), Line: v.Line, Object: v.Object.Name, Library: v.Object.Library,
+					Target: v.Target.Name, TargetLibrary: v.Target.Library, Kind: v.Dependency.Kind,
+				})
+			}
+			result.Data = records
+		} else if format == "sarif" {
+			log := sarif.New("pbmanager", Version, sarif.Rule{
+				ID:               "layer-violation",
+				ShortDescription: sarif.Message{Text: "Reference from a lower layer to a higher layer"},
+			})
+			for _, v := range violations {
+				log.Add("layer-violation", sarif.LevelError, layerViolationMessage(v), layerViolationFile(v), v.Line)
+			}
+			err = log.Write(os.Stdout)
+			if err != nil {
+				return err
+			}
+		} else {
+			for _, v := range violations {
+				fmt.Printf("%s:%d: %s\n", layerViolationFile(v), v.Line, layerViolationMessage(v))
+			}
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("found %d layer violations", len(violations))
+		}
+		if format == "text" {
+			fmt.Println("No layer violations found")
+		}
+		return nil
+	},
+}
+
+func init() {
+	checkLayersCmd.Flags().String("format", "text", "output format, one of [text|sarif]")
+	checkLayersCmd.Flags().String("profile", "", "profile defining the layers (library order), default is the profile of the application")
+	checkLayersCmd.Flags().String("order-from", "", "take the layers from the library list of this pbt instead of the profile")
+	checkCmd.AddCommand(checkLayersCmd)
+	rootCmd.AddCommand(checkCmd)
+}
+
+// layerViolationRecord is a layer violation in the result of --output json.
+type layerViolationRecord struct {
+	File          string `json:"file"`
+	Line          int    `json:"line"`
+	Object        string `json:"object"`
+	Library       string `json:"library"`
+	Target        string `json:"target"`
+	TargetLibrary string `json:"targetLibrary"`
+	Kind          string `json:"kind"`
+}
+
+// layerViolationFile returns the source file of the violating object, library/entry for sources read from a library.
+func layerViolationFile(v deps.LayerViolation) string {
+	if v.Object.Path != "" {
+		return v.Object.Path
+	}
+	return filepath.Join(v.Object.Library, v.Object.Entry)
+}
+
+func layerViolationMessage(v deps.LayerViolation) string {
+	return fmt.Sprintf("%s (%s) references %s of the higher layer %s (%s)",
+		v.Object.Name, v.Object.Library, v.Target.Name, v.Target.Library, v.Dependency.Kind)
+}
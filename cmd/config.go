@@ -3,6 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/windows/registry"
@@ -11,6 +14,7 @@ import (
 var (
 	registerSvnDiff  bool
 	registerSvnMerge bool
+	registerGit      bool
 )
 
 // configCmd represents the build command
@@ -36,6 +40,12 @@ var configCmd = &cobra.Command{
 				return err
 			}
 		}
+		if registerGit {
+			err := installGitDrivers(exePath)
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	},
 }
@@ -43,6 +53,7 @@ var configCmd = &cobra.Command{
 func init() {
 	configCmd.Flags().BoolVarP(&registerSvnDiff, "register-svn-diff", "d", false, "Register pbmanager as Diff tool for PBL files in TortoiseSVN")
 	configCmd.Flags().BoolVarP(&registerSvnMerge, "register-svn-merge", "m", false, "Register pbmanager as Merge tool for PBL files in TortoiseSVN")
+	configCmd.Flags().BoolVarP(&registerGit, "register-git", "g", false, "Register pbmanager as diff (textconv) and merge driver for PBL files in the git repository of the base path")
 	rootCmd.AddCommand(configCmd)
 }
 
@@ -60,3 +71,56 @@ func installDiffMerge(parentKey, commandLine string) error {
 	}
 	return nil
 }
+
+// gitAttributes are the lines written to .gitattributes by installGitDrivers.
+var gitAttributes = []string{"*.pbl -text diff=pbl merge=pbl"}
+
+// installGitDrivers registers the textconv filter and the merge driver for pbl files in the git repository
+// containing basePath and adds the pbl files to the .gitattributes of the repository.
+func installGitDrivers(exePath string) error {
+	out, err := exec.Command("git", "-C", basePath, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return fmt.Errorf("%s is not inside a git repository: %v", basePath, err)
+	}
+	repoPath := filepath.FromSlash(strings.TrimSpace(string(out)))
+
+	exe := `"` + filepath.ToSlash(exePath) + `"`
+	configs := [][2]string{
+		{"diff.pbl.textconv", exe + " textconv"},
+		{"diff.pbl.cachetextconv", "true"},
+		{"merge.pbl.name", "PowerBuilder library merge (pbmanager)"},
+		{"merge.pbl.driver", exe + " diff %O %A %B --path %P --base-name base --mine-name ours --theirs-name theirs"},
+	}
+	for _, c := range configs {
+		out, err := exec.Command("git", "-C", repoPath, "config", c[0], c[1]).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("could not set git config %s: %v (%s)", c[0], err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	attrFile := filepath.Join(repoPath, ".gitattributes")
+	data, err := os.ReadFile(attrFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := string(data)
+	for _, attr := range gitAttributes {
+		if strings.Contains(content, attr) {
+			continue
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += attr + "\n"
+	}
+	if content == string(data) {
+		return nil
+	}
+	err = os.WriteFile(attrFile, []byte(content), 0o664)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %v", attrFile, err)
+	}
+	fmt.Printf("Updated %s, do not forget to commit it\n", attrFile)
+	addResultObject("updated", "", attrFile)
+	return nil
+}
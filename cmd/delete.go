@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/spf13/cobra"
@@ -63,7 +65,11 @@ var deleteCmd = &cobra.Command{
 		}
 		defer Orca.Close()
 
-		return deletePbl(Orca, pblFilePath, objRegex)
+		snapshotFile, err := takeSnapshot("delete", filepath.Dir(pblFilePath), snapshot.Path{Name: pblFilePath})
+		if err != nil {
+			return err
+		}
+		return restoreHint(deletePbl(Orca, pblFilePath, objRegex), snapshotFile)
 	},
 }
 
@@ -76,7 +82,7 @@ func init() {
 	deleteCmd.PersistentFlags().BoolVarP(&ignoreMissing, "ignore-missing", "i", true, "do not fail if object does not exist")
 }
 
-func deletePbl(Orca *pborca.Orca, pblFilePath string, objRegex *regexp.Regexp) error {
+func deletePbl(Orca backend.Backend, pblFilePath string, objRegex *regexp.Regexp) error {
 	if ignoreMissing {
 		// outDir = filepath.Join(outDir, filepath.Base(pblFilePath))
 	}
@@ -100,6 +106,7 @@ func deletePbl(Orca *pborca.Orca, pblFilePath string, objRegex *regexp.Regexp) e
 			}
 			deletedCount++
 			fmt.Printf("deleted %s\n", objName)
+			addResultObject("deleted", filepath.Base(pblFilePath), objName)
 		}
 	}
 	if deletedCount == 0 && !ignoreMissing {
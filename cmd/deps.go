@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/deps"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+)
+
+// depsCmd represents the deps command
+var depsCmd = &cobra.Command{
+	Use:   "deps <pbt/pbl path or source folder>",
+	Short: "Shows the dependencies between objects",
+	Long: `Analyses the sources of a target, a library or a folder of exported sources and shows which objects depend on each other.
+Dependencies are ancestors, types of variables, create statements, function and event calls on typed references,
+calls of global functions and DataObjects of DataWindow controls and reports.
+The libraries are read directly, ORCA is not needed. In a source folder, the name of the folder containing a
+source file is used as library name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		objName, _ := cmd.Flags().GetString("object")
+		cycles, _ := cmd.Flags().GetBool("cycles")
+		if format != "text" && format != "json" && format != "dot" {
+			return fmt.Errorf("invalid format %s, use text, json or dot", format)
+		}
+		if format == "text" && jsonOutput() {
+			format = "json"
+		}
+		if format == "dot" && (objName != "" || cycles) {
+			return fmt.Errorf("format dot can not be combined with --object or --cycles")
+		}
+		srcPath := args[0]
+		if !filepath.IsAbs(srcPath) {
+			srcPath = filepath.Join(basePath, srcPath)
+		}
+		sources, err := readDepsSources(srcPath)
+		if err != nil {
+			return err
+		}
+		// stdout may be piped into another tool (e.g. dot)
+		graph := deps.Build(sources, printWarnStderr)
+
+		switch {
+		case objName != "":
+			return printObjectDeps(graph, objName, format)
+		case cycles:
+			return printLibraryCycles(graph, format)
+		case format == "dot":
+			fmt.Print(graph.Dot())
+		case format == "json":
+			return printJSON(graph.Objects)
+		default:
+			for _, obj := range graph.Objects {
+				fmt.Printf("%s (%s)\n", obj.Name, obj.Library)
+				for _, dep := range obj.Deps {
+					fmt.Printf("\t-> %s (%s)\n", dep.Target, dep.Kind)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	depsCmd.Flags().String("format", "text", "output format, one of [text|json|dot]")
+	depsCmd.Flags().String("object", "", "only show the dependencies and dependants of this object")
+	depsCmd.Flags().Bool("cycles", false, "show the libraries depending on each other, fails if there are any")
+	rootCmd.AddCommand(depsCmd)
+}
+
+// printJSON prints v as JSON. With --output json, v becomes the data of the result document instead.
+func printJSON(v any) error {
+	if jsonOutput() {
+		result.Data = v
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printObjectDeps(graph *deps.Graph, objName, format string) error {
+	obj := graph.Object(objName)
+	if obj == nil {
+		return fmt.Errorf("object %s not found", objName)
+	}
+	dependants := graph.Dependants(obj.Name)
+	if format == "json" {
+		return printJSON(struct {
+			*deps.Object
+			Dependants []*deps.Object `json:"dependants"`
+		}{obj, dependants})
+	}
+	fmt.Printf("%s (%s)\n", obj.Name, obj.Library)
+	fmt.Println("Dependencies:")
+	for _, dep := range obj.Deps {
+		fmt.Printf("\t%s (%s, %s)\n", dep.Target, graph.Object(dep.Target).Library, dep.Kind)
+	}
+	fmt.Println("Dependants:")
+	for _, d := range dependants {
+		var kinds []string
+		for _, dep := range d.Deps {
+			if strings.EqualFold(dep.Target, obj.Name) {
+				kinds = append(kinds, dep.Kind)
+			}
+		}
+		fmt.Printf("\t%s (%s, %s)\n", d.Name, d.Library, strings.Join(kinds, ", "))
+	}
+	return nil
+}
+
+func printLibraryCycles(graph *deps.Graph, format string) error {
+	cycles := graph.LibraryCycles()
+	if format == "json" {
+		err := printJSON(cycles)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, c := range cycles {
+			fmt.Printf("Cycle between %s:\n", strings.Join(c.Libraries, ", "))
+			for _, edge := range c.Edges {
+				fmt.Printf("\t%s\n", edge)
+			}
+		}
+	}
+	if len(cycles) > 0 {
+		return fmt.Errorf("found %d cycles between libraries", len(cycles))
+	}
+	if format != "json" {
+		fmt.Println("No cycles between libraries found")
+	}
+	return nil
+}
+
+var regexSrcExt = regexp.MustCompile(`(?i)^\.sr[adfjmpqsuwx]$`)
+
+// readDepsSources reads the sources of all libraries of a pbt (in the order of the library list), of a single pbl
+// or of all source files within a folder.
+func readDepsSources(srcPath string) ([]deps.Source, error) {
+	if isDir(srcPath) {
+		var sources []deps.Source
+		err := filepath.WalkDir(srcPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !regexSrcExt.MatchString(filepath.Ext(path)) {
+				return err
+			}
+			src, err := utils.ReadPbSource(path)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, deps.Source{Library: filepath.Base(filepath.Dir(path)), Name: filepath.Base(path), Src: string(src), Path: path})
+			return nil
+		})
+		return sources, err
+	}
+
+	pblFiles := []string{srcPath}
+	switch filepath.Ext(srcPath) {
+	case ".pbt":
+		pbt, err := orca.NewPbtFromFile(srcPath)
+		if err != nil {
+			return nil, err
+		}
+		pblFiles = pbt.LibList
+	case ".pbl":
+	default:
+		return nil, fmt.Errorf("%s is not a pbt file, a pbl file or a folder", srcPath)
+	}
+	var sources []deps.Source
+	for _, pblFile := range pblFiles {
+		lib, err := pbl.Open(pblFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range lib.SourceEntries() {
+			src, err := lib.Source(entry.Name)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, deps.Source{Library: filepath.Base(pblFile), Name: entry.Name, Src: src})
+		}
+	}
+	return sources, nil
+}
@@ -7,10 +7,15 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pblmerge"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/textdiff"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/informaticon/lib.go.base.pborca/orca"
@@ -19,27 +24,45 @@ import (
 
 var (
 	mergeTool  string
+	diffFormat string
+	diffPath   string
 	nameBase   string
 	nameMine   string
 	nameTheirs string
 )
 
-type exportJob struct {
-	libraryPath     string
-	destinationPath string
-}
-
 // https://tortoisesvn.net/docs/release/TortoiseSVN_en/tsvn-dug-settings.html
 
 // diffCmd represents the diff command
 var diffCmd = &cobra.Command{
 	Use:   "diff <pbl base> <pbl mine> [<pbl theirs>] [<pbl merged>]",
-	Short: "Compares two or three pbl files",
-	Long:  `Export the source of two pbl files and opens WinMerge to show the differences.`,
-	Args:  cobra.RangeArgs(2, 4),
+	Short: "Compares two or merges three pbl files",
+	Long: `Export the source of two pbl files and opens WinMerge to show the differences.
+If three pbl files are given, the changes from base to theirs are merged into mine and the result is imported
+into the merged pbl. Objects with conflicts are written to <merged pbl>.conflicts.
+A fifth argument (the file name passed by TortoiseSVN) is ignored.`,
+	Args: cobra.RangeArgs(2, 5),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 
+		if diffFormat != "" {
+			if len(args) != 2 {
+				return fmt.Errorf("--format can only be used to compare two files")
+			}
+			if diffFormat != "unified" && diffFormat != "json" && diffFormat != "stat" {
+				return fmt.Errorf("invalid format %s, use unified, json or stat", diffFormat)
+			}
+			pblFilePathBase, err := getCleanPblPbtFilePath(basePath, args[0])
+			if err != nil {
+				return err
+			}
+			pblFilePathMine, err := getCleanPblPbtFilePath(basePath, args[1])
+			if err != nil {
+				return err
+			}
+			return diffText(pblFilePathBase, pblFilePathMine, diffFormat)
+		}
+
 		if orcaVars.pbVersion != 22 {
 			return fmt.Errorf("currently, only PowerBuilder 22 is supported")
 		}
@@ -58,11 +81,6 @@ var diffCmd = &cobra.Command{
 		if orcaVars.serverAddr != "" {
 			opts = append(opts, pborca.WithOrcaServer(orcaVars.serverAddr, orcaVars.serverApiKey))
 		}
-		Orca, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
-		if err != nil {
-			return err
-		}
-		defer Orca.Close()
 
 		pblFilePathBase, err := getCleanPblPbtFilePath(basePath, args[0])
 		if err != nil {
@@ -73,7 +91,7 @@ var diffCmd = &cobra.Command{
 			return err
 		}
 		if len(args) == 2 {
-			err = diff(pblFilePathBase, pblFilePathMine)
+			err = diff(newOrcaPool(orcaVars.pbVersion, opts...), pblFilePathBase, pblFilePathMine)
 			if err != nil {
 				fmt.Println(err)
 			}
@@ -92,12 +110,17 @@ var diffCmd = &cobra.Command{
 				}
 			}
 
+			Orca, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
+			if err != nil {
+				return err
+			}
+			defer Orca.Close()
 			return merge(Orca, pblFilePathBase, pblFilePathMine, pblFilePathTheirs, pblFilePathMerged)
 		}
 	},
 }
 
-func diff(objFilePathBase, objFilePathMine string) error {
+func diff(sessions *orcapool.Pool[backend.Backend], objFilePathBase, objFilePathMine string) error {
 	tempDir := filepath.Join(os.TempDir(), "pbdiff", time.Now().Format("20060102_150405"))
 	os.MkdirAll(tempDir, 0o664)
 	defer os.RemoveAll(tempDir)
@@ -106,87 +129,58 @@ func diff(objFilePathBase, objFilePathMine string) error {
 	objSrcPathMine := filepath.Join(tempDir, fmt.Sprintf("%s (%s)", filepath.Base(objFilePathMine), getPblFileDescr(objFilePathMine)))
 	os.MkdirAll(objSrcPathMine, 0o664)
 
-	c := make(chan exportJob)
-
+	// libraries to export, with the folders to export them to
+	destinations := make(map[string][]string)
+	var libraries []string
+	addLibrary := func(lib, dest string) {
+		if destinations[lib] == nil {
+			libraries = append(libraries, lib)
+		}
+		destinations[lib] = append(destinations[lib], dest)
+	}
 	if filepath.Ext(objFilePathBase) == ".pbt" {
-		// producer for pbt
-		go func() {
-			pbt, _ := orca.NewPbtFromFile(objFilePathBase)
-			for _, lib := range pbt.LibList {
-				var job exportJob
-				job.libraryPath = lib
-				job.destinationPath = objSrcPathBase
-				c <- job
+		for _, p := range []struct{ pbt, dest string }{{objFilePathBase, objSrcPathBase}, {objFilePathMine, objSrcPathMine}} {
+			pbt, err := orca.NewPbtFromFile(p.pbt)
+			if err != nil {
+				return err
 			}
-			pbt, _ = orca.NewPbtFromFile(objFilePathMine)
 			for _, lib := range pbt.LibList {
-				var job exportJob
-				job.libraryPath = lib
-				job.destinationPath = objSrcPathMine
-				c <- job
+				addLibrary(lib, p.dest)
 			}
-			close(c)
-		}()
+		}
 	} else if filepath.Ext(objFilePathBase) == ".pbl" {
-		// producer for pbl
-		go func() {
-			var job exportJob
-			job.libraryPath = objFilePathBase
-			job.destinationPath = objSrcPathBase
-			c <- job
-
-			job.libraryPath = objFilePathMine
-			job.destinationPath = objSrcPathMine
-			c <- job
-
-			close(c)
-		}()
+		addLibrary(objFilePathBase, objSrcPathBase)
+		addLibrary(objFilePathMine, objSrcPathMine)
 	}
 
-	// consumer
-	numOfConsumers := 4
-	var wg1 sync.WaitGroup
-	for i := 1; i <= numOfConsumers; i++ {
-		wg1.Add(1)
-		go func() {
-			defer wg1.Done()
-			var opts []func(*pborca.Orca)
-			opts = append(opts, pborca.WithOrcaTimeout(time.Duration(orcaVars.timeoutSeconds)*time.Second))
-			opts = append(opts, pborca.WithMessageCallback(func(level uint32, msg string) {
-				log.Printf("%d: %s\n", level, msg)
-			}))
-			if orcaVars.serverAddr != "" {
-				opts = append(opts, pborca.WithOrcaServer(orcaVars.serverAddr, orcaVars.serverApiKey))
-			}
-			Orca, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
+	err := sessions.Run(libraries, func(Orca backend.Backend, lib string) error {
+		for _, dest := range destinations[lib] {
+			fmt.Println("Exporting ", lib, " to ", dest)
+			err := exportPbl(Orca, lib, regexp.MustCompile("^.*$"), dest, "utf8")
 			if err != nil {
-				panic(err)
-			}
-
-			for job := range c {
-				fmt.Println("Exporting ", job.libraryPath, " to ", job.destinationPath)
-				err := exportPbl(Orca, job.libraryPath, regexp.MustCompile("^.*$"), job.destinationPath, "utf8")
-				if err != nil {
-					fmt.Println(err)
-				}
+				return err
 			}
-			Orca.Close()
-		}()
+		}
+		return nil
+	})
+	if err != nil {
+		// show the differences of the exported libraries anyway
+		fmt.Println(err)
 	}
 
-	wg1.Wait()
-
 	var cmd *exec.Cmd
-
 	if filepath.Ext(objFilePathBase) == ".pbt" {
-		cmd = getDiffCommand(objSrcPathMine, objSrcPathBase, nameMine, nameBase)
-	} else if filepath.Ext(objFilePathBase) == ".pbl" {
-		cmd = getDiffCommand(
+		cmd, err = getDiffCommand(objSrcPathMine, objSrcPathBase, nameMine, nameBase)
+	} else {
+		cmd, err = getDiffCommand(
 			filepath.Join(objSrcPathMine, filepath.Base(objFilePathMine)),
 			filepath.Join(objSrcPathBase, filepath.Base(objFilePathBase)),
 			nameMine, nameBase,
 		)
 	}
+	if err != nil {
+		return err
+	}
 	out, err := cmd.CombinedOutput()
 	if len(out) > 0 {
 		fmt.Println(string(out))
@@ -197,88 +191,207 @@ func diff(objFilePathBase, objFilePathMine string) error {
 	return nil
 }
 
-func merge(Orca *pborca.Orca, pblFilePathBase, pblFilePathMine, pblFilePathTheirs, pblFilePathMerged string) error {
-	return fmt.Errorf("merging is not yet implemented")
-	/*
-		tempDir := filepath.Join(os.TempDir(), "pbdiff", time.Now().Format("20170907_170606"))
-		os.MkdirAll(tempDir, 0664)
-		defer os.RemoveAll(tempDir)
+// merge merges the changes from base to theirs into mine (see pblmerge.Merge). The result is imported into the
+// merged pbl (or into mine, if no merged pbl is given). Objects which could not be merged are written with conflict
+// markers to a folder next to the merged pbl and are not imported.
+func merge(Orca backend.Backend, pblFilePathBase, pblFilePathMine, pblFilePathTheirs, pblFilePathMerged string) error {
+	for _, p := range []string{pblFilePathBase, pblFilePathMine, pblFilePathTheirs, pblFilePathMerged} {
+		if filepath.Ext(p) == ".pbt" {
+			return fmt.Errorf("merging is only supported for pbl files, not for targets (%s)", p)
+		}
+	}
+	files := pblmerge.Files{Base: pblFilePathBase, Mine: pblFilePathMine, Theirs: pblFilePathTheirs, Merged: pblFilePathMerged}
+	library := pblFilePathMerged
+	if library == "" {
+		library = pblFilePathMine
+	}
+	// the files may be temp files (e.g. of a git merge driver), the target belongs to the library in the working copy
+	if diffPath != "" {
+		files.WorkingCopy = diffPath
+		if !filepath.IsAbs(files.WorkingCopy) {
+			files.WorkingCopy = filepath.Join(basePath, files.WorkingCopy)
+		}
+		library = files.WorkingCopy
+	}
+	pbtFilePath, err := findPbtFilePath(filepath.Dir(library), "")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Merging into %s with target %s\n", library, pbtFilePath)
 
-		pblSrcPathBase := filepath.Join(tempDir, fmt.Sprintf("%s (%s)", filepath.Base(pblFilePathBase), getPblFileDescr(pblFilePathBase)))
-		os.MkdirAll(pblSrcPathBase, 0664)
-		err := exportPbl(Orca, pblFilePathBase, regexp.MustCompile("^.*$"), pblSrcPathBase)
-		if err != nil {
-			return err
+	res, err := pblmerge.Merge(Orca, pbtFilePath, files, textdiff.Labels{Base: nameBase, Mine: nameMine, Theirs: nameTheirs})
+	if err != nil {
+		return err
+	}
+	for _, name := range res.Deleted {
+		fmt.Printf("Deleted %s\n", name)
+		addResultObject("deleted", filepath.Base(res.Library), name)
+	}
+	for _, name := range res.Imported {
+		fmt.Printf("Successfully imported %s\n", name)
+		addResultObject("imported", filepath.Base(res.Library), name)
+	}
+	for _, err := range res.Errs {
+		fmt.Println(err)
+	}
+	if len(res.Conflicts) > 0 {
+		fmt.Printf("%d objects could not be merged, their sources are written to %s:\n", len(res.Conflicts), res.ConflictDir)
+		for _, c := range res.Conflicts {
+			fmt.Printf("\t%s: %s\n", c.Name, c.Reason)
+			addResultObject("conflict", filepath.Base(res.Library), c.Name)
 		}
-		pblSrcPathMine := filepath.Join(tempDir, fmt.Sprintf("%s (%s)", filepath.Base(pblFilePathMine), getPblFileDescr(pblFilePathMine)))
-		os.MkdirAll(pblSrcPathMine, 0664)
-		err = exportPbl(Orca, pblFilePathMine, regexp.MustCompile("^.*$"), pblSrcPathMine)
-		if err != nil {
-			return err
+	}
+
+	if len(res.Errs) > 0 || len(res.Conflicts) > 0 {
+		return fmt.Errorf("merge finished with %d conflicts and %d failed imports", len(res.Conflicts), len(res.Errs))
+	}
+	fmt.Println("Merge finished")
+	return nil
+}
+
+// libraryDiff lists the differences between the objects of two versions of a library.
+type libraryDiff struct {
+	Library string       `json:"library"`
+	Added   []objectDiff `json:"added"`
+	Removed []objectDiff `json:"removed"`
+	Changed []objectDiff `json:"changed"`
+}
+
+type objectDiff struct {
+	Name     string `json:"name"`
+	Inserted int    `json:"insertedLines"`
+	Deleted  int    `json:"deletedLines"`
+	Diff     string `json:"diff"`
+}
+
+// diffText compares two pbl (or pbt) files without ORCA and prints the differences in the given format
+// (unified, json or stat).
+func diffText(objFilePathBase, objFilePathMine, format string) error {
+	libsBase, err := readLibrarySources(objFilePathBase)
+	if err != nil {
+		return err
+	}
+	libsMine, err := readLibrarySources(objFilePathMine)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(objFilePathBase) != ".pbt" {
+		// the file names of the pbl files may differ (e.g. for svn temp files), the name of mine is shown
+		libsBase = map[string]map[string]string{filepath.Base(objFilePathMine): libsBase[filepath.Base(objFilePathBase)]}
+	}
+
+	var libNames []string
+	for name := range libsBase {
+		libNames = append(libNames, name)
+	}
+	for name := range libsMine {
+		if _, ok := libsBase[name]; !ok {
+			libNames = append(libNames, name)
 		}
-		pblSrcPathTheirs := filepath.Join(tempDir, fmt.Sprintf("%s (%s)", filepath.Base(pblFilePathTheirs), getPblFileDescr(pblFilePathTheirs)))
-		os.MkdirAll(pblSrcPathTheirs, 0664)
-		err = exportPbl(Orca, pblFilePathTheirs, regexp.MustCompile("^.*$"), pblSrcPathTheirs)
-		if err != nil {
-			return err
+	}
+	sort.Strings(libNames)
+
+	diffs := []libraryDiff{}
+	for _, libName := range libNames {
+		d := diffLibrary(libName, libsBase[libName], libsMine[libName])
+		if len(d.Added)+len(d.Removed)+len(d.Changed) > 0 {
+			diffs = append(diffs, d)
 		}
-		command := exec.Command(mergeTool, "/r", "/x", "/u", "/ignoreblanklines", "/dl", nameMine, "/dm", nameBase, "/dr", nameTheirs, pblSrcPathMine, pblSrcPathBase, pblSrcPathTheirs)
+	}
 
-		out, err := command.CombinedOutput()
-		if err != nil {
-			return err
+	switch format {
+	case "json":
+		return printJSON(diffs)
+	case "unified":
+		for _, d := range diffs {
+			for _, objs := range [][]objectDiff{d.Removed, d.Added, d.Changed} {
+				for _, obj := range objs {
+					fmt.Print(obj.Diff)
+				}
+			}
 		}
-		if len(out) > 0 {
-			fmt.Println(out)
+	case "stat":
+		for _, d := range diffs {
+			for _, obj := range append(append(d.Removed, d.Added...), d.Changed...) {
+				fmt.Printf(" %s/%s | +%d -%d\n", d.Library, obj.Name, obj.Inserted, obj.Deleted)
+			}
 		}
+	}
+	for _, d := range diffs {
+		fmt.Printf("%s: %d added, %d removed, %d changed\n", d.Library, len(d.Added), len(d.Removed), len(d.Changed))
+	}
+	if len(diffs) == 0 {
+		fmt.Println("no differences found")
+	}
+	return nil
+}
 
-		fmt.Println("Do you want to read back in the merge result? (The base file from the middle will be imported). [y/N]")
-		reader := bufio.NewReader(os.Stdin)
-		str, err := reader.ReadString('\n')
-		if err != nil {
-			return err
+func diffLibrary(libName string, base, mine map[string]string) libraryDiff {
+	d := libraryDiff{Library: libName, Added: []objectDiff{}, Removed: []objectDiff{}, Changed: []objectDiff{}}
+	var objNames []string
+	for name := range base {
+		objNames = append(objNames, name)
+	}
+	for name := range mine {
+		if _, ok := base[name]; !ok {
+			objNames = append(objNames, name)
 		}
-		if !regexp.MustCompile(`(?i)(y|j)[\r\n]+`).MatchString(str) {
-			return nil
+	}
+	sort.Strings(objNames)
+
+	for _, name := range objNames {
+		srcBase, inBase := base[name]
+		srcMine, inMine := mine[name]
+		if inBase && inMine && srcBase == srcMine {
+			continue
 		}
-		pbtFilePath, err := findPbtFilePath(filepath.Dir(pblFilePathBase), "")
-		if err != nil {
-			return err
+		obj := objectDiff{Name: name}
+		obj.Inserted, obj.Deleted = textdiff.Stat(srcBase, srcMine)
+		nameA, nameB := nameBase+"/"+libName+"/"+name, nameMine+"/"+libName+"/"+name
+		if !inBase {
+			nameA = "/dev/null"
 		}
-		fmt.Printf("Starting import into %s with target %s\n", pblFilePathBase, pbtFilePath)
-		srcFiles, err := os.ReadDir(pblSrcPathBase)
-		if err != nil {
-			return err
+		if !inMine {
+			nameB = "/dev/null"
 		}
-		var errs []error
-		for _, srcFile := range srcFiles {
-			objName := filepath.Base(srcFile.Name())
-			objName = strings.TrimSuffix(objName, filepath.Ext(objName))
-
-			srcData, err := os.ReadFile(filepath.Join(pblSrcPathBase, srcFile.Name()))
-			if err != nil {
-				return err
-			}
+		obj.Diff = textdiff.Unified(nameA, nameB, srcBase, srcMine, 3)
+		switch {
+		case !inBase:
+			d.Added = append(d.Added, obj)
+		case !inMine:
+			d.Removed = append(d.Removed, obj)
+		default:
+			d.Changed = append(d.Changed, obj)
+		}
+	}
+	return d
+}
 
-			err = Orca.SetObjSource(pbtFilePath, pblFilePathMine, objName, string(srcData))
-			if err == nil {
-				fmt.Printf("Successfully imported %s\n", objName)
-			} else {
-				fmt.Printf("Import of %s failed: %v\n", objName, err)
-				errs = append(errs, err)
-			}
+// readLibrarySources returns the sources of a pbl or of all libraries of a pbt, keyed by the file name of the
+// library and the entry name of the object.
+func readLibrarySources(objFilePath string) (map[string]map[string]string, error) {
+	pblFiles := []string{objFilePath}
+	if filepath.Ext(objFilePath) == ".pbt" {
+		pbt, err := orca.NewPbtFromFile(objFilePath)
+		if err != nil {
+			return nil, err
 		}
-		if len(errs) > 0 {
-			return fmt.Errorf("import finished with %d errors", len(errs))
+		pblFiles = pbt.LibList
+	}
+	libs := make(map[string]map[string]string)
+	for _, pblFile := range pblFiles {
+		sources, err := pbl.ReadSources(pblFile)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Println("Import finished")
-		return nil
-	*/
+		libs[strings.ToLower(filepath.Base(pblFile))] = sources
+	}
+	return libs, nil
 }
 
 // getDiffCommand returns a cmd to diff 2 folders.
 // nameMine and nameBase are only taken into account for WinMege.
-func getDiffCommand(objSrcPathMine, objSrcPathBase, nameMine, nameBase string) *exec.Cmd {
+func getDiffCommand(objSrcPathMine, objSrcPathBase, nameMine, nameBase string) (*exec.Cmd, error) {
 	basePath := utils.GetCommonBaseDir(objSrcPathMine, objSrcPathBase)
 	objSrcRelPathBase, err := filepath.Rel(basePath, objSrcPathBase)
 	if err != nil {
@@ -293,13 +406,13 @@ func getDiffCommand(objSrcPathMine, objSrcPathBase, nameMine, nameBase string) *
 		cmd := exec.Command(mergeTool)
 		cmd.Dir = basePath
 		cmd.Args = append(cmd.Args, "/r", "/x", "/u", "/ignoreblanklines", objSrcRelPathMine, objSrcRelPathBase, "/dl", nameMine, "/dr", nameBase)
-		return cmd
+		return cmd, nil
 	} else if tool == "codium" || tool == "code" {
 		cmd := exec.Command("powershell", "-nologo", "-noprofile")
 		cmd.Dir = basePath
 		stdin, err := cmd.StdinPipe()
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		defer stdin.Close()
 		fmt.Fprintf(stdin, "%s '%s' '%s' --wait --new-window\n", tool, objSrcRelPathMine, objSrcRelPathBase)
@@ -317,12 +430,14 @@ func getDiffCommand(objSrcPathMine, objSrcPathBase, nameMine, nameBase string) *
 			cmd.Args = append(cmd.Args, filepath.Join(filepath.Dir(mergeTool), "resources\\app\\out\\cli.js"))
 			cmd.Dir = basePath
 			cmd.Args = append(cmd.Args, "--wait", "--new-window", `"`+objSrcRelPathMine+`"`, `"`+objSrcRelPathBase+`"`)*/
-		return cmd
+		return cmd, nil
 	}
-	return nil
+	return nil, fmt.Errorf("unsupported diff tool %s (use WinMergeU.exe, code or codium, or --format for a textual diff)", tool)
 }
 
 func init() {
+	diffCmd.Flags().StringVar(&diffFormat, "format", "", "print the differences instead of opening the diff tool, one of [unified|json|stat]")
+	diffCmd.Flags().StringVar(&diffPath, "path", "", "path of the library in the working copy if the pbl files are temp files (used to find the target when merging)")
 	diffCmd.Flags().StringVar(&mergeTool, "diff-tool", "C:/Program Files/WinMerge/WinMergeU.exe", "Path to diff tool (WinMergeU.exe, code.exe or codium.exe).")
 	diffCmd.Flags().StringVar(&nameMine, "mine-name", "Mine", "Description in WinMerge for the mine file")
 	diffCmd.Flags().StringVar(&nameBase, "base-name", "Base", "Description in WinMerge for the base file")
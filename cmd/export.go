@@ -6,8 +6,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/exportmanifest"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/informaticon/lib.go.base.pborca/orca"
@@ -66,6 +71,19 @@ With --output-dir, you can specify the path where the object(s) are exportet to.
 			return err
 		}
 
+		if exportNative {
+			if fileType == ".pbt" {
+				err = exportPbtNative(pbxFilePath, objRegex, exportOutputDir, exportOutputEnc)
+			} else {
+				err = exportPblNative(pbxFilePath, objRegex, exportOutputDir, exportOutputEnc)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println("export finished")
+			return nil
+		}
+
 		if orcaVars.pbVersion != 22 {
 			return fmt.Errorf("currently, only PowerBuilder 22 is supported")
 		}
@@ -77,18 +95,17 @@ With --output-dir, you can specify the path where the object(s) are exportet to.
 		if orcaVars.serverAddr != "" {
 			opts = append(opts, pborca.WithOrcaServer(orcaVars.serverAddr, orcaVars.serverApiKey))
 		}
-		Orca, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
-		if err != nil {
-			return err
-		}
-		defer Orca.Close()
-
 		if fileType == ".pbt" {
-			err = exportPbt(Orca, pbxFilePath, objRegex, exportOutputDir, exportOutputEnc)
+			err = exportPbt(newOrcaPool(orcaVars.pbVersion, opts...), pbxFilePath, objRegex, exportOutputDir, exportOutputEnc)
 			if err != nil {
 				return err
 			}
 		} else {
+			Orca, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
+			if err != nil {
+				return err
+			}
+			defer Orca.Close()
 			err = exportPbl(Orca, pbxFilePath, objRegex, exportOutputDir, exportOutputEnc)
 			if err != nil {
 				return err
@@ -100,7 +117,12 @@ With --output-dir, you can specify the path where the object(s) are exportet to.
 	},
 }
 
-var exportCreateSupdir bool
+var (
+	exportCreateSupdir bool
+	exportNative       bool
+	exportIncremental  bool
+	exportPrune        bool
+)
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
@@ -109,11 +131,15 @@ func init() {
 	exportCmd.PersistentFlags().String("output-encoding", "utf8", fmt.Sprintf("encoding to use, possible values: %s", maps.Keys(encodings)))
 
 	exportCmd.PersistentFlags().BoolVarP(&exportCreateSupdir, "create-subdir", "s", true, "create a subfolder with the library name to export the source file(s) into")
+	exportCmd.PersistentFlags().BoolVar(&exportNative, "native", false, "read the library with the built-in pbl reader instead of ORCA (no PowerBuilder runtime needed)")
+	exportCmd.PersistentFlags().BoolVar(&exportIncremental, "incremental", false, "only export objects changed since the last export (see the manifest .pbmanager-export.json in the output directory)")
+	exportCmd.PersistentFlags().BoolVar(&exportPrune, "prune", false, "remove source files of objects which do not exist in the library anymore")
 }
 
-func exportPbl(Orca *pborca.Orca, pblFilePath string, objRegex *regexp.Regexp, outDir string, outEnc string) error {
-	if exportCreateSupdir {
-		outDir = filepath.Join(outDir, filepath.Base(pblFilePath))
+func exportPbl(Orca backend.Backend, pblFilePath string, objRegex *regexp.Regexp, outDir string, outEnc string) error {
+	ex, err := newLibraryExport(pblFilePath, outDir, nil)
+	if err != nil {
+		return err
 	}
 
 	objs, err := Orca.GetObjList(pblFilePath)
@@ -121,11 +147,12 @@ func exportPbl(Orca *pborca.Orca, pblFilePath string, objRegex *regexp.Regexp, o
 		return err
 	}
 
-	dirCreated := false
+	var entries []string
 	for _, objArr := range objs {
 		for _, obj := range objArr.GetObjArr() {
 			objName := obj.GetName() + pborca.GetObjSuffixFromType(obj.GetObjType())
-			if objRegex.FindString(objName) == "" {
+			entries = append(entries, objName)
+			if objRegex.FindString(objName) == "" || ex.skip(objName) {
 				continue
 			}
 			srcData, err := Orca.GetObjSource(pblFilePath, objName)
@@ -136,44 +163,241 @@ func exportPbl(Orca *pborca.Orca, pblFilePath string, objRegex *regexp.Regexp, o
 			if err != nil {
 				return err
 			}
-			if !dirCreated {
-				fmt.Printf("Exporting library %s\n", filepath.Base(filepath.Base(pblFilePath)))
-				err := os.MkdirAll(outDir, os.ModeDir)
-				if err != nil {
-					return err
-				}
-				dirCreated = true
-			}
-			srcBytes, err := encode(srcData, outEnc)
-			if err != nil {
-				return err
-			}
-			err = os.WriteFile(filepath.Join(outDir, fileName), srcBytes, 0o664)
+			err = ex.write(objName, fileName, srcData, outEnc)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
-	return nil
+	return ex.finish(entries)
 }
 
-func exportPbt(Orca *pborca.Orca, pbtFilePath string, objRegex *regexp.Regexp, outDir string, outEnc string) error {
+// exportPbt exports the libraries of a target in parallel, every library on a session of the pool.
+func exportPbt(sessions *orcapool.Pool[backend.Backend], pbtFilePath string, objRegex *regexp.Regexp, outDir string, outEnc string) error {
 	pbt, err := orca.NewPbtFromFile(pbtFilePath)
 	if err != nil {
 		return err
 	}
+	var libs []string
 	for _, lib := range pbt.LibList {
 		if !utils.FileExists(lib) {
 			fmt.Printf("Library %s does not exist, skipping.....\n", lib)
 			continue
 		}
-		err = exportPbl(Orca, lib, objRegex, outDir, outEnc)
+		libs = append(libs, lib)
+	}
+
+	return sessions.Run(libs, func(Orca backend.Backend, lib string) error {
+		return exportPbl(Orca, lib, objRegex, outDir, outEnc)
+	})
+}
+
+// exportPblNative exports the source entries of a library without ORCA.
+func exportPblNative(pblFilePath string, objRegex *regexp.Regexp, outDir string, outEnc string) error {
+	lib, err := pbl.Open(pblFilePath)
+	if err != nil {
+		return err
+	}
+	ex, err := newLibraryExport(pblFilePath, outDir, lib)
+	if err != nil {
+		return err
+	}
+
+	var entries []string
+	for _, entry := range lib.SourceEntries() {
+		entries = append(entries, entry.Name)
+		if objRegex.FindString(entry.Name) == "" || ex.skip(entry.Name) {
+			continue
+		}
+		srcData, err := lib.Source(entry.Name)
+		if err != nil {
+			return err
+		}
+		err = ex.write(entry.Name, entry.Name, srcData, outEnc)
 		if err != nil {
 			return err
 		}
 	}
+	return ex.finish(entries)
+}
 
+func exportPbtNative(pbtFilePath string, objRegex *regexp.Regexp, outDir string, outEnc string) error {
+	pbt, err := orca.NewPbtFromFile(pbtFilePath)
+	if err != nil {
+		return err
+	}
+	for _, lib := range pbt.LibList {
+		if !utils.FileExists(lib) {
+			fmt.Printf("Library %s does not exist, skipping.....\n", lib)
+			continue
+		}
+		err = exportPblNative(lib, objRegex, outDir, outEnc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	exportManifests   = make(map[string]*exportmanifest.Manifest)
+	exportManifestsMu sync.Mutex
+)
+
+// loadExportManifest returns the manifest of an export folder. The libraries of a target are exported in
+// parallel, so they share one manifest.
+func loadExportManifest(outDir string) (*exportmanifest.Manifest, error) {
+	exportManifestsMu.Lock()
+	defer exportManifestsMu.Unlock()
+	if m, ok := exportManifests[outDir]; ok {
+		return m, nil
+	}
+	m, err := exportmanifest.Load(outDir)
+	if err != nil {
+		return nil, err
+	}
+	exportManifests[outDir] = m
+	return m, nil
+}
+
+// libraryExport writes the exported objects of a library. With --incremental, objects which did not change since
+// the last export (see exportmanifest) are skipped, with --prune, the files of deleted objects are removed.
+type libraryExport struct {
+	pblFilePath string
+	library     string
+	outDir      string                   // folder of the source files of the library
+	manifest    *exportmanifest.Manifest // nil if neither --incremental nor --prune is set
+	lib         *pbl.Library             // timestamps of the entries, nil if the library can not be read
+	dirCreated  bool
+
+	written, skipped, removed int
+}
+
+// newLibraryExport prepares the export of a library into outDir. lib may be nil, it is read if needed.
+func newLibraryExport(pblFilePath, outDir string, lib *pbl.Library) (*libraryExport, error) {
+	ex := &libraryExport{pblFilePath: pblFilePath, library: filepath.Base(pblFilePath), outDir: outDir, lib: lib}
+	if exportCreateSupdir {
+		ex.outDir = filepath.Join(outDir, ex.library)
+	}
+	if !exportIncremental && !exportPrune {
+		return ex, nil
+	}
+	var err error
+	ex.manifest, err = loadExportManifest(outDir)
+	if err != nil {
+		return nil, err
+	}
+	if ex.lib == nil {
+		ex.lib, err = pbl.Open(pblFilePath)
+		if err != nil {
+			ex.lib = nil
+			if exportIncremental {
+				printWarn(fmt.Sprintf("could not read the timestamps of %s, all objects are exported: %v", ex.library, err))
+			}
+		}
+	}
+	return ex, nil
+}
+
+// skip returns true if the entry did not change since the last export.
+func (ex *libraryExport) skip(entryName string) bool {
+	if !exportIncremental || ex.lib == nil {
+		return false
+	}
+	entry, ok := ex.lib.Entry(entryName)
+	if !ok || !ex.manifest.Unchanged(ex.library, entryName, entry.Modified, entry.Size) {
+		return false
+	}
+	ex.skipped++
+	return true
+}
+
+// write writes the source of an entry to fileName. With --incremental, files with the same content are not
+// written again.
+func (ex *libraryExport) write(entryName, fileName, srcData, outEnc string) error {
+	if !ex.dirCreated {
+		fmt.Printf("Exporting library %s\n", ex.library)
+		err := os.MkdirAll(ex.outDir, os.ModeDir)
+		if err != nil {
+			return err
+		}
+		ex.dirCreated = true
+	}
+	srcBytes, err := encode(srcData, outEnc)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(ex.outDir, fileName)
+	if exportIncremental && exportmanifest.FileUnchanged(path, srcBytes) {
+		ex.skipped++
+	} else {
+		err = os.WriteFile(path, srcBytes, 0o664)
+		if err != nil {
+			return err
+		}
+		ex.written++
+		addResultObject("exported", ex.library, fileName)
+	}
+	if ex.manifest != nil {
+		var modified time.Time
+		var size uint32
+		if ex.lib != nil {
+			if entry, ok := ex.lib.Entry(entryName); ok {
+				modified, size = entry.Modified, entry.Size
+			}
+		}
+		ex.manifest.Set(ex.library, entryName, modified, size, path, srcBytes)
+	}
+	return nil
+}
+
+// finish removes the files of objects which are not part of the library anymore (--prune), saves the manifest
+// and prints the summary of the library. entries are all entries of the library.
+func (ex *libraryExport) finish(entries []string) error {
+	if exportPrune {
+		exists := make(map[string]bool)
+		for _, e := range entries {
+			exists[strings.ToLower(e)] = true
+		}
+		var deleted []string
+		for entryName, e := range ex.manifest.Entries(ex.library) {
+			if !exists[entryName] {
+				deleted = append(deleted, ex.manifest.Path(e))
+				ex.manifest.Remove(ex.library, entryName)
+			}
+		}
+		if exportCreateSupdir {
+			// the folder only contains the objects of this library
+			files, err := os.ReadDir(ex.outDir)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			for _, f := range files {
+				if !f.IsDir() && regexSrcExt.MatchString(filepath.Ext(f.Name())) && !exists[strings.ToLower(f.Name())] {
+					deleted = append(deleted, filepath.Join(ex.outDir, f.Name()))
+				}
+			}
+		}
+		for _, path := range deleted {
+			err := os.Remove(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ex.removed++
+			addResultObject("removed", ex.library, filepath.Base(path))
+		}
+	}
+	if ex.manifest != nil {
+		err := ex.manifest.Save()
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s: %d written, %d skipped, %d removed\n", ex.library, ex.written, ex.skipped, ex.removed)
 	return nil
 }
 
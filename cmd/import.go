@@ -1,15 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/buildlog"
 	"github.com/informaticon/dev.win.base.pbmanager/internal/importer"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/informaticon/lib.go.base.pborca/orca"
@@ -28,8 +30,10 @@ var importCmd = &cobra.Command{
 	Long: `To import a source file, pbmanager needs to know the PowerBuilder target (pbt-file).
 You can set the path to the target or let pbmanager try to find the target.
 Usually, you have to declare the pbl file into which you want to import the source,
-but you can also just specify a pbt and a list of pbl names (-p parameter). In this case,
-pbmanager tries to import all the pbl multiple times until there is no compilation error.
+but you can also just specify a pbt and a list of pbl names (-p parameter).
+Source folders are imported in the order of the dependencies between the objects (see deps command),
+objects depending on each other are imported repeatedly. Objects that still fail are listed at the end,
+their compiler messages can be written as JSON, JUnit XML or SARIF (see --report-format).
 Examples:
 	- pbmanager import -b C:/a3/lib -t liq.pbt tst1.pbl src/w_main.srw
 	- pbmanager import -b C:/a3/lib tst1.pbl src/
@@ -37,6 +41,10 @@ Examples:
 	- pbmanager import my.pbt -p tst1,exf1,str1 . C:/additional/src_folder C:/third/src`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		err = checkReportFormat(true)
+		if err != nil {
+			return err
+		}
 		var pblSrcFilePath string
 		var srcPaths []string
 
@@ -89,6 +97,30 @@ Examples:
 		}
 		defer Orca.Close()
 
+		var snapshotFile string
+		defer func() {
+			var importErr *importer.ImportError
+			if errors.As(err, &importErr) {
+				reportVars.messages = append(reportVars.messages, importErr.Messages()...)
+				for _, f := range importErr.Failures {
+					addResultObject("failed", f.Library, f.Object)
+				}
+			}
+			if len(reportVars.messages) > 0 {
+				result.Data = reportVars.messages
+			}
+			if rerr := writeReport(filepath.Base(pbtFilePath)); rerr != nil {
+				printWarn(fmt.Sprintf("could not write compiler report: %v", rerr))
+			}
+			err = restoreHint(err, snapshotFile)
+		}()
+		if len(pblList) == 0 {
+			snapshotFile, err = takeSnapshot("import", filepath.Dir(pbtFilePath), snapshot.Path{Name: pblSrcFilePath})
+			if err != nil {
+				return err
+			}
+		}
+
 		if isFile(srcPaths[0]) {
 			// pbl import mode - single file
 			for _, srcPath := range srcPaths {
@@ -97,38 +129,22 @@ Examples:
 					return err
 				}
 				err = Orca.SetObjSource(pbtFilePath, pblSrcFilePath, filepath.Base(srcPath), srcData)
+				objName := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
 				if err != nil {
+					reportVars.messages = buildlog.FromError(filepath.Base(pblSrcFilePath), objName, err)
 					return fmt.Errorf("could not import %s: %w", filepath.Base(srcPath), err)
 				}
+				addImportedObject(filepath.Base(pblSrcFilePath), objName)
 			}
 		} else if len(pblList) == 0 {
 			// pbl import mode - folder
-			errs := make(map[string]error)
-			for _, srcPath := range srcPaths {
-				err = filepath.WalkDir(srcPath, func(path string, d fs.DirEntry, err error) error {
-					if err != nil {
-						return err
-					}
-					if d.IsDir() {
-						return nil
-					}
-					srcData, err := utils.ReadPbSource(path)
-					if err != nil {
-						return err
-					}
-					objName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(filepath.Base(path)))
-					err = Orca.SetObjSource(pbtFilePath, pblSrcFilePath, filepath.Base(objName), srcData)
-					if err != nil {
-						errs[objName] = err
-					}
-					return nil
-				})
-				if err != nil {
-					return err
-				}
+			pblFilePaths := make([]string, len(srcPaths))
+			for i := range srcPaths {
+				pblFilePaths[i] = pblSrcFilePath
 			}
-			if len(errs) > 0 {
-				fmt.Printf("compilation errors occured: %v\n", errs)
+			err = importer.Import(Orca, pbtFilePath, srcPaths, pblFilePaths, addImportedObject)
+			if err != nil {
+				return err
 			}
 		} else /* len(pblList) > 0 */ {
 			// pbt import modde - multiple pbl
@@ -161,7 +177,15 @@ Examples:
 					}
 				}
 			}
-			err = importer.Import(Orca, pbtFilePath, srcPaths, pblFilePaths)
+			var paths []snapshot.Path
+			for _, pblFilePath := range pblFilePaths {
+				paths = append(paths, snapshot.Path{Name: pblFilePath})
+			}
+			snapshotFile, err = takeSnapshot("import", filepath.Dir(pbtFilePath), paths...)
+			if err != nil {
+				return err
+			}
+			err = importer.Import(Orca, pbtFilePath, pblSrcFilePaths, pblFilePaths, addImportedObject)
 			if err != nil {
 				return err
 			}
@@ -172,8 +196,13 @@ Examples:
 	},
 }
 
+func addImportedObject(library, object string) {
+	addResultObject("imported", library, object)
+}
+
 func init() {
 	importCmd.Flags().StringVarP(&pbtFilePath, "target", "t", "", "Target file to use (e.g. C:/a3/lib/a3.pbt). If omitted, pbmanagers tries to find the appropriate taget automatically.")
 	importCmd.Flags().StringSliceVarP(&pblList, "pbl-list", "p", pblList, "List of pbl to import (try multiple times until there is no compilation error.")
+	addReportFlags(importCmd)
 	rootCmd.AddCommand(importCmd)
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <pbl/pbt path>",
+	Short: "Shows format and content of a pbl file",
+	Long: `Reports the format version (ANSI/Unicode, PowerBuilder release), the library comment, the number of objects per type
+and the size and timestamps of each object. The library is read directly, ORCA is not needed.
+If a pbt is passed, all libraries of the target are inspected.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pbxFilePath := args[0]
+		format, _ := cmd.Flags().GetString("format")
+		if format != "table" && format != "json" {
+			return fmt.Errorf("invalid format %s, use table or json", format)
+		}
+		if !filepath.IsAbs(pbxFilePath) {
+			pbxFilePath = filepath.Join(basePath, pbxFilePath)
+		}
+		fileType := filepath.Ext(pbxFilePath)
+		if !utils.FileExists(pbxFilePath) || (fileType != ".pbl" && fileType != ".pbt") {
+			return fmt.Errorf("file %s does not exist or is not a pbl/pbt file", pbxFilePath)
+		}
+
+		pblFiles := []string{pbxFilePath}
+		if fileType == ".pbt" {
+			pbt, err := orca.NewPbtFromFile(pbxFilePath)
+			if err != nil {
+				return err
+			}
+			pblFiles = pbt.LibList
+		}
+
+		var infos []*pbl.Info
+		for _, pblFile := range pblFiles {
+			if !utils.FileExists(pblFile) {
+				printWarnStderr(fmt.Sprintf("Library %s does not exist, skipping", pblFile))
+				continue
+			}
+			lib, err := pbl.Open(pblFile)
+			if err != nil {
+				return err
+			}
+			infos = append(infos, lib.Inspect())
+		}
+
+		if format == "json" || jsonOutput() {
+			return printJSON(infos)
+		}
+		for _, info := range infos {
+			printInspectTable(info)
+		}
+		return nil
+	},
+}
+
+func init() {
+	inspectCmd.Flags().String("format", "table", "output format, one of [table|json]")
+	rootCmd.AddCommand(inspectCmd)
+}
+
+func printInspectTable(info *pbl.Info) {
+	encoding := "ANSI"
+	if info.Unicode {
+		encoding = "Unicode"
+	}
+	release := info.PbRelease
+	if release == "" {
+		release = "unknown"
+	}
+	fmt.Printf("Library:     %s\n", info.Path)
+	fmt.Printf("Format:      %s (%s), PowerBuilder release %s\n", info.FormatVersion, encoding, release)
+	fmt.Printf("Comment:     %s\n", info.Comment)
+	fmt.Printf("Modified:    %s\n", info.Modified.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Blocks:      %d (%d free), %d fragmented entries\n", info.Blocks, info.FreeBlocks, info.FragmentedEntries)
+
+	types := make([]string, 0, len(info.TypeCounts))
+	for t := range info.TypeCounts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	fmt.Print("Objects:    ")
+	for _, t := range types {
+		fmt.Printf(" %s=%d", t, info.TypeCounts[t])
+	}
+	fmt.Println()
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSOURCE SIZE\tSOURCE MODIFIED\tCOMPILED SIZE\tCOMPILED MODIFIED\tCOMMENT")
+	for _, obj := range info.Objects {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\t%s\n", obj.Name, obj.Type,
+			obj.SourceSize, formatInspectTime(obj.SourceModified),
+			obj.CompiledSize, formatInspectTime(obj.CompiledModified), obj.Comment)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+func formatInspectTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats of --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var outputFormat string
+
+// stdout is the standard output of the process. With --output json, os.Stdout is redirected to stderr, so the
+// human readable messages do not mix with the result document.
+var stdout = os.Stdout
+
+// commandResult is the document written by --output json after a command finished.
+type commandResult struct {
+	Command  string         `json:"command"`
+	Args     []string       `json:"args"`
+	Success  bool           `json:"success"`
+	Error    string         `json:"error,omitempty"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Duration float64        `json:"durationSeconds"`
+	Timings  []resultTiming `json:"timings,omitempty"` // steps of the command, e.g. of upgrade
+	Objects  []resultObject `json:"objects"`
+	Warnings []string       `json:"warnings"`
+	Data     any            `json:"data,omitempty"` // command specific result, e.g. the dependency graph of deps
+}
+
+// resultObject is an object or file affected by a command.
+type resultObject struct {
+	Action  string `json:"action"` // e.g. exported, imported, deleted
+	Library string `json:"library,omitempty"`
+	Name    string `json:"name"`
+}
+
+type resultTiming struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"durationSeconds"`
+}
+
+var result = &commandResult{Objects: []resultObject{}, Warnings: []string{}}
+
+// resultMu guards result, libraries may be processed in parallel (see orcapool)
+var resultMu sync.Mutex
+
+// startOutput checks --output and redirects the human readable output to stderr if the result is written as JSON.
+func startOutput(cmd *cobra.Command, args []string) error {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		return fmt.Errorf("invalid output %s, use text or json", outputFormat)
+	}
+	result.Command = cmd.CommandPath()
+	result.Args = args
+	result.Started = time.Now()
+	if jsonOutput() {
+		os.Stdout = os.Stderr
+	}
+	return nil
+}
+
+// jsonOutput returns true if the result of the command is written as JSON document (--output json).
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// addResultObject records an object (or file) affected by the command.
+func addResultObject(action, library, name string) {
+	resultMu.Lock()
+	defer resultMu.Unlock()
+	result.Objects = append(result.Objects, resultObject{Action: action, Library: library, Name: name})
+}
+
+// addResultTiming records the duration of a step of the command.
+func addResultTiming(name string, started time.Time) {
+	resultMu.Lock()
+	defer resultMu.Unlock()
+	result.Timings = append(result.Timings, resultTiming{Name: name, Duration: time.Since(started).Seconds()})
+}
+
+// printWarnStderr prints a warning to stderr and adds it to the result of the command. It is used by commands
+// whose stdout is processed by other tools.
+func printWarnStderr(message string) {
+	resultMu.Lock()
+	result.Warnings = append(result.Warnings, message)
+	resultMu.Unlock()
+	fmt.Fprintln(os.Stderr, "WARN: ", message)
+}
+
+// finishOutput writes the result document if --output json is set.
+func finishOutput(err error) {
+	if !jsonOutput() {
+		return
+	}
+	result.Finished = time.Now()
+	if !result.Started.IsZero() {
+		result.Duration = result.Finished.Sub(result.Started).Seconds()
+	}
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// exit writes the result document of a failed command and terminates the process.
+func exit(code int, err error) {
+	finishOutput(err)
+	os.Exit(code)
+}
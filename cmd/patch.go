@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/migrate"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+)
+
+// patchCmd represents the patch command
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Applies declarative source patches",
+}
+
+// patchApplyCmd represents the patch apply command
+var patchApplyCmd = &cobra.Command{
+	Use:   "apply <pbt path> <patch file>...",
+	Short: "Applies the patches of one or more patch files to a target",
+	Long: `A patch file is a JSON file with a list of patches. Every patch replaces the matches of a regex in the source of one object:
+	{"patches": [{
+		"name": "FIX1", "library": "inf1.pbl", "object": "inf1_u_transaction",
+		"check": "(?is)//FIX1:", "match": "(?is)(if ls_version.*?end if)", "replace": "//FIX1:\r\n/*${1}*/", "expect": 1
+	}]}
+check (optional) is a regex telling the patch has already been applied, expect (optional) the required number of matches.
+The library is relative to the folder of the target. Every patch is reported as applied, skipped or failed.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pbtFilePath := args[0]
+		if !filepath.IsAbs(pbtFilePath) {
+			pbtFilePath = filepath.Join(basePath, pbtFilePath)
+		}
+		if !utils.FileExists(pbtFilePath) {
+			return fmt.Errorf("pbt file %s does not exist", pbtFilePath)
+		}
+		patches, err := loadPatchFiles(args[1:])
+		if err != nil {
+			return err
+		}
+		pbtData, err := orca.NewPbtFromFile(pbtFilePath)
+		if err != nil {
+			return err
+		}
+
+		var opts []func(*pborca.Orca)
+		if orcaVars.pbRuntimeFolder != "" {
+			opts = append(opts, pborca.WithOrcaRuntime(orcaVars.pbRuntimeFolder))
+		}
+		opts = append(opts, pborca.WithOrcaTimeout(time.Duration(orcaVars.timeoutSeconds)*time.Second))
+		if orcaVars.serverAddr != "" {
+			opts = append(opts, pborca.WithOrcaServer(orcaVars.serverAddr, orcaVars.serverApiKey))
+		}
+		Orca, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
+		if err != nil {
+			return err
+		}
+		defer Orca.Close()
+
+		return applyPatchFiles(pbtData, patches, Orca)
+	},
+}
+
+func init() {
+	patchCmd.AddCommand(patchApplyCmd)
+	rootCmd.AddCommand(patchCmd)
+}
+
+func loadPatchFiles(patchFiles []string) ([]*migrate.Patch, error) {
+	var patches []*migrate.Patch
+	for _, patchFile := range patchFiles {
+		if !filepath.IsAbs(patchFile) {
+			patchFile = filepath.Join(basePath, patchFile)
+		}
+		p, err := migrate.LoadPatchFile(patchFile)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, p...)
+	}
+	return patches, nil
+}
+
+// applyPatchFiles applies the patches, prints the result of every patch and returns an error if a patch failed.
+func applyPatchFiles(pbtData *orca.Pbt, patches []*migrate.Patch, o backend.Backend) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	results := migrate.ApplyPatches(pbtData.BasePath, pbtData.AppName, patches, o)
+	failed := 0
+	for _, res := range results {
+		if res.Message == "" {
+			fmt.Printf("%-8s %s\n", res.Status, res.Name)
+		} else {
+			fmt.Printf("%-8s %s: %s\n", res.Status, res.Name, res.Message)
+		}
+		addResultObject(res.Status, "", res.Name)
+		if res.Status == migrate.PatchFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d patches failed", failed, len(results))
+	}
+	fmt.Printf("Applied patch files (%d patches)\n", len(results))
+	return nil
+}
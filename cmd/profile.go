@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/profile"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	"github.com/spf13/cobra"
+)
+
+// defaultProfileFile is the profile file used if --profile-file is not set and the file exists in the base path.
+const defaultProfileFile = "pbmanager-profiles.json"
+
+var profileFile string
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile [<name>]",
+	Short: "Lists the application profiles or prints a profile",
+	Long: `A profile defines the product specific parts of upgrade and backport: the fixes applied after the migration,
+the library ordering, project moves and the workspace file to replace.
+The profiles a3, lohn and generic are built in, custom profiles are read from the profile file (--profile-file,
+default pbmanager-profiles.json in the base path). Without name, all profiles are listed.
+The printed profile can be used as a template for a custom profile.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := loadProfiles()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			p, err := profiles.Get(args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(p)
+		}
+		if jsonOutput() {
+			return printJSON(profiles.All())
+		}
+		for _, p := range profiles.All() {
+			fmt.Printf("%-10s apps: %s\n", p.Name, strings.Join(p.Apps, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.PersistentFlags().StringVar(&profileFile, "profile-file", "", "JSON file with custom application profiles (see profile). If omitted, pbmanager-profiles.json in the base path is used if it exists.")
+}
+
+// loadProfiles returns the built-in profiles and the profiles of the profile file.
+func loadProfiles() (*profile.Set, error) {
+	file := profileFile
+	if file != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(basePath, file)
+	}
+	if file == "" && utils.FileExists(filepath.Join(basePath, defaultProfileFile)) {
+		file = filepath.Join(basePath, defaultProfileFile)
+	}
+	return profile.Load(file)
+}
+
+// selectProfile returns the profile with the given name or, if name is empty, the profile of the application.
+func selectProfile(name, appName string) (*profile.Profile, error) {
+	profiles, err := loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		return profiles.Get(name)
+	}
+	return profiles.ForApp(appName), nil
+}
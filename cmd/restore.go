@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore [<snapshot zip>]",
+	Short: "Restores the files saved in a snapshot",
+	Long: `The commands upgrade, import, delete, sync, target sort and backport save the files they modify to a snapshot (zip file with a manifest)
+before they change anything. restore resets the files to the state of the snapshot, files created by the command are removed.
+The snapshots are stored in .pbmanager/snapshots beside the target (or in the folder set with --snapshot-dir).
+Without argument, the snapshots of the base path are listed.
+Examples:
+	- pbmanager restore -b C:/a3/lib
+	- pbmanager restore C:/a3/lib/.pbmanager/snapshots/20240301-101500.000-upgrade.zip`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			manifests, err := snapshot.List(snapshotDir(basePath))
+			if err != nil {
+				return err
+			}
+			if jsonOutput() {
+				return printJSON(manifests)
+			}
+			if len(manifests) == 0 {
+				fmt.Printf("no snapshots found in %s\n", snapshotDir(basePath))
+			}
+			for _, m := range manifests {
+				fmt.Printf("%s  %-10s %s\n", m.Created.Format("2006-01-02 15:04:05"), m.Command, m.File())
+			}
+			return nil
+		}
+
+		snapshotFile := args[0]
+		if !filepath.IsAbs(snapshotFile) {
+			snapshotFile = filepath.Join(basePath, snapshotFile)
+		}
+		m, err := snapshot.Open(snapshotFile)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("restoring snapshot of %s from %s\n", m.Command, m.Created.Format("2006-01-02 15:04:05"))
+		err = snapshot.Restore(snapshotFile, func(s string) {
+			fmt.Printf("  %s\n", s)
+			action, path, _ := strings.Cut(s, " ")
+			addResultObject(action, "", path)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("restore finished")
+		return nil
+	},
+}
+
+var snapshotVars struct {
+	disabled bool
+	keep     int
+	dir      string
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.PersistentFlags().BoolVar(&snapshotVars.disabled, "no-snapshot", false, "Do not save a snapshot of the modified files (see restore).")
+	rootCmd.PersistentFlags().IntVar(&snapshotVars.keep, "snapshot-keep", 10, "Number of snapshots to keep, older ones are deleted. 0 keeps all snapshots.")
+	rootCmd.PersistentFlags().StringVar(&snapshotVars.dir, "snapshot-dir", "", "Folder to store the snapshots in. If omitted, .pbmanager/snapshots beside the target is used.")
+}
+
+// snapshotDir returns the folder of the snapshots of a workspace.
+func snapshotDir(workspace string) string {
+	if snapshotVars.dir != "" {
+		if !filepath.IsAbs(snapshotVars.dir) {
+			return filepath.Join(basePath, snapshotVars.dir)
+		}
+		return snapshotVars.dir
+	}
+	return filepath.Join(workspace, snapshot.DirName)
+}
+
+// takeSnapshot saves the paths before command modifies them and deletes old snapshots. It returns the snapshot
+// file or an empty string if snapshots are disabled.
+func takeSnapshot(command, workspace string, paths ...snapshot.Path) (string, error) {
+	if snapshotVars.disabled {
+		return "", nil
+	}
+	dir := snapshotDir(workspace)
+	m, err := snapshot.Create(dir, command, paths)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("snapshot saved to %s\n", m.File())
+	addResultObject("snapshot", "", m.File())
+
+	deleted, err := snapshot.Prune(dir, snapshotVars.keep)
+	if err != nil {
+		printWarn(fmt.Sprintf("could not delete old snapshots: %v", err))
+	}
+	for _, file := range deleted {
+		slog.Info(fmt.Sprintf("deleted old snapshot %s", file))
+	}
+	return m.File(), nil
+}
+
+// restoreHint adds the restore command to undo the changes to the error of a command.
+func restoreHint(err error, snapshotFile string) error {
+	if err == nil || snapshotFile == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nthe changes can be undone with: pbmanager restore %s", err, snapshotFile)
+}
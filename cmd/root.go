@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
 	logging "github.com/informaticon/lib.go.base.logging"
 	"github.com/informaticon/lib.go.base.logging/filter/level"
 	"github.com/informaticon/lib.go.base.logging/rule"
 	"github.com/informaticon/lib.go.base.logging/sender/eventlog"
 	"github.com/informaticon/lib.go.base.logging/sender/std"
 	"github.com/informaticon/lib.go.base.logging/transformer/pretty"
+	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +29,12 @@ var Version = "0.0.0-trunk"
 var rootCmd = &cobra.Command{
 	Use:   "pbmanager",
 	Short: "PowerBuilder management tools",
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := startOutput(cmd, args)
+		if err != nil {
+			return err
+		}
+
 		var logMinSeverity slog.Level
 		switch flagLogLevel {
 		case "debug":
@@ -37,8 +46,9 @@ var rootCmd = &cobra.Command{
 		case "error":
 			logMinSeverity = slog.LevelError
 		default:
-			fmt.Printf("invalid log level: %s\n", flagLogLevel)
-			os.Exit(2)
+			err = fmt.Errorf("invalid log level: %s", flagLogLevel)
+			fmt.Println(err)
+			exit(2, err)
 		}
 
 		slog.SetDefault(slog.New(logging.New(nil,
@@ -50,6 +60,7 @@ var rootCmd = &cobra.Command{
 				Filter(level.New(level.WithMin(logMinSeverity))).
 				Send(eventlog.New("dev.win.base.pbmanager", eventlog.WithExternal())),
 		)))
+		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		flagV, err := cmd.Flags().GetBool("version")
@@ -69,7 +80,11 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
+		// with --output json, stdout is redirected to stderr
 		fmt.Println(err)
+	}
+	finishOutput(err)
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -80,6 +95,8 @@ var orcaVars struct {
 	timeoutSeconds  uint
 	serverAddr      string
 	serverApiKey    string
+	sessions        int
+	sessionTimeout  uint
 }
 var (
 	basePath     string
@@ -96,8 +113,11 @@ func init() {
 	rootCmd.PersistentFlags().UintVar(&orcaVars.timeoutSeconds, "orca-timeout", 7200, "Timeout (seconds) for PowerBuilder ORCA commands.")
 	rootCmd.PersistentFlags().StringVar(&orcaVars.serverAddr, "orca-server", "", "Orca server address to use. If not specified, a server will be started automatically.")
 	rootCmd.PersistentFlags().StringVar(&orcaVars.serverApiKey, "orca-apikey", "", "Orca server API key to use.")
+	rootCmd.PersistentFlags().IntVar(&orcaVars.sessions, "orca-sessions", orcapool.DefaultSize, "Number of ORCA sessions used in parallel by commands working on whole targets (export, diff, upgrade).")
+	rootCmd.PersistentFlags().UintVar(&orcaVars.sessionTimeout, "orca-session-timeout", 0, "Timeout (seconds) for the work on one library in a parallel ORCA session, 0 for none. A session which timed out is replaced by a new one.")
 	rootCmd.PersistentFlags().StringVarP(&basePath, "base-path", "b", b, "Working directory to use. Needed if you want to provide relative paths. If omitted, pbmanager will choose the current working directory as base path.")
 	rootCmd.PersistentFlags().StringVar(&flagLogLevel, "log-level", "warn", "Minimum log level to print. [debug, info, warn, error]")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "Result format [text, json]. With json, the command writes one result document (affected objects, warnings, timings, errors) to stdout and the log messages to stderr.")
 	rootCmd.Flags().Bool("version", false, "Print pbmanager version")
 }
 
@@ -105,3 +125,20 @@ func init() {
 func getVersion() string {
 	return fmt.Sprintf("v%s, BuildTime: %s", Version, BuildTime)
 }
+
+// newOrcaPool returns a pool of ORCA sessions started with the given options, its size and timeout are set with
+// --orca-sessions and --orca-session-timeout. Sessions which could not be started are reported as warnings.
+func newOrcaPool(pbVersion int, options ...func(*pborca.Orca)) *orcapool.Pool[backend.Backend] {
+	return orcapool.New(orcaVars.sessions, time.Duration(orcaVars.sessionTimeout)*time.Second,
+		func() (backend.Backend, error) {
+			o, err := pborca.NewOrca(pbVersion, options...)
+			if err != nil {
+				return nil, err
+			}
+			return o, nil
+		},
+		func(b backend.Backend) {
+			b.(*pborca.Orca).Close()
+		},
+	).WithWarnFunc(printWarnStderr)
+}
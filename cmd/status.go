@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/srcstatus"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status <pbl/pbt path>",
+	Short: "Compares the libraries with the exported sources",
+	Long: `Compares the objects of a library (or of all libraries of a target) with the sources written by export -s,
+i.e. the folder src/<library>.pbl/ beside the library. Encoding, BOM and line endings are ignored.
+Objects which are modified, only in the library or only in the source folder are listed and the command fails,
+so it can be used in a pre-commit hook. The libraries are read directly, ORCA is not needed.
+Examples:
+	- pbmanager status C:/a3/lib/a3.pbt
+	- pbmanager status --src-dir C:/a3/src C:/a3/lib/inf1.pbl`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pbxFilePath := args[0]
+		if !filepath.IsAbs(pbxFilePath) {
+			pbxFilePath = filepath.Join(basePath, pbxFilePath)
+		}
+		fileType := filepath.Ext(pbxFilePath)
+		if !utils.FileExists(pbxFilePath) || (fileType != ".pbl" && fileType != ".pbt") {
+			return fmt.Errorf("file %s does not exist or is not a pbl/pbt file", pbxFilePath)
+		}
+		srcDir, _ := cmd.Flags().GetString("src-dir")
+		if srcDir == "" {
+			srcDir = filepath.Join(filepath.Dir(pbxFilePath), "src")
+		} else if !filepath.IsAbs(srcDir) {
+			srcDir = filepath.Join(basePath, srcDir)
+		}
+
+		pblFiles := []string{pbxFilePath}
+		if fileType == ".pbt" {
+			pbt, err := orca.NewPbtFromFile(pbxFilePath)
+			if err != nil {
+				return err
+			}
+			pblFiles = pbt.LibList
+		}
+
+		var changes []srcstatus.Change
+		for _, pblFile := range pblFiles {
+			if !utils.FileExists(pblFile) {
+				printWarn(fmt.Sprintf("library %s does not exist, skipping", pblFile))
+				continue
+			}
+			sources, err := pbl.ReadSources(pblFile)
+			if err != nil {
+				return err
+			}
+			library := filepath.Base(pblFile)
+			c, err := srcstatus.Compare(library, sources, filepath.Join(srcDir, library))
+			if err != nil {
+				return err
+			}
+			changes = append(changes, c...)
+		}
+
+		labels := map[string]string{
+			srcstatus.Modified:  "modified:",
+			srcstatus.OnlyInPbl: "only in pbl:",
+			srcstatus.OnlyInSrc: "only in src:",
+		}
+		for _, c := range changes {
+			fmt.Printf("%-13s %s/%s\n", labels[c.Status], c.Library, c.Object)
+			addResultObject(c.Status, c.Library, c.Object)
+		}
+		if len(changes) > 0 {
+			return fmt.Errorf("%d objects differ between the libraries and %s, export them again", len(changes), srcDir)
+		}
+		fmt.Printf("libraries and %s are in sync\n", srcDir)
+		return nil
+	},
+}
+
+func init() {
+	statusCmd.Flags().String("src-dir", "", "folder containing the exported sources (default is <pbl/pbt path>/src)")
+	rootCmd.AddCommand(statusCmd)
+}
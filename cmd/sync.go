@@ -0,0 +1,387 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/importer"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/srcstatus"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/srcsync"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync [options] [<pbt path>]",
+	Short: "Synchronises the libraries of a target with the exported sources in both directions",
+	Long: `Compares the objects of the libraries of a target with the source folder (src/<library>.pbl/ as written by export -s)
+and with the state of the last synchronisation, which is stored next to the source folder (e.g. src.sync.json).
+Objects changed only in a library are exported, objects changed only in the source folder are imported and objects
+deleted on one side are deleted on the other side. Objects changed on both sides are reported as conflicts and are
+not touched, resolve them by exporting or importing the object and run sync again.
+Encoding, BOM and line endings are ignored. ORCA is only started if objects have to be imported or deleted.
+Examples:
+	- pbmanager sync C:/a3/lib/a3.pbt
+	- pbmanager sync --dry-run -b C:/a3/lib`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var pbtFile string
+		if len(args) == 1 {
+			pbtFile = args[0]
+		}
+		pbtFile, err = findPbtFilePath(basePath, pbtFile)
+		if err != nil {
+			return err
+		}
+		srcDir, _ := cmd.Flags().GetString("src-dir")
+		if srcDir == "" {
+			srcDir = filepath.Join(filepath.Dir(pbtFile), "src")
+		} else if !filepath.IsAbs(srcDir) {
+			srcDir = filepath.Join(basePath, srcDir)
+		}
+		enc, _ := cmd.Flags().GetString("encoding")
+		if _, ok := encodings[enc]; !ok {
+			return fmt.Errorf("unknown encoding %s, use one of %s", enc, strings.Join(maps.Keys(encodings), ", "))
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		pbtData, err := orca.NewPbtFromFile(pbtFile)
+		if err != nil {
+			return err
+		}
+		s := &syncer{pbtFile: pbtFile, srcDir: srcDir, encoding: enc, libs: make(map[string]string)}
+		err = s.read(pbtData.LibList)
+		if err != nil {
+			return err
+		}
+		state, err := srcsync.LoadState(srcsync.StatePath(srcDir))
+		if err != nil {
+			return err
+		}
+		steps := state.Plan(s.pbl, s.src)
+
+		var pending []srcsync.Step
+		conflicts := 0
+		for _, step := range steps {
+			switch step.Action {
+			case srcsync.Unchanged:
+				continue
+			case srcsync.Conflict:
+				fmt.Printf("%-10s %s: %s\n", step.Action, step.Name, step.Reason)
+				addResultObject(step.Action, "", step.Name)
+				conflicts++
+				continue
+			}
+			pending = append(pending, step)
+			if dryRun {
+				fmt.Printf("%-10s %s\n", step.Action, step.Name)
+				addResultObject(step.Action, "", step.Name)
+			}
+		}
+		if dryRun {
+			if len(pending) == 0 && conflicts == 0 {
+				fmt.Println("libraries and sources are in sync")
+			}
+			return nil
+		}
+
+		snapshotFile, err := takeSnapshot("sync", filepath.Dir(pbtFile), s.snapshotPaths(pending, state.Path())...)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = restoreHint(err, snapshotFile)
+		}()
+
+		defer s.close()
+		failed, err := s.apply(steps, state)
+		if serr := state.Save(); err == nil {
+			err = serr
+		}
+		if err != nil {
+			return err
+		}
+		if conflicts > 0 || failed > 0 {
+			return fmt.Errorf("sync finished with %d conflicts and %d failed objects", conflicts, failed)
+		}
+		fmt.Printf("sync finished (%d objects synchronised)\n", len(pending))
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.Flags().String("src-dir", "", "folder containing the exported sources (default is <pbt path>/src)")
+	syncCmd.Flags().String("encoding", "utf8", fmt.Sprintf("encoding of exported files, possible values: %s", maps.Keys(encodings)))
+	syncCmd.Flags().Bool("dry-run", false, "only list the objects which would be exported, imported or deleted")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// syncer holds both sides of a synchronisation, keyed by srcsync.Key.
+type syncer struct {
+	pbtFile  string
+	srcDir   string
+	encoding string
+	orca     backend.Backend
+
+	libs     map[string]string // lower case library name -> pbl file
+	pbl      map[string]srcsync.Version
+	pblSrc   map[string]string // sources of the library objects
+	src      map[string]srcsync.Version
+	srcFiles map[string]string // source files
+}
+
+// read reads the objects of the libraries and the source files of their source folders.
+func (s *syncer) read(libList []string) error {
+	s.pbl = make(map[string]srcsync.Version)
+	s.pblSrc = make(map[string]string)
+	s.src = make(map[string]srcsync.Version)
+	s.srcFiles = make(map[string]string)
+	for _, pblFile := range libList {
+		if !utils.FileExists(pblFile) {
+			printWarn(fmt.Sprintf("library %s does not exist, skipping", pblFile))
+			continue
+		}
+		library := filepath.Base(pblFile)
+		s.libs[strings.ToLower(library)] = pblFile
+		err := s.readLibrary(library)
+		if err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(filepath.Join(s.srcDir, library))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !regexSrcExt.MatchString(filepath.Ext(e.Name())) {
+				continue
+			}
+			path := filepath.Join(s.srcDir, library, e.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			src, err := srcstatus.Normalize(data)
+			if err != nil {
+				return fmt.Errorf("could not decode %s: %v", path, err)
+			}
+			key := srcsync.Key(library, e.Name())
+			s.src[key] = srcsync.Version{Name: library + "/" + e.Name(), Hash: srcsync.Hash(src)}
+			s.srcFiles[key] = path
+		}
+	}
+	return nil
+}
+
+// readLibrary (re)reads the objects of a library.
+func (s *syncer) readLibrary(library string) error {
+	sources, err := pbl.ReadSources(s.libs[strings.ToLower(library)])
+	if err != nil {
+		return err
+	}
+	for entry, src := range sources {
+		normalized, _ := srcstatus.Normalize([]byte(src))
+		key := srcsync.Key(library, entry)
+		s.pbl[key] = srcsync.Version{Name: library + "/" + entry, Hash: srcsync.Hash(normalized)}
+		s.pblSrc[key] = src
+	}
+	return nil
+}
+
+// snapshotPaths returns the files modified by the steps.
+func (s *syncer) snapshotPaths(steps []srcsync.Step, stateFile string) []snapshot.Path {
+	paths := []snapshot.Path{{Name: s.srcDir}, {Name: stateFile}}
+	seen := make(map[string]bool)
+	for _, step := range steps {
+		if step.Action != srcsync.Import && step.Action != srcsync.DeletePbl {
+			continue
+		}
+		library, _, _ := strings.Cut(step.Key, "/")
+		if !seen[library] {
+			seen[library] = true
+			paths = append(paths, snapshot.Path{Name: s.libs[library]})
+		}
+	}
+	return paths
+}
+
+// apply executes the steps and records the new state of the synchronised objects. It returns the number of
+// objects which could not be synchronised.
+func (s *syncer) apply(steps []srcsync.Step, state *srcsync.State) (int, error) {
+	failed := 0
+	var imports []srcsync.Step
+	for _, step := range steps {
+		library, entry, _ := strings.Cut(step.Name, "/")
+		var err error
+		switch step.Action {
+		case srcsync.Unchanged:
+			state.Record(step.Key, s.pbl[step.Key].Hash, s.src[step.Key].Hash)
+			continue
+		case srcsync.Conflict:
+			continue
+		case srcsync.Export:
+			err = s.export(step, library, entry)
+			if err == nil {
+				state.Record(step.Key, s.pbl[step.Key].Hash, s.pbl[step.Key].Hash)
+			}
+		case srcsync.DeleteSrc:
+			err = os.Remove(s.srcFiles[step.Key])
+			if err == nil {
+				state.Record(step.Key, "", "")
+			}
+		case srcsync.DeletePbl:
+			err = s.connect()
+			if err == nil {
+				err = s.orca.DeleteObj(s.libs[strings.ToLower(library)], entry)
+			}
+			if err == nil {
+				state.Record(step.Key, "", "")
+			}
+		case srcsync.Import:
+			imports = append(imports, step)
+			continue
+		}
+		if err != nil {
+			printWarn(fmt.Sprintf("%s of %s failed: %v", step.Action, step.Name, err))
+			failed++
+			continue
+		}
+		fmt.Printf("%-10s %s\n", step.Action, step.Name)
+		addResultObject(step.Action, library, entry)
+	}
+
+	if len(imports) == 0 {
+		return failed, nil
+	}
+	err := s.connect()
+	if err != nil {
+		return failed, err
+	}
+	// the objects are imported in the order of their dependencies
+	errs := make(map[string]error)
+	keys := make(map[string]string) // step key by library and object name
+	var sources []importer.Source
+	for _, step := range imports {
+		src, err := s.importSource(step)
+		if err != nil {
+			errs[step.Key] = err
+			continue
+		}
+		keys[strings.ToLower(filepath.Base(src.Library)+"/"+strings.TrimSuffix(src.Entry, filepath.Ext(src.Entry)))] = step.Key
+		sources = append(sources, src)
+	}
+	err = importer.ImportSources(s.orca, s.pbtFile, sources, nil)
+	var importErr *importer.ImportError
+	if errors.As(err, &importErr) {
+		for _, f := range importErr.Failures {
+			errs[keys[strings.ToLower(f.Library+"/"+f.Object)]] = f.Err
+		}
+	} else if err != nil {
+		return failed, err
+	}
+
+	// the library objects are read again, ORCA may store them slightly different
+	reread := make(map[string]bool)
+	for _, step := range steps {
+		library, _, _ := strings.Cut(step.Name, "/")
+		if step.Action != srcsync.Import || reread[strings.ToLower(library)] {
+			continue
+		}
+		reread[strings.ToLower(library)] = true
+		err = s.readLibrary(library)
+		if err != nil {
+			return failed, err
+		}
+	}
+	for _, step := range steps {
+		if step.Action != srcsync.Import {
+			continue
+		}
+		library, entry, _ := strings.Cut(step.Name, "/")
+		if err, ok := errs[step.Key]; ok {
+			printWarn(fmt.Sprintf("import of %s failed: %v", step.Name, err))
+			failed++
+			continue
+		}
+		state.Record(step.Key, s.pbl[step.Key].Hash, s.src[step.Key].Hash)
+		fmt.Printf("%-10s %s\n", step.Action, step.Name)
+		addResultObject(step.Action, library, entry)
+	}
+	return failed, nil
+}
+
+// export writes the source of a library object to the source folder.
+func (s *syncer) export(step srcsync.Step, library, entry string) error {
+	path, ok := s.srcFiles[step.Key]
+	if !ok {
+		path = filepath.Join(s.srcDir, library, entry)
+		err := os.MkdirAll(filepath.Dir(path), 0o775)
+		if err != nil {
+			return err
+		}
+	}
+	data, err := encode(s.pblSrc[step.Key], s.encoding)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o664)
+}
+
+// importSource reads the source file of an object to import into its library.
+func (s *syncer) importSource(step srcsync.Step) (importer.Source, error) {
+	library, entry, _ := strings.Cut(step.Name, "/")
+	data, err := os.ReadFile(s.srcFiles[step.Key])
+	if err != nil {
+		return importer.Source{}, err
+	}
+	src, err := srcstatus.Normalize(data)
+	if err != nil {
+		return importer.Source{}, err
+	}
+	return importer.Source{
+		Library: s.libs[strings.ToLower(library)],
+		Entry:   entry,
+		Src:     []byte(strings.ReplaceAll(src, "\n", "\r\n")),
+	}, nil
+}
+
+// connect starts ORCA if it is not running yet.
+func (s *syncer) connect() error {
+	if s.orca != nil {
+		return nil
+	}
+	if orcaVars.pbVersion != 22 {
+		return fmt.Errorf("currently, only PowerBuilder 22 is supported")
+	}
+	var opts []func(*pborca.Orca)
+	if orcaVars.pbRuntimeFolder != "" {
+		opts = append(opts, pborca.WithOrcaRuntime(orcaVars.pbRuntimeFolder))
+	}
+	opts = append(opts, pborca.WithOrcaTimeout(time.Duration(orcaVars.timeoutSeconds)*time.Second))
+	if orcaVars.serverAddr != "" {
+		opts = append(opts, pborca.WithOrcaServer(orcaVars.serverAddr, orcaVars.serverApiKey))
+	}
+	o, err := pborca.NewOrca(orcaVars.pbVersion, opts...)
+	if err != nil {
+		return err
+	}
+	s.orca = o
+	return nil
+}
+
+func (s *syncer) close() {
+	if o, ok := s.orca.(*pborca.Orca); ok {
+		o.Close()
+	}
+}
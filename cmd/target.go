@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backport"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+	"github.com/spf13/cobra"
+)
+
+// targetCmd groups the commands working on pbt files
+var targetCmd = &cobra.Command{
+	Use:   "target",
+	Short: "Commands for PowerBuilder targets (pbt files)",
+}
+
+// targetSortCmd represents the target sort command
+var targetSortCmd = &cobra.Command{
+	Use:   "sort <pbt path>",
+	Short: "Sorts the library list of a target",
+	Long: `Sorts the library list of a target by the library order of the application profile (see profile) or by the
+library list of another target (--order-from). Libraries which are not part of the order are placed first and reported.
+Examples:
+	- pbmanager target sort C:/a3/lib/a3.pbt
+	- pbmanager target sort --order-from C:/a3/lib/a3.pbt C:/a3/lib/a3_test.pbt
+	- pbmanager target sort --check C:/a3/lib/a3.pbt`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pbtFilePath := args[0]
+		if !filepath.IsAbs(pbtFilePath) {
+			pbtFilePath = filepath.Join(basePath, pbtFilePath)
+		}
+		if !utils.FileExists(pbtFilePath) || filepath.Ext(pbtFilePath) != ".pbt" {
+			return fmt.Errorf("file %s does not exist or is not a pbt file", pbtFilePath)
+		}
+		pbtData, err := orca.NewPbtFromFile(pbtFilePath)
+		if err != nil {
+			return err
+		}
+		profileName, _ := cmd.Flags().GetString("profile")
+		orderFrom, _ := cmd.Flags().GetString("order-from")
+		libraryOrder, err := getLibraryOrder(profileName, orderFrom, pbtData.AppName)
+		if err != nil {
+			return err
+		}
+
+		src, err := os.ReadFile(pbtFilePath)
+		if err != nil {
+			return err
+		}
+		sorted, unlisted, err := backport.SortPbtLibList(src, libraryOrder)
+		if err != nil {
+			return fmt.Errorf("%s: %v", pbtFilePath, err)
+		}
+		if len(unlisted) > 0 {
+			printWarn(fmt.Sprintf("libraries not in any list (placed first): %s", strings.Join(unlisted, ", ")))
+		}
+		if bytes.Equal(src, sorted) {
+			fmt.Println("library list is sorted already")
+			return nil
+		}
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			return fmt.Errorf("library list of %s is not sorted", pbtFilePath)
+		}
+
+		snapshotFile, err := takeSnapshot("target-sort", filepath.Dir(pbtFilePath), snapshot.Path{Name: pbtFilePath})
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(pbtFilePath, sorted, 0o664)
+		if err != nil {
+			return restoreHint(err, snapshotFile)
+		}
+		fmt.Println("library list sorted")
+		addResultObject("sorted", "", pbtFilePath)
+		return nil
+	},
+}
+
+func init() {
+	targetSortCmd.Flags().String("profile", "", "application profile defining the library order (see profile). If omitted, the profile of the application is used.")
+	targetSortCmd.Flags().String("order-from", "", "take the library order from the library list of this target instead of the profile")
+	targetSortCmd.Flags().Bool("check", false, "do not change the target, fail if the library list is not sorted")
+	targetCmd.AddCommand(targetSortCmd)
+	rootCmd.AddCommand(targetCmd)
+}
+
+// getLibraryOrder returns the library order of the target orderFrom or, if orderFrom is empty, of the profile.
+func getLibraryOrder(profileName, orderFrom, appName string) ([][]string, error) {
+	if orderFrom == "" {
+		prof, err := selectProfile(profileName, appName)
+		if err != nil {
+			return nil, err
+		}
+		return prof.LibraryOrder, nil
+	}
+	if !filepath.IsAbs(orderFrom) {
+		orderFrom = filepath.Join(basePath, orderFrom)
+	}
+	pbtData, err := orca.NewPbtFromFile(orderFrom)
+	if err != nil {
+		return nil, fmt.Errorf("could not read library order from %s: %v", orderFrom, err)
+	}
+	return backport.LibraryOrderFromLibList(pbtData.LibList), nil
+}
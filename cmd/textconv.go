@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/spf13/cobra"
+)
+
+// textconvCmd represents the textconv command
+var textconvCmd = &cobra.Command{
+	Use:   "textconv <pbl path>",
+	Short: "Prints the sources of all objects of a pbl file",
+	Long: `Prints the sources of all objects of a pbl file, sorted by name and separated by a header line per object.
+It is used as git textconv filter (see config --register-git), so git diff and git log -p show the PowerScript changes.
+The library is read directly, ORCA is not needed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pblFilePath := args[0]
+		if !filepath.IsAbs(pblFilePath) {
+			pblFilePath = filepath.Join(basePath, pblFilePath)
+		}
+		sources, err := pbl.ReadSources(pblFilePath)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for name := range sources {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return strings.ToLower(names[i]) < strings.ToLower(names[j])
+		})
+		for _, name := range names {
+			src := strings.ReplaceAll(sources[name], "\r\n", "\n")
+			if !strings.HasSuffix(src, "\n") {
+				src += "\n"
+			}
+			fmt.Printf("==== %s ====\n%s", name, src)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(textconvCmd)
+}
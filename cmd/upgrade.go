@@ -6,9 +6,16 @@ import (
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/buildlog"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/profile"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/snapshot"
 	"github.com/informaticon/dev.win.base.pbmanager/migrate"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
@@ -31,6 +38,25 @@ You have to specify the path to the PowerBuilder target (e.g. C:/a3/lib/a3.pbt).
 		if !utils.FileExists(args[0]) {
 			return fmt.Errorf("pbt file %s does not exist", args[0])
 		}
+		pbtFile := args[0]
+		journalInputs := func() ([]string, error) {
+			pbtData, err := orca.NewPbtFromFile(pbtFile)
+			if err != nil {
+				return nil, err
+			}
+			return append([]string{pbtFile}, pbtData.LibList...), nil
+		}
+		if status, _ := cmd.Flags().GetBool("status"); status {
+			journal, err := migrate.LoadJournal(pbtFile, journalInputs)
+			if err != nil {
+				return err
+			}
+			if jsonOutput() {
+				return printJSON(journal)
+			}
+			fmt.Print(journal.Status(upgradeSteps))
+			return nil
+		}
 		if orcaVars.pbVersion != 22 {
 			return fmt.Errorf("currently, only PowerBuilder 22 is supported")
 		}
@@ -47,32 +73,116 @@ You have to specify the path to the PowerBuilder target (e.g. C:/a3/lib/a3.pbt).
 		if err != nil {
 			return err
 		}
-		if mode, _ := cmd.Flags().GetString("mode"); mode == "full" {
-			err = doUpgrade(pbtData, orcaVars.pbVersion, opts...)
+		checkLibFormats(pbtData, printWarn)
+		profileName, _ := cmd.Flags().GetString("profile")
+		prof, err := selectProfile(profileName, pbtData.AppName)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Using profile %s\n", prof.Name)
+		patchFiles, _ := cmd.Flags().GetStringSlice("patch-file")
+		patches, err := loadPatchFiles(patchFiles)
+		if err != nil {
+			return err
+		}
+		err = checkReportFormat(true)
+		if err != nil {
+			return err
+		}
+		dryRunFormat, _ := cmd.Flags().GetString("dry-run-format")
+		if dryRunFormat != "unified" && dryRunFormat != "json" {
+			return fmt.Errorf("invalid dry run format %s, use unified or json", dryRunFormat)
+		}
+		mode, _ := cmd.Flags().GetString("mode")
+		resume, _ := cmd.Flags().GetBool("resume")
+		isDryRun, _ := cmd.Flags().GetBool("dry-run")
+		if resume && (mode != "full" || isDryRun) {
+			return fmt.Errorf("--resume is only supported for the mode full without --dry-run")
+		}
+
+		var journal *migrate.Journal
+		if resume {
+			journal, err = migrate.LoadJournal(pbtFile, journalInputs)
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(2)
+				return err
+			}
+			if journal.Done(upgradeSteps) {
+				return fmt.Errorf("the upgrade of %s is done already", pbtFile)
+			}
+			force, _ := cmd.Flags().GetBool("force")
+			err = journal.CheckResume(force, printWarn)
+			if err != nil {
+				return err
+			}
+		} else if mode == "full" && !isDryRun {
+			journal = migrate.NewJournal(pbtFile, journalInputs)
+			if old, err := migrate.LoadJournal(pbtFile, journalInputs); err == nil {
+				// libraries added temporarily by an earlier run still have to be removed
+				journal.TempLibs = old.TempLibs
+			}
+		}
+
+		var dryRun *migrate.DryRun
+		var snapshotFile string
+		if isDryRun {
+			dryRun = migrate.StartDryRun()
+		} else {
+			snapshotFile, err = takeSnapshot("upgrade", pbtData.BasePath, upgradeSnapshotPaths(pbtData)...)
+			if err != nil {
+				return err
+			}
+		}
+		if mode == "full" {
+			err = doUpgrade(pbtData, prof, patches, journal, orcaVars.pbVersion, opts...)
+			if rerr := writeReport(filepath.Base(pbtFile)); rerr != nil {
+				printWarn(fmt.Sprintf("could not write compiler report: %v", rerr))
+			}
+			if len(reportVars.messages) > 0 {
+				result.Data = reportVars.messages
+			}
+			if err != nil {
+				fmt.Println(restoreHint(err, snapshotFile))
+				if journal != nil {
+					fmt.Printf("Run upgrade with --resume to continue at the failed step (see %s)\n", migrate.JournalPath(pbtFile))
+				}
+				exit(2, err)
 			}
 		} else {
-			err = doPatch(pbtData, mode, orcaVars.pbVersion, opts...)
+			err = doPatch(pbtData, prof, mode, patches, orcaVars.pbVersion, opts...)
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(2)
+				fmt.Println(restoreHint(err, snapshotFile))
+				exit(2, err)
 			}
 		}
 
 		if removeExe, _ := cmd.Flags().GetBool("remove-exe"); removeExe {
-			if utils.FileExists(filepath.Join(pbtData.BasePath, pbtData.AppName+".exe")) {
-				os.Remove(filepath.Join(pbtData.BasePath, pbtData.AppName+".exe"))
+			exeFile := filepath.Join(pbtData.BasePath, pbtData.AppName+".exe")
+			if utils.FileExists(exeFile) && !migrate.SkipInDryRun("delete %s", exeFile) {
+				os.Remove(exeFile)
 			}
 		}
+		if dryRun != nil {
+			dryRun.Finish()
+			if dryRunFormat == "json" {
+				return printJSON(dryRun.Changes)
+			}
+			fmt.Print(dryRun.Unified())
+		}
 		return nil
 	},
 }
 
 func init() {
 	upgradeCmd.Flags().String("mode", "full", "one of [full|patches|FixArf|FixFinDw|FixSqla17], (full=upgrade with patches, patches=only patches, others: fix a particular bug)")
+	upgradeCmd.Flags().String("profile", "", "application profile defining the fixes, project moves and workspace file (see profile). If omitted, the profile of the application is used.")
 	upgradeCmd.Flags().Bool("remove-exe", false, "remove existing target exe after migration")
+	upgradeCmd.Flags().Bool("resume", false, "continue a failed upgrade at the failed step (see the journal next to the pbt file)")
+	upgradeCmd.Flags().Bool("force", false, "with --resume: continue even if the last step did not finish")
+	upgradeCmd.Flags().Bool("status", false, "show the progress of the last upgrade and exit")
+	upgradeCmd.Flags().Bool("dry-run", false, "do not change anything, print the changes the upgrade would make")
+	upgradeCmd.Flags().String("dry-run-format", "unified", "output format of --dry-run, one of [unified|json]")
+	upgradeCmd.Flags().StringSlice("patch-file", nil, "patch files (see patch apply) to apply after the built-in patches")
+	addReportFlags(upgradeCmd)
 	rootCmd.AddCommand(upgradeCmd)
 }
 
@@ -86,18 +196,20 @@ func buildWithPbc(pbtPath string) string {
 		return err.Error()
 	}
 	log, err := compiler.Run()
+	reportVars.messages = append(reportVars.messages, buildlog.ParseText(log)...)
 	if err != nil {
 		return fmt.Sprintf("Build with pbc220.exe failed, compiler log:\n%s", log)
 	}
 	return fmt.Sprintf("Build with pbc220.exe was successfull, compiler log:\n%s", log)
 }
 
-func doPatch(pbtData *orca.Pbt, patchType string, pbVersion int, options ...func(*pborca.Orca)) error {
+func doPatch(pbtData *orca.Pbt, prof *profile.Profile, patchType string, patches []*migrate.Patch, pbVersion int, options ...func(*pborca.Orca)) error {
 	orca, err := pborca.NewOrca(pbVersion, options...)
 	if err != nil {
 		return err
 	}
 	defer orca.Close()
+	sessions := newOrcaPool(pbVersion, options...)
 
 	switch strings.ToLower(patchType) {
 	case "fixsqla17":
@@ -105,7 +217,7 @@ func doPatch(pbtData *orca.Pbt, patchType string, pbVersion int, options ...func
 		if err != nil {
 			return err
 		}
-		err = migrate.FixSqla17ByteString(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+		err = migrate.FixSqla17ByteString(pbtData.BasePath, pbtData.AppName, sessions, printWarn)
 		if err != nil {
 			return err
 		}
@@ -113,7 +225,7 @@ func doPatch(pbtData *orca.Pbt, patchType string, pbVersion int, options ...func
 	case "fixarf":
 		return migrate.FixArf(pbtData.BasePath, pbtData.AppName, orca, printWarn)
 	case "fixfindw":
-		return migrate.FixDatawindows(pbtData, orca, migrate.DwfixFinScrollbar, printWarn)
+		return migrate.FixDatawindows(pbtData, sessions, migrate.DwfixFinScrollbar, printWarn)
 	}
 
 	err = migrate.InsertNewPbdom(pbtData)
@@ -133,28 +245,24 @@ func doPatch(pbtData *orca.Pbt, patchType string, pbVersion int, options ...func
 		return err
 	}
 
-	for i, proj := range pbtData.Projects {
-		if proj.Name == "a3" && proj.PblFile == "inf2.pbl" {
-			_, err := orca.GetObjSource(filepath.Join(pbtData.BasePath, proj.PblFile), "a3.srj")
-			if err == nil {
-				continue
-			}
-			err = migrate.FixProjLib(filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt"), proj.Name, "inf2.pbl", "inf1.pbl")
-			if err != nil {
-				return err
-			}
-			pbtData.Projects[i].PblFile = "inf1.pbl"
-		}
+	err = moveProjects(pbtData, prof, orca)
+	if err != nil {
+		return err
 	}
 
-	if pbtData.AppName == "a3" || pbtData.AppName == "loh" {
-		err = applyPostPatches(pbtData, orca)
+	if len(prof.Fixes) > 0 {
+		err = applyPostPatches(pbtData, prof, orca, sessions)
 		if err != nil {
 			return err
 		}
 		fmt.Println("Applying patches done")
 	} else {
-		fmt.Println("Skipping applying patches (not an a3/lohn project) ")
+		fmt.Printf("Skipping applying patches (profile %s has no fixes)\n", prof.Name)
+	}
+
+	err = applyPatchFiles(pbtData, patches, orca)
+	if err != nil {
+		return err
 	}
 
 	libs3rd.CleanupLibs()
@@ -163,78 +271,150 @@ func doPatch(pbtData *orca.Pbt, patchType string, pbVersion int, options ...func
 	return nil
 }
 
-func doUpgrade(pbtData *orca.Pbt, pbVersion int, options ...func(*pborca.Orca)) error {
+// upgradeSnapshotPaths returns the paths modified by an upgrade: the files in the folder of the target
+// (pbt, pbl, pb.ini, ...), the pbdk folder and the libraries outside of the folder.
+func upgradeSnapshotPaths(pbtData *orca.Pbt) []snapshot.Path {
+	paths := []snapshot.Path{
+		{Name: pbtData.BasePath, Flat: true},
+		{Name: filepath.Join(pbtData.BasePath, "pbdk")},
+	}
+	for _, lib := range pbtData.LibList {
+		paths = append(paths, snapshot.Path{Name: lib})
+	}
+	return paths
+}
+
+// upgradeSteps are the names of the steps of doUpgrade as recorded in the journal
+var upgradeSteps = []string{"insert-exf", "add-libs", "fix-projects", "pre-patches", "migrate", "post-patches", "patch-files", "full-build", "cleanup"}
+
+func doUpgrade(pbtData *orca.Pbt, prof *profile.Profile, patches []*migrate.Patch, journal *migrate.Journal, pbVersion int, options ...func(*pborca.Orca)) error {
 	orca, err := pborca.NewOrca(pbVersion, options...)
 	if err != nil {
 		return err
 	}
 	defer orca.Close()
+	sessions := newOrcaPool(pbVersion, options...)
 
-	err = migrate.InsertExfInPbt(pbtData, orca)
+	err = runStep(journal, "insert-exf", func() error {
+		return migrate.InsertExfInPbt(pbtData, orca)
+	})
 	if err != nil {
 		return err
 	}
 
 	var libs3rd migrate.Libs3rd
+	if journal != nil {
+		// libraries added by a previous run have to be removed at the end
+		libs3rd.AddCopiedFiles(journal.TempLibs...)
+	}
 
-	err = libs3rd.AddMissingLibs(pbtData)
+	err = runStep(journal, "add-libs", func() error {
+		err := libs3rd.AddMissingLibs(pbtData)
+		if journal != nil {
+			journal.TempLibs = libs3rd.CopiedFiles()
+		}
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	for i, proj := range pbtData.Projects {
-		if proj.Name == "a3" && proj.PblFile == "inf2.pbl" {
-			_, err := orca.GetObjSource(filepath.Join(pbtData.BasePath, proj.PblFile), "a3.srj")
-			if err == nil {
-				continue
-			}
-			err = migrate.FixProjLib(filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt"), proj.Name, "inf2.pbl", "inf1.pbl")
-			if err != nil {
-				return err
-			}
-			pbtData.Projects[i].PblFile = "inf1.pbl"
-		}
+	err = runStep(journal, "fix-projects", func() error {
+		return moveProjects(pbtData, prof, orca)
+	})
+	if err != nil {
+		return err
 	}
 
-	err = applyPrePatches(pbtData, orca, printWarn)
+	err = runStep(journal, "pre-patches", func() error {
+		err := applyPrePatches(pbtData, orca, printWarn)
+		if err != nil && !migrate.SkipInDryRun("build %s with pbc", pbtData.GetPath()) {
+			fmt.Println(buildWithPbc(pbtData.GetPath()))
+		}
+		return err
+	})
 	if err != nil {
-		fmt.Println(buildWithPbc(pbtData.GetPath()))
 		return err
 	}
 
-	err = migrateToPb220(pbtData, orca)
+	err = runStep(journal, "migrate", func() error {
+		err := migrateToPb220(pbtData, orca)
+		if err != nil {
+			if !migrate.SkipInDryRun("build %s with pbc", pbtData.GetPath()) {
+				fmt.Println(buildWithPbc(pbtData.GetPath()))
+			}
+			return err
+		}
+		fmt.Println("Migration to Pb220 done")
+		return nil
+	})
 	if err != nil {
-		fmt.Println(buildWithPbc(pbtData.GetPath()))
 		return err
 	}
-	fmt.Println("Migration to Pb220 done")
 
-	if pbtData.AppName == "a3" || pbtData.AppName == "loh" {
-		err = applyPostPatches(pbtData, orca)
+	err = runStep(journal, "post-patches", func() error {
+		if len(prof.Fixes) == 0 {
+			fmt.Printf("Skipping applying patches (profile %s has no fixes)\n", prof.Name)
+			return nil
+		}
+		err := applyPostPatches(pbtData, prof, orca, sessions)
 		if err != nil {
 			return err
 		}
 		fmt.Println("Applying patches done")
-	} else {
-		fmt.Println("Skipping applying patches (not an a3/lohn project) ")
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	dat, err := orca.FullBuildTarget(filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt"))
+	err = runStep(journal, "patch-files", func() error {
+		return applyPatchFiles(pbtData, patches, orca)
+	})
 	if err != nil {
-		return fmt.Errorf("%s\n%v", strings.Join(dat, "\n"), err)
+		return err
 	}
 
-	fmt.Println("Full Build done")
-	libs3rd.CleanupLibs()
-	fmt.Println("Deleting helper libs done")
+	err = runStep(journal, "full-build", func() error {
+		if migrate.SkipInDryRun("full build of %s", pbtData.GetPath()) {
+			return nil
+		}
+		dat, err := orca.FullBuildTarget(filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt"))
+		reportVars.messages = append(reportVars.messages, buildlog.Parse(dat)...)
+		if err != nil {
+			return fmt.Errorf("%s\n%v", strings.Join(dat, "\n"), err)
+		}
+		fmt.Println("Full Build done")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return runStep(journal, "cleanup", func() error {
+		libs3rd.CleanupLibs()
+		fmt.Println("Deleting helper libs done")
+		return nil
+	})
+}
+
+// runStep runs a step of the upgrade, it is recorded in the journal if there is one.
+func runStep(journal *migrate.Journal, name string, step func() error) error {
+	defer addResultTiming(name, time.Now())
+	if journal == nil {
+		return step()
+	}
+	return journal.Run(name, step)
 }
 
-func migrateToPb220(pbtData *orca.Pbt, orca *pborca.Orca) error {
+func migrateToPb220(pbtData *orca.Pbt, orca backend.Backend) error {
 	pbtFilePath := filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt")
 
+	if migrate.SkipInDryRun("migrate %s to PowerBuilder %d", pbtFilePath, orcaVars.pbVersion) {
+		return nil
+	}
 	out, err := orca.MigrateTarget(pbtFilePath)
+	reportVars.messages = append(reportVars.messages, buildlog.Parse(out)...)
 	if err != nil {
 		return fmt.Errorf("migration of %s failed, compiler log\n%s\nORCA Error:%v", pbtFilePath, strings.Join(out, "\n"), err)
 	}
@@ -242,7 +422,7 @@ func migrateToPb220(pbtData *orca.Pbt, orca *pborca.Orca) error {
 	return nil
 }
 
-func applyPrePatches(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)) (err error) {
+func applyPrePatches(pbtData *orca.Pbt, orca backend.Backend, warnFunc func(string)) (err error) {
 	err = migrate.InsertNewPbdom(pbtData)
 	if err != nil {
 		return
@@ -253,10 +433,10 @@ func applyPrePatches(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)
 	pbtFile := filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt")
 
 	objName := "lif1_u_metratec_base"
-	src, err := orca.GetObjSource(pblFile, objName)
+	src, err := migrate.GetObjSource(orca, pblFile, objName)
 	if err != nil {
 		objName = "inf1_u_metratec_base"
-		src, err = orca.GetObjSource(pblFile, objName)
+		src, err = migrate.GetObjSource(orca, pblFile, objName)
 		if err != nil {
 			return
 		}
@@ -269,7 +449,7 @@ func applyPrePatches(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)
 
 	warnFunc("Start PB115 pre migration")
 	src = regex.ReplaceAllString(src, `${1}CI${2}`)
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = migrate.SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		fmt.Printf("info: SetObjSource for preMigration of PB115 failed, this can be ignored (%v)\n", err)
 	}
@@ -279,7 +459,7 @@ func applyPrePatches(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)
 		return
 	}
 
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = migrate.SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		return
 	}
@@ -288,96 +468,119 @@ func applyPrePatches(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)
 	return nil
 }
 
-func applyPostPatches(pbtData *orca.Pbt, orca *pborca.Orca) (err error) {
-	if pbtData.AppName == "a3" {
-		// lohn has no registry object
-		err = migrate.FixRegistry(pbtData.BasePath, pbtData.AppName, orca, printWarn)
-		if err != nil {
-			return
+// moveProjects moves the projects of the profile to their new library if the project object does not exist in
+// the old library.
+func moveProjects(pbtData *orca.Pbt, prof *profile.Profile, orca backend.Backend) error {
+	for i, proj := range pbtData.Projects {
+		for _, move := range prof.ProjectMoves {
+			if proj.Name != move.Project || proj.PblFile != move.From {
+				continue
+			}
+			_, err := migrate.GetObjSource(orca, filepath.Join(pbtData.BasePath, proj.PblFile), proj.Name+".srj")
+			if err == nil {
+				continue
+			}
+			err = migrate.FixProjLib(filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt"), proj.Name, move.From, move.To)
+			if err != nil {
+				return err
+			}
+			pbtData.Projects[i].PblFile = move.To
 		}
 	}
+	return nil
+}
 
-	err = migrate.FixRuntimeFolder(pbtData, orca, printWarn)
-	if err != nil {
-		return
-	}
-
-	for _, proj := range pbtData.Projects {
-		err = migrate.ChangePbdomBuildOptions(proj.PblFile, proj.Name, pbtData, orca, printWarn)
-		if err != nil && slices.Contains([]string{"a3", "loh"}, proj.Name) {
-			return
+// applyPostPatches applies the fixes of the profile in their order. Fixes working on all libraries use the
+// sessions of the pool.
+func applyPostPatches(pbtData *orca.Pbt, prof *profile.Profile, orca backend.Backend, sessions *orcapool.Pool[backend.Backend]) error {
+	for _, fix := range prof.Fixes {
+		err := applyFix(fix, pbtData, prof, orca, sessions)
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	err = migrate.FixLifProcess(pbtData.BasePath, pbtData.AppName, orca, printWarn)
-	if err != nil {
-		return
-	}
-
-	err = migrate.FixHttpClient(pbtData.BasePath, pbtData.AppName, orca, printWarn)
-	if err != nil {
-		return
-	}
-
-	err = migrate.AddMirrorObjects(pbtData.BasePath, pbtData.AppName, orca, printWarn)
-	if err != nil {
-		return
-	}
-
-	uncommonFiles, err := migrate.CheckForUncommonFiles(pbtData.BasePath)
-	if err != nil {
-		return
-	}
-	if len(uncommonFiles) > 0 {
-		printWarn(fmt.Sprintf("uncommon files were found: %s", uncommonFiles))
-	}
-
-	err = migrate.RemoveFiles(pbtData.BasePath, printWarn)
-	if err != nil {
-		return
-	}
-
-	err = migrate.InsertNewPbdk(pbtData.BasePath)
-	if err != nil {
-		return
-	}
-
-	if pbtData.AppName == "loh" {
-		err = migrate.FixPayrollXmlDecl(pbtData.BasePath, pbtData.AppName, orca, printWarn)
-		if err != nil {
-			return
+func applyFix(fix string, pbtData *orca.Pbt, prof *profile.Profile, orca backend.Backend, sessions *orcapool.Pool[backend.Backend]) error {
+	switch fix {
+	case profile.FixRegistry:
+		return migrate.FixRegistry(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixRuntimeFolder:
+		return migrate.FixRuntimeFolder(pbtData, orca, printWarn)
+	case profile.FixPbdomBuildOptions:
+		for _, proj := range pbtData.Projects {
+			err := migrate.ChangePbdomBuildOptions(proj.PblFile, proj.Name, pbtData, orca, printWarn)
+			if err != nil && slices.Contains(prof.RequiredProjects, proj.Name) {
+				return err
+			}
 		}
-		err = migrate.FixPayrollXmlEncoding(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+		return nil
+	case profile.FixLifProcess:
+		return migrate.FixLifProcess(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixHttpClient:
+		return migrate.FixHttpClient(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixMirrorObjects:
+		return migrate.AddMirrorObjects(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixUncommonFiles:
+		uncommonFiles, err := migrate.CheckForUncommonFiles(pbtData.BasePath)
 		if err != nil {
-			return
+			return err
 		}
+		if len(uncommonFiles) > 0 {
+			printWarn(fmt.Sprintf("uncommon files were found: %s", uncommonFiles))
+		}
+		return nil
+	case profile.FixRemoveFiles:
+		return migrate.RemoveFiles(pbtData.BasePath, printWarn)
+	case profile.FixPbdk:
+		return migrate.InsertNewPbdk(pbtData.BasePath)
+	case profile.FixPayrollXmlDecl:
+		return migrate.FixPayrollXmlDecl(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixPayrollXmlEncoding:
+		return migrate.FixPayrollXmlEncoding(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixArf:
+		return migrate.FixArf(pbtData.BasePath, pbtData.AppName, orca, printWarn)
+	case profile.FixPbInit:
+		return migrate.FixPbInit(pbtData.BasePath, printWarn)
+	case profile.FixPbw:
+		template := prof.PbwTemplate
+		if template != "" && !filepath.IsAbs(template) {
+			template = filepath.Join(basePath, template)
+		}
+		return migrate.ReplacePbwFile(filepath.Join(pbtData.BasePath, prof.PbwFile), template)
+	case profile.FixDatawindows:
+		return migrate.FixDatawindows(pbtData, sessions, migrate.DwfixAll, printWarn)
 	}
+	return fmt.Errorf("unknown fix %s", fix)
+}
 
-	err = migrate.FixArf(pbtData.BasePath, pbtData.AppName, orca, printWarn)
-	if err != nil {
-		return
-	}
-
-	err = migrate.FixPbInit(pbtData.BasePath, printWarn)
-	if err != nil {
-		return
-	}
-
-	if pbtData.AppName == "loh" {
-		err = migrate.ReplacePayrollPbwFile(filepath.Join(pbtData.BasePath, "a3_lohn.pbw"))
+// checkLibFormats inspects the libraries of the target and warns about libraries which are probably already
+// migrated or cannot be migrated (ANSI libraries of PB9 and older).
+func checkLibFormats(pbtData *orca.Pbt, warnFunc func(string)) {
+	for _, lib := range pbtData.LibList {
+		if !utils.FileExists(lib) {
+			continue
+		}
+		l, err := pbl.Open(lib)
 		if err != nil {
-			return
+			warnFunc(fmt.Sprintf("could not inspect library %s: %v", filepath.Base(lib), err))
+			continue
+		}
+		if !l.Unicode {
+			warnFunc(fmt.Sprintf("library %s is an ANSI library (PowerBuilder 9 or older)", filepath.Base(lib)))
+			continue
+		}
+		if release := l.PbRelease(); release == strconv.Itoa(orcaVars.pbVersion) {
+			warnFunc(fmt.Sprintf("library %s seems to be migrated to PowerBuilder %s already", filepath.Base(lib), release))
 		}
 	}
-
-	err = migrate.FixDatawindows(pbtData, orca, migrate.DwfixAll, printWarn)
-	if err != nil {
-		return
-	}
-
-	return
 }
 
+// printWarn prints a warning and adds it to the result of the command (see --output).
 func printWarn(message string) {
+	resultMu.Lock()
+	result.Warnings = append(result.Warnings, message)
+	resultMu.Unlock()
 	fmt.Println("WARN: ", message)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
@@ -66,6 +67,10 @@ func isPblPbtFile(path string) bool {
 	if filepath.Ext(path) == ".pbl" || filepath.Ext(path) == ".pbt" {
 		return true
 	}
+	// temp file of a git merge driver (.merge_file_XXXXXX), git always passes pbl files because of .gitattributes
+	if regexGitMergeFile.MatchString(filepath.Base(path)) {
+		return true
+	}
 	cs := strings.Split(filepath.Base(path), ".")
 	if len(cs) >= 3 {
 		return (cs[len(cs)-2] == "pbl" || cs[len(cs)-2] == "pbt") && cs[len(cs)-1][:1] == "r"
@@ -73,6 +78,8 @@ func isPblPbtFile(path string) bool {
 	return false
 }
 
+var regexGitMergeFile = regexp.MustCompile(`(?i)^\.merge_file_[a-z0-9]+$`)
+
 func getCleanPblPbtFilePath(basePath, path string) (string, error) {
 	path = filepath.Clean(path)
 	if !filepath.IsAbs(path) {
@@ -0,0 +1,31 @@
+// Package backend defines the operations pbmanager needs from ORCA, so the code working on libraries can be
+// tested with Fake instead of a PowerBuilder runtime.
+package backend
+
+import (
+	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+)
+
+// Backend reads and writes the objects of libraries and builds targets. It is implemented by *pborca.Orca
+// and by Fake.
+type Backend interface {
+	// GetObjList returns the objects of a library by object name (without extension).
+	GetObjList(pbl string) (map[string]*orca.ObjArr, error)
+	// GetObjSource returns the source of an object, name may be given with or without extension.
+	GetObjSource(pbl, name string) (string, error)
+	// SetObjSource imports and compiles the source of an object.
+	SetObjSource(pbt, pbl, name string, src []byte) error
+	// SetObjBinary imports the OLE data of an object.
+	SetObjBinary(pbt, pbl, name string, data []byte) error
+	// DeleteObj deletes an object, name is given with extension.
+	DeleteObj(pbl, name string) error
+	// FullBuildTarget builds all objects of a target and returns the compiler messages.
+	FullBuildTarget(pbt string) ([]string, error)
+	// MigrateTarget migrates a target to the current PowerBuilder version and returns the compiler messages.
+	MigrateTarget(pbt string) ([]string, error)
+	// GetFilenameOfSrc returns the file name of an exported source, e.g. u_base.sru.
+	GetFilenameOfSrc(src string) (string, error)
+}
+
+var _ Backend = (*pborca.Orca)(nil)
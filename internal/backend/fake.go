@@ -0,0 +1,267 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+)
+
+// Actions of a Write
+const (
+	WriteSource  = "source"
+	WriteBinary  = "binary"
+	WriteDelete  = "delete"
+	WriteBuild   = "build"
+	WriteMigrate = "migrate"
+)
+
+// Write is a change made through a Fake.
+type Write struct {
+	Action  string
+	Library string // pbl, or pbt for WriteBuild and WriteMigrate
+	Object  string // entry name with extension
+	Data    []byte // source or OLE data
+}
+
+// Fake is a Backend keeping the libraries in memory. It records all writes and can simulate compile errors.
+// Library paths and object names are compared case insensitive, like ORCA does on Windows.
+// A Fake may be used by several goroutines (e.g. the sessions of an orcapool.Pool).
+type Fake struct {
+	// Compile is called before an object source is imported. If it returns an error, the object is not changed
+	// and SetObjSource returns the error, like ORCA does for compile errors. Compile may be nil.
+	Compile func(pbl, name string, src []byte) error
+	// BuildMessages and BuildErr are returned by FullBuildTarget and MigrateTarget.
+	BuildMessages []string
+	BuildErr      error
+
+	mu     sync.Mutex
+	libs   map[string]map[string]*fakeEntry
+	writes []Write
+}
+
+type fakeEntry struct {
+	name   string // with extension
+	src    string
+	binary []byte
+}
+
+var regexExportHeader = regexp.MustCompile(`\$PBExportHeader\$([^\r\n]+)`)
+
+// NewFake returns a Fake with the given libraries, each one a map of entry names (with extension, e.g.
+// u_base.sru) to sources.
+func NewFake(libs map[string]map[string]string) *Fake {
+	f := &Fake{libs: make(map[string]map[string]*fakeEntry)}
+	for pbl, entries := range libs {
+		f.AddLibrary(pbl)
+		for name, src := range entries {
+			f.libs[libKey(pbl)][strings.ToLower(name)] = &fakeEntry{name: name, src: src}
+		}
+	}
+	return f
+}
+
+// AddLibrary adds an empty library.
+func (f *Fake) AddLibrary(pbl string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.libs[libKey(pbl)] == nil {
+		f.libs[libKey(pbl)] = make(map[string]*fakeEntry)
+	}
+}
+
+// Writes returns the changes made so far.
+func (f *Fake) Writes() []Write {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Write(nil), f.writes...)
+}
+
+// Source returns the source of an entry, name with extension.
+func (f *Fake) Source(pbl, name string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.libs[libKey(pbl)][strings.ToLower(name)]
+	if !ok {
+		return "", false
+	}
+	return e.src, true
+}
+
+// Entries returns the sorted entry names of a library.
+func (f *Fake) Entries(pbl string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for _, e := range f.libs[libKey(pbl)] {
+		names = append(names, e.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *Fake) GetObjList(pbl string) (map[string]*orca.ObjArr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lib, err := f.lib(pbl)
+	if err != nil {
+		return nil, err
+	}
+	objs := make(map[string]*orca.ObjArr)
+	for _, e := range lib {
+		name := strings.TrimSuffix(e.name, filepath.Ext(e.name))
+		if objs[name] == nil {
+			objs[name] = &orca.ObjArr{}
+		}
+		objs[name].ObjArr = append(objs[name].ObjArr, &orca.Obj{Name: name, ObjType: objType(e.name)})
+	}
+	return objs, nil
+}
+
+func (f *Fake) GetObjSource(pbl, name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lib, err := f.lib(pbl)
+	if err != nil {
+		return "", err
+	}
+	e := findEntry(lib, name)
+	if e == nil {
+		return "", fmt.Errorf("object %s does not exist in %s", name, pbl)
+	}
+	return e.src, nil
+}
+
+func (f *Fake) SetObjSource(pbt, pbl, name string, src []byte) error {
+	if f.Compile != nil {
+		err := f.Compile(pbl, name, src)
+		if err != nil {
+			return err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lib, err := f.lib(pbl)
+	if err != nil {
+		return err
+	}
+	e := findEntry(lib, name)
+	if e == nil {
+		e = &fakeEntry{name: name}
+		if filepath.Ext(name) == "" {
+			fileName, err := filenameOfSrc(string(src))
+			if err != nil {
+				return fmt.Errorf("could not determine the type of %s: %v", name, err)
+			}
+			e.name = name + filepath.Ext(fileName)
+		}
+		lib[strings.ToLower(e.name)] = e
+	}
+	e.src = string(src)
+	f.writes = append(f.writes, Write{Action: WriteSource, Library: pbl, Object: e.name, Data: src})
+	return nil
+}
+
+func (f *Fake) SetObjBinary(pbt, pbl, name string, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lib, err := f.lib(pbl)
+	if err != nil {
+		return err
+	}
+	e := findEntry(lib, name)
+	if e == nil {
+		return fmt.Errorf("object %s does not exist in %s", name, pbl)
+	}
+	e.binary = data
+	f.writes = append(f.writes, Write{Action: WriteBinary, Library: pbl, Object: e.name, Data: data})
+	return nil
+}
+
+func (f *Fake) DeleteObj(pbl, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lib, err := f.lib(pbl)
+	if err != nil {
+		return err
+	}
+	e := findEntry(lib, name)
+	if e == nil {
+		return fmt.Errorf("object %s does not exist in %s", name, pbl)
+	}
+	delete(lib, strings.ToLower(e.name))
+	f.writes = append(f.writes, Write{Action: WriteDelete, Library: pbl, Object: e.name})
+	return nil
+}
+
+func (f *Fake) FullBuildTarget(pbt string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, Write{Action: WriteBuild, Library: pbt})
+	return f.BuildMessages, f.BuildErr
+}
+
+func (f *Fake) MigrateTarget(pbt string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, Write{Action: WriteMigrate, Library: pbt})
+	return f.BuildMessages, f.BuildErr
+}
+
+func (f *Fake) GetFilenameOfSrc(src string) (string, error) {
+	return filenameOfSrc(src)
+}
+
+func (f *Fake) lib(pbl string) (map[string]*fakeEntry, error) {
+	lib, ok := f.libs[libKey(pbl)]
+	if !ok {
+		return nil, fmt.Errorf("library %s does not exist", pbl)
+	}
+	return lib, nil
+}
+
+func libKey(pbl string) string {
+	return strings.ToLower(filepath.Clean(pbl))
+}
+
+// findEntry returns the entry of an object, name may be given without extension.
+func findEntry(lib map[string]*fakeEntry, name string) *fakeEntry {
+	name = strings.ToLower(name)
+	if e, ok := lib[name]; ok {
+		return e
+	}
+	if filepath.Ext(name) != "" {
+		return nil
+	}
+	for key, e := range lib {
+		if strings.TrimSuffix(key, filepath.Ext(key)) == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func filenameOfSrc(src string) (string, error) {
+	m := regexExportHeader.FindStringSubmatch(src)
+	if m == nil {
+		return "", fmt.Errorf("source has no export header")
+	}
+	return strings.TrimSpace(m[1]), nil
+}
+
+// objType returns the object type of an entry name by its extension.
+func objType(name string) orca.ObjType {
+	ext := strings.ToLower(filepath.Ext(name))
+	// the object types are numbered from 0, there are less than 32 of them
+	for t := orca.ObjType(0); t < 32; t++ {
+		if pborca.GetObjSuffixFromType(t) == ext {
+			return t
+		}
+	}
+	return orca.ObjType(-1)
+}
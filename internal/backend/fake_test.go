@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	f := NewFake(map[string]map[string]string{
+		"C:/a3/inf1.pbl": {"u_base.sru": "$PBExportHeader$u_base.sru\r\n", "d_list.srd": "release 22;\r\n"},
+	})
+	f.Compile = func(pbl, name string, src []byte) error {
+		if name == "u_broken" {
+			return errors.New("C0015: undefined variable")
+		}
+		return nil
+	}
+
+	objs, err := f.GetObjList("c:/A3/INF1.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 || objs["u_base"] == nil || objs["d_list"] == nil {
+		t.Errorf("unexpected object list %v", objs)
+	}
+	if src, err := f.GetObjSource("C:/a3/inf1.pbl", "U_BASE"); err != nil || src != "$PBExportHeader$u_base.sru\r\n" {
+		t.Errorf("GetObjSource without extension = %q, %v", src, err)
+	}
+	if _, err = f.GetObjSource("C:/a3/inf1.pbl", "u_missing.sru"); err == nil {
+		t.Errorf("expected error for a missing object")
+	}
+	if _, err = f.GetObjList("C:/a3/missing.pbl"); err == nil {
+		t.Errorf("expected error for a missing library")
+	}
+
+	// new object, the type is taken from the export header
+	err = f.SetObjSource("C:/a3/a3.pbt", "C:/a3/inf1.pbl", "w_main", []byte("$PBExportHeader$w_main.srw\r\nforward\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.SetObjSource("C:/a3/a3.pbt", "C:/a3/inf1.pbl", "u_broken", []byte("$PBExportHeader$u_broken.sru\r\n"))
+	if err == nil {
+		t.Errorf("expected compile error")
+	}
+	if err = f.DeleteObj("C:/a3/inf1.pbl", "d_list.srd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.FullBuildTarget("C:/a3/a3.pbt"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := f.Entries("C:/a3/inf1.pbl")
+	if len(entries) != 2 || entries[0] != "u_base.sru" || entries[1] != "w_main.srw" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+	want := []Write{
+		{Action: WriteSource, Library: "C:/a3/inf1.pbl", Object: "w_main.srw"},
+		{Action: WriteDelete, Library: "C:/a3/inf1.pbl", Object: "d_list.srd"},
+		{Action: WriteBuild, Library: "C:/a3/a3.pbt"},
+	}
+	writes := f.Writes()
+	if len(writes) != len(want) {
+		t.Fatalf("got %d writes, want %d: %v", len(writes), len(want), writes)
+	}
+	for i, w := range writes {
+		if w.Action != want[i].Action || w.Library != want[i].Library || w.Object != want[i].Object {
+			t.Errorf("write %d: got %+v, want %+v", i, w, want[i])
+		}
+	}
+
+	if name, err := f.GetFilenameOfSrc("$PBExportHeader$w_main.srw\r\n"); err != nil || name != "w_main.srw" {
+		t.Errorf("GetFilenameOfSrc() = %s, %v", name, err)
+	}
+}
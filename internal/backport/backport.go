@@ -12,7 +12,8 @@ import (
 )
 
 // ConvertProjectToTarget modifies src files referenced by .pbproj directory and converts the project back to target.
-func ConvertProjectToTarget(pbProjFile string, verbose bool) error {
+// The libraries of the target are ordered by libraryOrder (see NewTarget).
+func ConvertProjectToTarget(pbProjFile string, libraryOrder [][]string, verbose bool) error {
 	rules := []FileRule{
 		{description: "FixDWHeader", Matcher: matchExt(".srd"), Handler: handleSrdFile},
 		{description: "FixSraRuntime", Matcher: matchExt(".sra"), Handler: handleSraFile},
@@ -28,9 +29,11 @@ func ConvertProjectToTarget(pbProjFile string, verbose bool) error {
 
 	// Create pbt file
 	pbtFilePath := filepath.Join(filepath.Dir(pbProjFile), strings.TrimSuffix(filepath.Base(pbProjFile), ".pbproj")+".pbt")
-	err = os.WriteFile(pbtFilePath,
-		NewTarget(pbProj.Application.Name, pbProj.Libraries.AppEntry, pbProj.Libraries.GetPblPaths()).ToBytes(),
-		0o644)
+	target := NewTarget(pbProj.Application.Name, pbProj.Libraries.AppEntry, pbProj.Libraries.GetPblPaths(), libraryOrder)
+	if unlisted := target.UnlistedLibs(); len(unlisted) > 0 {
+		fmt.Printf("WARN: libraries not in any list (placed first): %s\n", strings.Join(unlisted, ", "))
+	}
+	err = os.WriteFile(pbtFilePath, target.ToBytes(), 0o644)
 	if err != nil {
 		return fmt.Errorf("failed to write actual application target %s: %v", pbtFilePath, err)
 	}
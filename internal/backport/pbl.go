@@ -0,0 +1,42 @@
+package backport
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+)
+
+// StagePbl creates the library pblFile and stores all source files of srcDir in it without compiling them, the
+// objects get compiled by a later ORCA regenerate or full build. Binary sections (.bin files) are skipped, they must
+// be imported by ORCA.
+//
+// StagePbl replaces CreateApplicationPbl, which created the application pbl with orcascr220.exe. The backport
+// does not need it anymore, pbautobuild creates the libraries from ws_objects (see ConvertProjectToTarget).
+func StagePbl(srcDir, pblFile string) (*pbl.Library, error) {
+	lib := pbl.New("")
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasPrefix(strings.ToLower(filepath.Ext(path)), ".sr") {
+			return nil
+		}
+		srcData, err := utils.ReadPbSource(path)
+		if err != nil {
+			return err
+		}
+		return lib.SetSource(filepath.Base(path), string(srcData))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage sources of %s: %v", srcDir, err)
+	}
+	err = lib.WriteFile(pblFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", pblFile, err)
+	}
+	return lib, nil
+}
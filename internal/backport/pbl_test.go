@@ -0,0 +1,42 @@
+package backport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+)
+
+func TestStagePbl(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "ws_objects", "loh1.pbl.src")
+	files := map[string]string{
+		"loh.sra":    "$PBExportHeader$loh.sra\r\nforward\r\nglobal type loh from application\r\nend type\r\n",
+		"w_main.srw": "$PBExportHeader$w_main.srw\r\nforward\r\nglobal type w_main from window\r\nend type\r\n",
+		"w_main.bin": "binary data",
+	}
+	if err := os.MkdirAll(srcDir, 0o775); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(src), 0o664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pblFile := filepath.Join(dir, "loh1.pbl")
+	if _, err := StagePbl(srcDir, pblFile); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := pbl.Open(pblFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lib.SourceEntries()); n != 2 {
+		t.Errorf("got %d source entries, want 2 (.bin files are skipped)", n)
+	}
+	if _, ok := lib.Entry("loh.sra"); !ok {
+		t.Errorf("application object loh.sra is missing")
+	}
+}
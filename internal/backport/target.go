@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -17,34 +19,18 @@ type Target struct {
 }
 
 // NewTarget returns the minimal structure of needed for a target file. Expects a list of pbl names, e.g.
-// []string{"some_app.pbl", "some_lib.pbl", ...}.
-func NewTarget(appName, appEntryPbl string, libList []string) *Target {
-	// Defines the 10 hardcoded lists (with base names)
-	list1 := []string{"lif"}
-	list2 := []string{"jif"}
-	list3 := []string{"arf", "cfg", "inf", "kal", "nfy", "pbdom", "sti", "stm", "tse"}
-	list4 := []string{"dss", "eft", "exf", "fsu", "grp", "liq", "net", "osu", "str", "szn"}
-	list5 := []string{"sfi"}
-	list6 := []string{"bai", "kim"}
-	list7 := []string{"cfg_lohn", "elm", "elmg", "elmp", "elx", "loh", "lor", "spe", "stm_lohn"}
-	list8 := []string{"anl", "deb", "fib", "fin", "fre", "kor", "kre", "mai", "mve", "tbs", "zea", "zei", "zek", "zes"}
-	list9 := []string{
-		"adr", "arc", "art", "bde", "biz", "dgm", "dis", "drucken", "dzb", "ecp", "ein", "ger",
-		"kon", "lag", "lda", "map", "mit", "obj", "ord", "pos", "prj", "rap", "res", "sal",
-	}
-	list10 := []string{
-		"avd", "bbp", "bst", "con", "das", "dbe", "dka", "dma", "dmi", "dta", "dto", "dwh", "dws",
-		"egm", "kas", "kat", "kng", "mie", "pro", "reg", "sdi", "ser", "sfm", "wae", "wgb", "wss",
-	}
+// []string{"some_app.pbl", "some_lib.pbl", ...}. libraryOrder lists groups of library base names in the order they
+// appear in the library list (see profile.Profile.LibraryOrder).
+func NewTarget(appName, appEntryPbl string, libList []string, libraryOrder [][]string) *Target {
+	// the first group gets the highest priority
+	lists := slices.Clone(libraryOrder)
+	slices.Reverse(lists)
 
 	return &Target{
 		AppName: appName,
 		AppLib:  appEntryPbl,
 		LibList: libList,
-		ListMap: buildListMap(
-			list1, list2, list3, list4, list5,
-			list6, list7, list8, list9, list10,
-		),
+		ListMap: buildListMap(lists...),
 	}
 }
 
@@ -105,21 +91,67 @@ func buildListMap(lists ...[]string) map[string]int {
 }
 
 // getPriority returns the sorting priority for a given base name.
-// Unlisted items get the highest priority (11) to appear first.
+// Unlisted items get the highest priority (11, or above the highest list if there are more than 10 lists) to
+// appear first.
 func getPriority(baseName string, listMap map[string]int) int {
 	if priority, ok := listMap[baseName]; ok {
-		return priority // 1-10
+		return priority
 	}
 	// "Unlisted" items get the highest priority so they sort first.
-	return 11
+	unlisted := 11
+	for _, priority := range listMap {
+		if priority >= unlisted {
+			unlisted = priority + 1
+		}
+	}
+	return unlisted
+}
+
+// libName returns the lower case library name of a library list entry without folder and extension, e.g. inf1
+// for ..\lib\INF1.PBL.
+func libName(entry string) string {
+	entry = strings.ToLower(entry)
+	if i := strings.LastIndexAny(entry, `/\`); i >= 0 {
+		entry = entry[i+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(entry, ".pbl"), ".pbd")
+}
+
+// UnlistedLibs returns the entries of the library list which are not part of any list.
+func (t *Target) UnlistedLibs() []string {
+	var unlisted []string
+	for _, entry := range t.LibList {
+		base, _ := parseName(libName(entry))
+		if _, ok := t.ListMap[base]; !ok {
+			unlisted = append(unlisted, entry)
+		}
+	}
+	return unlisted
 }
 
-// SortLibList sorts according to defined excel "Packages und Verantwortlichkeiten" (10: first packages, ...,
-// 1:), their number 3,2,1 and alphabetically within the same list.
+// LibraryOrderFromLibList returns a library order (see NewTarget) which keeps the order of an existing library
+// list, e.g. of another target. Every library base name is a group of its own.
+func LibraryOrderFromLibList(libList []string) [][]string {
+	var order [][]string
+	seen := make(map[string]bool)
+	for _, entry := range libList {
+		base, _ := parseName(libName(entry))
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+		order = append(order, []string{base})
+	}
+	return order
+}
+
+// SortLibList sorts according to the library lists (e.g. the excel "Packages und Verantwortlichkeiten" of the a3
+// profile, 10: first packages, ..., 1:), their number 3,2,1 and alphabetically within the same list. Entries may
+// contain folders, only the library name is compared.
 func (t *Target) SortLibList() {
 	sort.Slice(t.LibList, func(i, j int) bool {
-		a := strings.TrimSuffix(t.LibList[i], ".pbl")
-		b := strings.TrimSuffix(t.LibList[j], ".pbl")
+		a := libName(t.LibList[i])
+		b := libName(t.LibList[j])
 
 		// get pbl base names and suffix priorities (3,2,1)
 		baseA, suffixA := parseName(a)
@@ -129,7 +161,7 @@ func (t *Target) SortLibList() {
 		prioA := getPriority(baseA, t.ListMap)
 		prioB := getPriority(baseB, t.ListMap)
 
-		// Rule 1: List Priority (11 > 10 > ... > 1)
+		// Rule 1: List Priority (unlisted > 10 > ... > 1)
 		if prioA != prioB {
 			return prioA > prioB
 		}
@@ -146,3 +178,27 @@ func (t *Target) SortLibList() {
 	slog.Debug("--- Sorted List ---")
 	slog.Debug(fmt.Sprintf("%s", t.LibList))
 }
+
+var regexLibList = regexp.MustCompile(`(?mi)^(liblist[ \t]+")([^"]*)(";)`)
+
+// SortPbtLibList sorts the library list of the source of a pbt file (see SortLibList) and returns the changed
+// source and the libraries which are not part of any list. Everything else in the source stays unchanged.
+func SortPbtLibList(pbtSrc []byte, libraryOrder [][]string) ([]byte, []string, error) {
+	loc := regexLibList.FindSubmatchIndex(pbtSrc)
+	if loc == nil {
+		return nil, nil, fmt.Errorf("no liblist found in target")
+	}
+	var libList []string
+	for _, entry := range strings.Split(string(pbtSrc[loc[4]:loc[5]]), ";") {
+		if entry != "" {
+			libList = append(libList, entry)
+		}
+	}
+	t := NewTarget("", "", libList, libraryOrder)
+	t.SortLibList()
+
+	sorted := append([]byte(nil), pbtSrc[:loc[4]]...)
+	sorted = append(sorted, strings.Join(t.LibList, ";")...)
+	sorted = append(sorted, pbtSrc[loc[5]:]...)
+	return sorted, t.UnlistedLibs(), nil
+}
@@ -3,6 +3,8 @@ package backport
 import (
 	"reflect"
 	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/profile"
 )
 
 // TestParseName checks the name and suffix parsing logic.
@@ -172,7 +174,11 @@ func TestTarget_SortLibList(t *testing.T) {
 		"grp1.pbl", "liq1.pbl", "net1.pbl", "osu1.pbl", "str1.pbl", "szn1.pbl", "arf1.pbl", "cfg1.pbl", "inf1.pbl",
 		"kal1.pbl", "nfy1.pbl", "pbdom.pbl", "sti1.pbl", "stm1.pbl", "tse1.pbl", "jif1.pbl", "lif1.pbl",
 	}
-	target := NewTarget("a3", "inf1.pbl", realLibList)
+	a3, err := profile.Builtin("a3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := NewTarget("a3", "inf1.pbl", realLibList, a3.LibraryOrder)
 	target.SortLibList()
 	actual := target.LibList
 	if !reflect.DeepEqual(actual, expectedLibList) {
@@ -180,3 +186,47 @@ func TestTarget_SortLibList(t *testing.T) {
 			"real world example", realLibList, expectedLibList, actual)
 	}
 }
+
+func TestSortLibListGeneric(t *testing.T) {
+	set, err := profile.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := set.ForApp("xyz")
+	target := NewTarget("xyz", "xyz1.pbl", []string{"lif1.pbl", "inf1.pbl", "xyz1.pbl", "adr1.pbl", "inf2.pbl"}, p.LibraryOrder)
+	target.SortLibList()
+	want := []string{"xyz1.pbl", "adr1.pbl", "inf2.pbl", "inf1.pbl", "lif1.pbl"}
+	if !reflect.DeepEqual(target.LibList, want) {
+		t.Errorf("profile %s: got %v, want %v", p.Name, target.LibList, want)
+	}
+}
+
+func TestSortPbtLibList(t *testing.T) {
+	src := "Save Format v3.0(19990112)\r\nappname \"xyz\";\r\napplib \"app1.pbl\";\r\n" +
+		"liblist \"base1.pbl;..\\\\ext\\\\Tool1.pbl;app2.pbl;app1.pbl;new1.pbl;base2.pbl\";\r\ntype \"pb\";\r\n"
+	want := "Save Format v3.0(19990112)\r\nappname \"xyz\";\r\napplib \"app1.pbl\";\r\n" +
+		"liblist \"new1.pbl;app2.pbl;app1.pbl;base2.pbl;base1.pbl;..\\\\ext\\\\Tool1.pbl\";\r\ntype \"pb\";\r\n"
+
+	sorted, unlisted, err := SortPbtLibList([]byte(src), [][]string{{"app"}, {"base"}, {"tool"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sorted) != want {
+		t.Errorf("unexpected target:\n%s\nwant:\n%s", sorted, want)
+	}
+	if !reflect.DeepEqual(unlisted, []string{"new1.pbl"}) {
+		t.Errorf("unexpected unlisted libraries %v", unlisted)
+	}
+
+	if _, _, err := SortPbtLibList([]byte("appname \"xyz\";"), nil); err == nil {
+		t.Errorf("expected an error for a target without liblist")
+	}
+}
+
+func TestLibraryOrderFromLibList(t *testing.T) {
+	order := LibraryOrderFromLibList([]string{`C:\a3\lib\app2.pbl`, `C:\a3\lib\app1.pbl`, `C:\a3\lib\Base1.pbl`})
+	want := [][]string{{"app"}, {"base"}}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("LibraryOrderFromLibList() = %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,300 @@
+// Package buildlog parses the messages of the PowerBuilder compiler (ORCA and pbc) into structured records and
+// writes them as JSON, JUnit XML or SARIF, so a CI can annotate the failing lines and track the warnings.
+//
+// The compiler reports messages like
+//
+//	C:\a3\lib\inf1.pbl(w_main).cb_ok.clicked.12: Error       C0015: Undefined variable: ls_x
+//	w_main.of_test.3: Warning     C0014: Undefined variable: ll_y
+//
+// Lines which do not have this format are kept as messages without location.
+package buildlog
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/sarif"
+)
+
+// Severities of a message
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Output formats (see Write)
+const (
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+	FormatSARIF = "sarif"
+)
+
+// Formats are all supported output formats.
+var Formats = []string{FormatJSON, FormatJUnit, FormatSARIF}
+
+// Message is a message of the compiler.
+type Message struct {
+	Library  string `json:"library,omitempty"` // e.g. inf1.pbl
+	Object   string `json:"object,omitempty"`  // e.g. w_main
+	Script   string `json:"script,omitempty"`  // event or function, e.g. cb_ok.clicked or of_test
+	Line     int    `json:"line,omitempty"`    // line within the script
+	Severity string `json:"severity"`
+	Code     string `json:"code,omitempty"` // e.g. C0015
+	Text     string `json:"message"`
+}
+
+// String returns the message in the format of the compiler.
+func (m Message) String() string {
+	var sb strings.Builder
+	if m.Library != "" {
+		fmt.Fprintf(&sb, "%s(%s)", m.Library, m.Object)
+		if m.Script != "" {
+			sb.WriteString("." + m.Script)
+		}
+	} else if m.Object != "" {
+		sb.WriteString(m.Object)
+		if m.Script != "" {
+			sb.WriteString("." + m.Script)
+		}
+	}
+	if m.Line > 0 {
+		fmt.Fprintf(&sb, ".%d", m.Line)
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(": ")
+	}
+	sb.WriteString(m.Severity)
+	if m.Code != "" {
+		sb.WriteString(" " + m.Code)
+	}
+	return sb.String() + ": " + m.Text
+}
+
+var regexMessage = regexp.MustCompile(`(?i)^\s*(?:(.+?\.pb[ld])\(([^)]+)\)\.?)?([\w$#%-]+(?:\.[\w$#%-]+)*)?\.(\d+)\s*:\s*(error|warning|information|info|obsolete)\s+(?:([a-z]\d{4})\s*:\s*)?(.*)$`)
+
+var regexSeverity = regexp.MustCompile(`(?i)\b(error|warning)`)
+
+// Parse parses the lines of a compiler log. Empty lines and separator lines (----------) are skipped.
+func Parse(lines []string) []Message {
+	var msgs []Message
+	for _, text := range lines {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "----------") {
+				continue
+			}
+			msgs = append(msgs, parseLine(line))
+		}
+	}
+	return msgs
+}
+
+// ParseText parses a compiler log.
+func ParseText(log string) []Message {
+	return Parse([]string{log})
+}
+
+func parseLine(line string) Message {
+	match := regexMessage.FindStringSubmatch(line)
+	if match == nil {
+		severity := SeverityInfo
+		if m := regexSeverity.FindString(line); m != "" {
+			severity = strings.ToLower(m)
+		}
+		return Message{Severity: severity, Text: line}
+	}
+	msg := Message{Library: match[1], Object: match[2], Script: match[3], Code: strings.ToUpper(match[6]), Text: match[7]}
+	msg.Line, _ = strconv.Atoi(match[4])
+	if msg.Library == "" && msg.Script != "" {
+		// without library, the object is the first part
+		msg.Object, msg.Script, _ = strings.Cut(msg.Script, ".")
+	}
+	switch strings.ToLower(match[5]) {
+	case "error":
+		msg.Severity = SeverityError
+	case "warning", "obsolete":
+		msg.Severity = SeverityWarning
+	default:
+		msg.Severity = SeverityInfo
+	}
+	return msg
+}
+
+// FromError returns the messages of an error of ORCA concerning a single object, e.g. of an import. Messages
+// without library get the library and object, the location of those messages is the script within the object
+// (e.g. of_run.4). If the error contains no error message, the whole error is returned as error message.
+func FromError(library, object string, err error) []Message {
+	msgs := ParseText(err.Error())
+	hasError := false
+	for i := range msgs {
+		if msgs[i].Library == "" {
+			if msgs[i].Object != "" && !strings.EqualFold(msgs[i].Object, object) {
+				msgs[i].Script = strings.TrimSuffix(msgs[i].Object+"."+msgs[i].Script, ".")
+			}
+			msgs[i].Library = library
+			msgs[i].Object = object
+		}
+		if msgs[i].Severity == SeverityError {
+			hasError = true
+		}
+	}
+	if !hasError {
+		msgs = append(msgs, Message{Library: library, Object: object, Severity: SeverityError, Text: strings.TrimSpace(err.Error())})
+	}
+	return msgs
+}
+
+// Count returns the number of errors and warnings.
+func Count(msgs []Message) (errors, warnings int) {
+	for _, m := range msgs {
+		switch m.Severity {
+		case SeverityError:
+			errors++
+		case SeverityWarning:
+			warnings++
+		}
+	}
+	return
+}
+
+// Write writes the messages in the given format. name is the name of the build (e.g. the target), version the
+// version of pbmanager.
+func Write(w io.Writer, format, name, version string, msgs []Message) error {
+	switch format {
+	case FormatJSON:
+		if msgs == nil {
+			msgs = []Message{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(msgs)
+	case FormatJUnit:
+		return writeJUnit(w, name, msgs)
+	case FormatSARIF:
+		log := sarif.New("pbmanager", version)
+		for _, m := range msgs {
+			ruleID := m.Code
+			if ruleID == "" {
+				ruleID = "compiler"
+			}
+			level := sarif.LevelNote
+			switch m.Severity {
+			case SeverityError:
+				level = sarif.LevelError
+			case SeverityWarning:
+				level = sarif.LevelWarning
+			}
+			log.Add(ruleID, level, m.Text, location(m), m.Line)
+		}
+		return log.Write(w)
+	}
+	return fmt.Errorf("invalid format %s, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// location returns library/object of a message, e.g. inf1.pbl/w_main.
+func location(m Message) string {
+	if m.Object == "" {
+		return filepath.Base(m.Library)
+	}
+	if m.Library == "" {
+		return m.Object
+	}
+	return filepath.Base(m.Library) + "/" + m.Object
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []*junitCase    `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes one test case per object with messages, errors are failures, warnings and infos are written
+// to the output of the test case. The number of warnings is a property of the test suite.
+func writeJUnit(w io.Writer, name string, msgs []Message) error {
+	var cases []*junitCase
+	byObject := make(map[string]*junitCase)
+	for _, m := range msgs {
+		key := strings.ToLower(location(m))
+		c, ok := byObject[key]
+		if !ok {
+			c = &junitCase{ClassName: filepath.Base(m.Library), Name: m.Object}
+			if c.Name == "" {
+				c.Name = name
+			}
+			byObject[key] = c
+			cases = append(cases, c)
+		}
+		if m.Severity == SeverityError {
+			c.Failures = append(c.Failures, junitFailure{Message: m.Text, Type: m.Code, Text: m.String()})
+		} else {
+			c.SystemOut += m.String() + "\n"
+		}
+	}
+	if len(cases) == 0 {
+		cases = append(cases, &junitCase{ClassName: name, Name: "build"})
+	}
+	errors, warnings := Count(msgs)
+	failed := 0
+	for _, c := range cases {
+		if len(c.Failures) > 0 {
+			failed++
+		}
+	}
+	suites := junitSuites{Name: name, Tests: len(cases), Failures: failed, Suites: []junitSuite{{
+		Name:     name,
+		Tests:    len(cases),
+		Failures: failed,
+		Properties: []junitProperty{
+			{Name: "errors", Value: strconv.Itoa(errors)},
+			{Name: "warnings", Value: strconv.Itoa(warnings)},
+		},
+		Cases: cases,
+	}}}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(suites)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
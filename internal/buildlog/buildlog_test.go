@@ -0,0 +1,111 @@
+package buildlog
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		line string
+		want Message
+	}{
+		{
+			line: `C:\a3\lib\inf1.pbl(w_main).cb_ok.clicked.12: Error       C0015: Undefined variable: ls_x`,
+			want: Message{Library: `C:\a3\lib\inf1.pbl`, Object: "w_main", Script: "cb_ok.clicked", Line: 12, Severity: SeverityError, Code: "C0015", Text: "Undefined variable: ls_x"},
+		},
+		{
+			line: `w_main.of_test.3: Warning     C0014: Undefined variable: ll_y`,
+			want: Message{Object: "w_main", Script: "of_test", Line: 3, Severity: SeverityWarning, Code: "C0014", Text: "Undefined variable: ll_y"},
+		},
+		{
+			line: `inf1.pbl(u_base).1: Information C0146: The identifier 'x' conflicts`,
+			want: Message{Library: "inf1.pbl", Object: "u_base", Line: 1, Severity: SeverityInfo, Code: "C0146", Text: "The identifier 'x' conflicts"},
+		},
+		{
+			line: `Error: target a3.pbt could not be built`,
+			want: Message{Severity: SeverityError, Text: "Error: target a3.pbt could not be built"},
+		},
+		{
+			line: `Compiling u_base`,
+			want: Message{Severity: SeverityInfo, Text: "Compiling u_base"},
+		},
+	}
+	for _, tc := range testCases {
+		got := Parse([]string{tc.line})
+		if len(got) != 1 {
+			t.Errorf("Parse(%q) returned %d messages", tc.line, len(got))
+			continue
+		}
+		if !reflect.DeepEqual(got[0], tc.want) {
+			t.Errorf("Parse(%q)\n got %+v\nwant %+v", tc.line, got[0], tc.want)
+		}
+	}
+
+	got := ParseText("---------- Compiler: Errors\n\nw_main.of_test.3: Error C0001: x\n")
+	if len(got) != 1 {
+		t.Errorf("expected separators and empty lines to be skipped, got %v", got)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	got := FromError("inf1.pbl", "u_base", errors.New("import failed\nof_run.4: Error C0015: Undefined variable: x"))
+	if len(got) != 2 {
+		t.Fatalf("expected 2 messages, got %v", got)
+	}
+	if got[1].Library != "inf1.pbl" || got[1].Object != "u_base" || got[1].Script != "of_run" || got[1].Line != 4 {
+		t.Errorf("unexpected message %+v", got[1])
+	}
+	if got[0].Library != "inf1.pbl" || got[0].Object != "u_base" {
+		t.Errorf("message without location must get library and object, got %+v", got[0])
+	}
+
+	got = FromError("inf1.pbl", "u_base", errors.New("connection lost"))
+	if len(got) != 2 || got[1].Severity != SeverityError {
+		t.Errorf("expected the error as error message, got %+v", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	msgs := ParseText(`inf1.pbl(w_main).cb_ok.clicked.12: Error C0015: Undefined variable: ls_x
+inf1.pbl(w_main).of_test.3: Warning C0014: Undefined variable: ll_y
+inf1.pbl(u_base).of_run.1: Warning C0014: Undefined variable: ll_z`)
+	if errs, warns := Count(msgs); errs != 1 || warns != 2 {
+		t.Errorf("Count() = %d, %d, want 1, 2", errs, warns)
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, FormatJUnit, "a3.pbt", "1.0.0", msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitSuites
+	err = xml.Unmarshal(buf.Bytes(), &suites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 2 || suites.Failures != 1 {
+		t.Errorf("expected 2 tests and 1 failure, got %d and %d", suites.Tests, suites.Failures)
+	}
+	if c := suites.Suites[0].Cases[0]; c.Name != "w_main" || len(c.Failures) != 1 || c.Failures[0].Type != "C0015" {
+		t.Errorf("unexpected test case %+v", c)
+	}
+
+	for _, format := range []string{FormatJSON, FormatSARIF} {
+		buf.Reset()
+		err = Write(&buf, format, "a3.pbt", "1.0.0", msgs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "Undefined variable: ll_z") {
+			t.Errorf("%s output misses a message:\n%s", format, buf.String())
+		}
+	}
+	if err = Write(&buf, "xml", "a3.pbt", "", msgs); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,230 @@
+// Package datawindow parses the source of DataWindow objects (.srd) into a structured model and writes it back.
+//
+// A DataWindow source consists of the export header, a release statement and a list of items of the form
+// name(attr=value attr=value ...), e.g.:
+//
+//	release 22;
+//	datawindow(units=0 timer_interval=0 color=1073741824 ...)
+//	header(height=84 color="536870912" ...)
+//	table(column=(type=char(100) updatewhereclause=yes name=key dbname="t.key" )
+//	 retrieve="select key from t" )
+//	column(band=detail id=1 alignment="0" x="14" y="4" height="72" name=key edit.autohscroll=yes ...)
+//
+// Every item and attribute keeps the text it was parsed from, so String returns the unchanged input byte for byte.
+// Only modified attributes are written in a normalized form.
+package datawindow
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DataWindow is the parsed source of a DataWindow object.
+type DataWindow struct {
+	Release string // e.g. "22"
+	Items   []*Item
+
+	header  string // export header and release statement
+	trailer string // text after the last item
+}
+
+// Item is a single top-level statement like datawindow(...), detail(...), table(...) or column(...).
+type Item struct {
+	Name  string // e.g. datawindow, header, detail, table, column, text, compute, bitmap
+	Attrs []*Attr
+
+	pre   string // text between the previous item and the name
+	open  string // name and opening bracket as in the source
+	close string // text after the last attribute including the closing bracket
+}
+
+// Attr is an attribute of an item, e.g. alignment="2" or edit.autohscroll=yes.
+type Attr struct {
+	Name  string
+	Value string // value as in the source, quoted strings keep their quotes
+
+	pre string // whitespace in front of the attribute
+	raw string // name and value as in the source, empty if the attribute was modified
+}
+
+var regexRelease = regexp.MustCompile(`(?m)^release[ \t]+([0-9.]+)[ \t]*;`)
+
+// band names of a DataWindow
+var bands = map[string]bool{"header": true, "detail": true, "footer": true, "summary": true}
+
+// String returns the source of the DataWindow.
+func (dw *DataWindow) String() string {
+	var sb strings.Builder
+	sb.WriteString(dw.header)
+	for _, item := range dw.Items {
+		item.write(&sb)
+	}
+	sb.WriteString(dw.trailer)
+	return sb.String()
+}
+
+func (item *Item) write(sb *strings.Builder) {
+	sb.WriteString(item.pre)
+	sb.WriteString(item.open)
+	for _, a := range item.Attrs {
+		sb.WriteString(a.pre)
+		if a.raw != "" {
+			sb.WriteString(a.raw)
+			continue
+		}
+		sb.WriteString(a.Name)
+		if a.Value != "" {
+			sb.WriteString("=" + a.Value)
+		}
+	}
+	sb.WriteString(item.close)
+}
+
+// Item returns the first item with the given name (e.g. datawindow or detail) or nil.
+func (dw *DataWindow) Item(name string) *Item {
+	for _, item := range dw.Items {
+		if strings.EqualFold(item.Name, name) {
+			return item
+		}
+	}
+	return nil
+}
+
+// Band returns the band item with the given name (header, detail, footer or summary) or nil.
+func (dw *DataWindow) Band(name string) *Item {
+	if !bands[strings.ToLower(name)] {
+		return nil
+	}
+	return dw.Item(name)
+}
+
+// Table returns the table definition or nil for DataWindows without data source (e.g. external ones
+// always have a table, composite ones do not).
+func (dw *DataWindow) Table() *Table {
+	if item := dw.Item("table"); item != nil {
+		return &Table{item}
+	}
+	return nil
+}
+
+// Objects returns all objects placed in a band (column, text, compute, bitmap, line, ...). If kinds are given,
+// only objects of those kinds are returned, e.g. Objects("column").
+func (dw *DataWindow) Objects(kinds ...string) []*Item {
+	var objs []*Item
+	for _, item := range dw.Items {
+		if item.Attr("band") == nil {
+			continue
+		}
+		if len(kinds) > 0 && !containsFold(kinds, item.Name) {
+			continue
+		}
+		objs = append(objs, item)
+	}
+	return objs
+}
+
+// Object returns the object with the given name or nil.
+func (dw *DataWindow) Object(name string) *Item {
+	for _, item := range dw.Objects() {
+		if strings.EqualFold(item.Get("name"), name) {
+			return item
+		}
+	}
+	return nil
+}
+
+// Attr returns the attribute with the given name or nil.
+func (item *Item) Attr(name string) *Attr {
+	for _, a := range item.Attrs {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// Get returns the unquoted value of the attribute or an empty string if it does not exist.
+func (item *Item) Get(name string) string {
+	if a := item.Attr(name); a != nil {
+		return a.Text()
+	}
+	return ""
+}
+
+// Set changes the value of an attribute. The quoting of an existing attribute is preserved, new attributes are
+// appended and quoted if the value is not a simple word.
+func (item *Item) Set(name, value string) {
+	a := item.Attr(name)
+	if a == nil {
+		a = &Attr{Name: name, pre: " "}
+		if !regexWord.MatchString(value) {
+			value = quote(value)
+		}
+		a.Value = value
+		item.Attrs = append(item.Attrs, a)
+		return
+	}
+	if strings.HasPrefix(a.Value, `"`) {
+		value = quote(value)
+	}
+	if a.Value != value {
+		a.Value = value
+		a.raw = ""
+	}
+}
+
+// Remove deletes an attribute and returns false if it did not exist.
+func (item *Item) Remove(name string) bool {
+	for i, a := range item.Attrs {
+		if strings.EqualFold(a.Name, name) {
+			item.Attrs = append(item.Attrs[:i], item.Attrs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Rename changes the name of an attribute without moving it and returns false if it did not exist.
+func (item *Item) Rename(name, newName string) bool {
+	a := item.Attr(name)
+	if a == nil {
+		return false
+	}
+	a.Name = newName
+	a.raw = ""
+	return true
+}
+
+// Text returns the value with the surrounding quotes removed and ~" unescaped.
+// Other escape sequences (e.g. ~r~n) are returned as they are.
+func (a *Attr) Text() string {
+	if len(a.Value) >= 2 && strings.HasPrefix(a.Value, `"`) && strings.HasSuffix(a.Value, `"`) {
+		return strings.ReplaceAll(a.Value[1:len(a.Value)-1], `~"`, `"`)
+	}
+	return a.Value
+}
+
+// Attrs parses a bracketed value like (type=char(10) name=id) into its attributes.
+func (a *Attr) Attrs() ([]*Attr, error) {
+	if !strings.HasPrefix(a.Value, "(") || !strings.HasSuffix(a.Value, ")") {
+		return nil, nil
+	}
+	s := &scanner{src: a.Value, pos: 1}
+	attrs, _, err := s.attrs()
+	return attrs, err
+}
+
+var regexWord = regexp.MustCompile(`^[\w.]+$`)
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `~"`) + `"`
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+	return false
+}
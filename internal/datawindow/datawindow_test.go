@@ -0,0 +1,113 @@
+package datawindow
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseFile(t *testing.T, name string) (*DataWindow, string) {
+	t.Helper()
+	src, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dw, err := Parse(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dw, string(src)
+}
+
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.srd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		dw, src := parseFile(t, filepath.Base(file))
+		if dw.String() != src {
+			t.Errorf("%s: output differs from input", file)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dw, _ := parseFile(t, "d_test_objects.srd")
+	if dw.Release != "22" {
+		t.Errorf("wrong release %s", dw.Release)
+	}
+	if dw.Item("datawindow").Get("print.orientation") != "0" {
+		t.Errorf("attribute with blanks around = not parsed")
+	}
+	if dw.Band("detail").Get("height") != "76" || dw.Band("table") != nil {
+		t.Errorf("wrong bands")
+	}
+	if n := len(dw.Objects()); n != 6 {
+		t.Errorf("expected 6 objects, got %d", n)
+	}
+	if n := len(dw.Objects("column")); n != 2 {
+		t.Errorf("expected 2 columns, got %d", n)
+	}
+	if got := dw.Object("price_t").Get("text"); got != `Price "CHF"` {
+		t.Errorf("wrong text: %s", got)
+	}
+	if got := dw.Object("c_has_price").Get("expression"); got != `if( price > 0, "yes", "no" )` {
+		t.Errorf("wrong expression: %s", got)
+	}
+	if got := dw.Item("group").Get("by"); got != `("id" , "active" )` {
+		t.Errorf("wrong group by: %s", got)
+	}
+
+	table := dw.Table()
+	cols, err := table.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 3 || cols[1].Name != "price" || cols[1].Type != "decimal(2)" || cols[1].DbName != "article.price" {
+		t.Errorf("wrong columns: %+v", cols)
+	}
+	if !strings.HasPrefix(table.Retrieve(), `PBSELECT( VERSION(400) TABLE(NAME="article" )`) {
+		t.Errorf("wrong retrieve: %s", table.Retrieve())
+	}
+	args, err := table.Arguments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 2 || args[1] != (Argument{"as_text", "string"}) {
+		t.Errorf("wrong arguments: %+v", args)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	dw, src := parseFile(t, "d_test_objects.srd")
+	price := dw.Object("price")
+	price.Set("alignment", "0")
+	price.Remove("edit.hscrollbar")
+	active := dw.Object("active")
+	active.Rename("checkbox.scale", "checkbox.lefttext")
+	active.Set("tag", "two words")
+
+	want := strings.Replace(src, `id=2 alignment="1"`, `id=2 alignment="0"`, 1)
+	want = strings.Replace(want, ` edit.hscrollbar=yes`, ``, 1)
+	want = strings.Replace(want, `checkbox.scale=no checkbox.threed=yes  font.face="Arial" )`, `checkbox.lefttext=no checkbox.threed=yes  font.face="Arial" tag="two words" )`, 1)
+	if got := dw.String(); got != want {
+		t.Errorf("unexpected output after edit:\n%s", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"datawindow(units=0 )",
+		"release 22;\r\ndatawindow(units=0 ",
+		"release 22;\r\ntext(text=\"unterminated )",
+		"release 22;\r\nno brackets",
+	}
+	for _, src := range tests {
+		_, err := Parse(src)
+		if err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
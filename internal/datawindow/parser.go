@@ -0,0 +1,207 @@
+package datawindow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse parses the source of a DataWindow. The $PBExportHeader$ lines are optional, the release statement is
+// required.
+func Parse(src string) (*DataWindow, error) {
+	loc := regexRelease.FindStringSubmatchIndex(src)
+	if loc == nil {
+		return nil, fmt.Errorf("no release statement found, not a DataWindow source")
+	}
+	dw := &DataWindow{Release: src[loc[2]:loc[3]], header: src[:loc[1]]}
+
+	s := &scanner{src: src, pos: loc[1]}
+	for {
+		start := s.pos
+		s.skipSpace()
+		if s.pos >= len(s.src) || s.src[s.pos] == 0 {
+			dw.trailer = src[start:]
+			return dw, nil
+		}
+		item := &Item{pre: src[start:s.pos]}
+		nameStart := s.pos
+		item.Name = s.name()
+		if item.Name == "" || s.peek() != '(' {
+			return nil, s.errorf("expected item like name(...)")
+		}
+		s.pos++
+		item.open = src[nameStart:s.pos]
+		var err error
+		item.Attrs, item.close, err = s.attrs()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", item.Name, err)
+		}
+		dw.Items = append(dw.Items, item)
+	}
+}
+
+type scanner struct {
+	src string
+	pos int
+}
+
+func (s *scanner) peek() byte {
+	if s.pos >= len(s.src) {
+		return 0
+	}
+	return s.src[s.pos]
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	line := strings.Count(s.src[:min(s.pos, len(s.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.src) && strings.IndexByte(" \t\r\n", s.src[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+// name reads an identifier like print.margin.left.
+func (s *scanner) name() string {
+	start := s.pos
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		if c != '_' && c != '.' && c != '#' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		s.pos++
+	}
+	return s.src[start:s.pos]
+}
+
+// attrs reads the attributes up to the closing bracket of the current item. It returns the attributes and the
+// text following the last attribute including the closing bracket.
+func (s *scanner) attrs() ([]*Attr, string, error) {
+	var attrs []*Attr
+	for {
+		start := s.pos
+		// lists of values are separated by commas, e.g. (("a", number),("b", string))
+		for s.skipSpace(); s.peek() == ','; s.skipSpace() {
+			s.pos++
+		}
+		switch s.peek() {
+		case ')':
+			s.pos++
+			return attrs, s.src[start:s.pos], nil
+		case 0:
+			return nil, "", s.errorf("missing closing bracket")
+		}
+		a := &Attr{pre: s.src[start:s.pos]}
+		attrStart := s.pos
+		a.Name = s.name()
+		if a.Name == "" {
+			// value without name, e.g. ("arg", number) in arguments=(...)
+			value, err := s.value()
+			if err != nil {
+				return nil, "", err
+			}
+			a.Value = value
+		} else {
+			// blanks around = are allowed, e.g. print.orientation = 0
+			save := s.pos
+			s.skipSpace()
+			if s.peek() == '=' {
+				s.pos++
+				s.skipSpace()
+				value, err := s.value()
+				if err != nil {
+					return nil, "", fmt.Errorf("attribute %s: %v", a.Name, err)
+				}
+				a.Value = value
+			} else {
+				s.pos = save
+			}
+		}
+		a.raw = s.src[attrStart:s.pos]
+		attrs = append(attrs, a)
+	}
+}
+
+// value reads a quoted string, a bracketed list or a word like char(10) or yes.
+func (s *scanner) value() (string, error) {
+	start := s.pos
+	switch s.peek() {
+	case '"', '\'':
+		err := s.skipString()
+		if err != nil {
+			return "", err
+		}
+	case '(':
+		err := s.skipBrackets()
+		if err != nil {
+			return "", err
+		}
+	default:
+		for s.pos < len(s.src) && strings.IndexByte(" \t\r\n)", s.src[s.pos]) < 0 {
+			if s.src[s.pos] == '(' {
+				err := s.skipBrackets()
+				if err != nil {
+					return "", err
+				}
+				continue
+			}
+			if s.src[s.pos] == '"' || s.src[s.pos] == '\'' {
+				err := s.skipString()
+				if err != nil {
+					return "", err
+				}
+				continue
+			}
+			s.pos++
+		}
+	}
+	if s.pos == start {
+		return "", s.errorf("missing value")
+	}
+	return s.src[start:s.pos], nil
+}
+
+// skipString skips a string in double or single quotes. ~ escapes the following character.
+func (s *scanner) skipString() error {
+	start := s.pos
+	q := s.src[s.pos]
+	for s.pos++; s.pos < len(s.src); s.pos++ {
+		switch s.src[s.pos] {
+		case '~':
+			s.pos++
+		case q:
+			s.pos++
+			return nil
+		}
+	}
+	s.pos = start
+	return s.errorf("unterminated string")
+}
+
+// skipBrackets skips a bracketed expression including nested brackets and strings.
+func (s *scanner) skipBrackets() error {
+	start := s.pos
+	depth := 0
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case '"', '\'':
+			err := s.skipString()
+			if err != nil {
+				return err
+			}
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				s.pos++
+				return nil
+			}
+		}
+		s.pos++
+	}
+	s.pos = start
+	return s.errorf("missing closing bracket")
+}
@@ -0,0 +1,71 @@
+package datawindow
+
+import "strings"
+
+// Table is the table(...) item describing the result set and the data source of a DataWindow.
+type Table struct {
+	*Item
+}
+
+// Column is a column of the result set, e.g. column=(type=char(100) name=key dbname="t.key" ).
+type Column struct {
+	Name   string
+	Type   string // e.g. char(100), long, decimal(2)
+	DbName string
+	Attrs  []*Attr
+}
+
+// Argument is a retrieval argument, e.g. ("al_id", number).
+type Argument struct {
+	Name string
+	Type string
+}
+
+// Columns returns the columns of the result set in the order of their ids.
+func (t *Table) Columns() ([]*Column, error) {
+	var cols []*Column
+	for _, a := range t.Attrs {
+		if !strings.EqualFold(a.Name, "column") {
+			continue
+		}
+		attrs, err := a.Attrs()
+		if err != nil {
+			return nil, err
+		}
+		col := &Column{Attrs: attrs}
+		item := &Item{Attrs: attrs}
+		col.Name = item.Get("name")
+		col.Type = item.Get("type")
+		col.DbName = item.Get("dbname")
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// Retrieve returns the SELECT statement (or the PBSELECT syntax) of the DataWindow.
+func (t *Table) Retrieve() string {
+	return t.Get("retrieve")
+}
+
+// Arguments returns the retrieval arguments.
+func (t *Table) Arguments() ([]Argument, error) {
+	a := t.Attr("arguments")
+	if a == nil {
+		return nil, nil
+	}
+	list, err := a.Attrs()
+	if err != nil {
+		return nil, err
+	}
+	var args []Argument
+	for _, entry := range list {
+		// every argument is a value without name: ("al_id", number)
+		parts := strings.SplitN(strings.Trim(entry.Value, "()"), ",", 2)
+		arg := Argument{Name: strings.Trim(strings.TrimSpace(parts[0]), `"`)}
+		if len(parts) == 2 {
+			arg.Type = strings.TrimSpace(parts[1])
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
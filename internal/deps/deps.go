@@ -0,0 +1,341 @@
+// Package deps extracts the dependencies between the objects of a PowerBuilder target from their sources.
+//
+// The analysis is based on the syntax tree of the powerscript package and a few patterns within the scripts:
+// ancestors (from), types of instance, shared and global variables, create statements, function and event calls
+// on typed references, calls of global functions and the DataObject names used by DataWindow controls. Only
+// references to objects of the analysed sources end up in the graph, system types like window or long are ignored.
+package deps
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/datawindow"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/powerscript"
+)
+
+// Kinds of dependencies
+const (
+	KindAncestor   = "ancestor"
+	KindVariable   = "variable"
+	KindCreate     = "create"
+	KindCall       = "call"
+	KindDataObject = "dataobject"
+)
+
+// Source is the exported source of one object.
+type Source struct {
+	Library string // file name of the library, e.g. exf1.pbl
+	Name    string // entry name, e.g. u_exf_error_manager.sru
+	Src     string
+	Path    string // file the source was read from, empty for sources read from a library
+}
+
+// Object is a node of the graph.
+type Object struct {
+	Name    string       `json:"name"`  // object name without extension, e.g. u_exf_error_manager
+	Entry   string       `json:"entry"` // entry name, e.g. u_exf_error_manager.sru
+	Library string       `json:"library"`
+	Path    string       `json:"path,omitempty"` // see Source.Path
+	Deps    []Dependency `json:"dependencies"`
+}
+
+// Dependency is an edge of the graph. An object depending on another one in several ways has one dependency per kind.
+type Dependency struct {
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`  // first line of the source referencing the target this way
+	Lines  []int  `json:"lines"` // all lines of the source referencing the target this way, in ascending order
+}
+
+// Graph is the dependency graph of a set of objects.
+type Graph struct {
+	// Objects in the order of the sources
+	Objects []*Object
+	byName  map[string]*Object
+}
+
+// reference is a possible dependency found in a source, the name is not yet checked against the known objects.
+type reference struct {
+	kind string
+	name string
+	line int
+}
+
+// Build analyses the sources and returns the graph. If several libraries contain an object with the same name,
+// the first one is used, like PowerBuilder does with the library list. Sources which can not be parsed are
+// reported by warnFunc and are part of the graph without dependencies.
+func Build(sources []Source, warnFunc func(string)) *Graph {
+	g := &Graph{byName: make(map[string]*Object)}
+	refs := make(map[*Object][]reference)
+	for _, src := range sources {
+		name := strings.TrimSuffix(src.Name, path.Ext(src.Name))
+		if _, exists := g.byName[strings.ToLower(name)]; exists {
+			continue
+		}
+		obj := &Object{Name: name, Entry: src.Name, Library: src.Library, Path: src.Path}
+		g.Objects = append(g.Objects, obj)
+		g.byName[strings.ToLower(name)] = obj
+
+		var err error
+		refs[obj], err = analyze(src.Name, src.Src)
+		if err != nil {
+			warnFunc(fmt.Sprintf("could not analyze %s/%s: %v", src.Library, src.Name, err))
+		}
+	}
+
+	for _, obj := range g.Objects {
+		seen := make(map[[2]string]int) // index in Deps by target and kind
+		for _, ref := range refs[obj] {
+			target := g.Object(ref.name)
+			if target == nil || target == obj {
+				continue
+			}
+			key := [2]string{target.Name, ref.kind}
+			if i, ok := seen[key]; ok {
+				if !slices.Contains(obj.Deps[i].Lines, ref.line) {
+					obj.Deps[i].Lines = append(obj.Deps[i].Lines, ref.line)
+				}
+				continue
+			}
+			seen[key] = len(obj.Deps)
+			obj.Deps = append(obj.Deps, Dependency{Target: target.Name, Kind: ref.kind, Lines: []int{ref.line}})
+		}
+		for i := range obj.Deps {
+			slices.Sort(obj.Deps[i].Lines)
+			obj.Deps[i].Line = obj.Deps[i].Lines[0]
+		}
+		sort.Slice(obj.Deps, func(i, j int) bool {
+			if obj.Deps[i].Target != obj.Deps[j].Target {
+				return strings.ToLower(obj.Deps[i].Target) < strings.ToLower(obj.Deps[j].Target)
+			}
+			return obj.Deps[i].Kind < obj.Deps[j].Kind
+		})
+	}
+	return g
+}
+
+// Object returns the object with the given name (with or without extension) or nil.
+func (g *Graph) Object(name string) *Object {
+	name = strings.ToLower(name)
+	if obj, ok := g.byName[name]; ok {
+		return obj
+	}
+	return g.byName[strings.TrimSuffix(name, path.Ext(name))]
+}
+
+// Dependants returns the objects depending directly on the object with the given name.
+func (g *Graph) Dependants(name string) []*Object {
+	target := g.Object(name)
+	var objs []*Object
+	for _, obj := range g.Objects {
+		for _, dep := range obj.Deps {
+			if target != nil && strings.EqualFold(dep.Target, target.Name) {
+				objs = append(objs, obj)
+				break
+			}
+		}
+	}
+	return objs
+}
+
+var (
+	regexCreate     = regexp.MustCompile(`(?i)\bcreate\s+([a-z_][\w$#%-]*)`)
+	regexMemberCall = regexp.MustCompile(`(?i)\b([a-z_][\w$#%-]*)\s*\.\s*(?:(?:function|event|post|trigger|dynamic|static)\s+)*[a-z_][\w$#%-]*\s*\(`)
+	regexCall       = regexp.MustCompile(`(?i)\b([a-z_][\w$#%-]*)\s*\(`)
+	regexDataObject = regexp.MustCompile(`(?i)\bdataobject\s*=\s*["']([^"']+)["']`)
+	// local declarations like 'u_exf_ex lu_e' or 'n_cst_a lu_a, lu_b[]'
+	regexLocalVar  = regexp.MustCompile(`(?im)^[ \t]*([a-z_][\w$#%-]*)[ \t]+([a-z_][\w$#%-]*(?:\[[^\]]*\])?(?:[ \t]*,[ \t]*[a-z_][\w$#%-]*(?:\[[^\]]*\])?)*)[ \t]*(?:=.*)?\r?$`)
+	regexArrayDims = regexp.MustCompile(`\[[^\]]*\]`)
+)
+
+// statementKeywords are keywords which look like a type in a local declaration, e.g. 'destroy lu_e'
+var statementKeywords = map[string]bool{
+	"return": true, "destroy": true, "throw": true, "goto": true, "call": true, "halt": true, "if": true,
+	"elseif": true, "until": true, "while": true, "choose": true, "case": true, "next": true, "loop": true,
+}
+
+// analyze returns the references found in the source of an object.
+func analyze(entryName, src string) ([]reference, error) {
+	switch strings.ToLower(path.Ext(entryName)) {
+	case ".srd":
+		return analyzeDataWindow(src)
+	case ".sra", ".srf", ".srm", ".srs", ".sru", ".srw":
+	default:
+		// queries, pipelines and projects do not reference other objects
+		return nil, nil
+	}
+	f, err := powerscript.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []reference
+	// types within the object (controls, local structures) are no dependencies
+	local := make(map[string]bool)
+	for _, t := range f.Types {
+		local[strings.ToLower(t.Name)] = true
+	}
+	add := func(kind, name string, offset int) {
+		if name != "" && !local[strings.ToLower(name)] {
+			refs = append(refs, reference{kind, name, f.Line(offset)})
+		}
+	}
+
+	// variables by name, the types are needed to resolve member calls
+	vars := make(map[string]string)
+	for _, t := range f.Types {
+		add(KindAncestor, t.Ancestor, t.Start)
+		for _, m := range regexDataObject.FindAllStringSubmatchIndex(f.Text(t.Body), -1) {
+			add(KindDataObject, f.Src[t.Body.Start+m[2]:t.Body.Start+m[3]], t.Body.Start+m[0])
+		}
+	}
+	if f.Forward != nil {
+		for _, v := range f.Forward.Globals {
+			add(KindVariable, v.Type, v.Start)
+			vars[strings.ToLower(v.Name)] = v.Type
+		}
+	}
+	for _, b := range f.Variables {
+		for _, v := range b.Vars {
+			add(KindVariable, v.Type, v.Start)
+			vars[strings.ToLower(v.Name)] = v.Type
+		}
+	}
+
+	var bodies []script
+	for _, fn := range f.Functions {
+		bodies = append(bodies, scriptBody(f, fn.Body, fn.Params))
+	}
+	for _, e := range f.Events {
+		bodies = append(bodies, scriptBody(f, e.Body, e.Params))
+	}
+	for _, on := range f.OnBlocks {
+		bodies = append(bodies, script{text: f.Text(on.Body), start: on.Body.Start})
+	}
+	for _, body := range bodies {
+		for _, m := range regexDataObject.FindAllStringSubmatchIndex(body.text, -1) {
+			add(KindDataObject, body.text[m[2]:m[3]], body.offset(m[0]))
+		}
+		// the dataobject names are the only strings of interest
+		code := stripStringsAndComments(body.text)
+		localVars := localVariables(code, vars)
+		for _, m := range regexCreate.FindAllStringSubmatchIndex(code, -1) {
+			if name := code[m[2]:m[3]]; !strings.EqualFold(name, "using") {
+				add(KindCreate, name, body.offset(m[0]))
+			}
+		}
+		for _, m := range regexMemberCall.FindAllStringSubmatchIndex(code, -1) {
+			if typ, ok := localVars[strings.ToLower(code[m[2]:m[3]])]; ok {
+				add(KindCall, typ, body.offset(m[0]))
+			}
+		}
+		for _, m := range regexCall.FindAllStringSubmatchIndex(code, -1) {
+			// global functions, everything else is filtered by Build
+			add(KindCall, code[m[2]:m[3]], body.offset(m[0]))
+		}
+	}
+	return refs, nil
+}
+
+// script is the text of a function, event or on block.
+type script struct {
+	text   string
+	start  int // offset of the body within the source
+	prefix int // length of the declarations prepended to the body
+}
+
+// offset returns the offset within the source of an offset within the text. Offsets within the prepended
+// declarations are mapped to the start of the body.
+func (s script) offset(i int) int {
+	return s.start + max(i-s.prefix, 0)
+}
+
+// scriptBody returns the text of a function or event body. The parameters are prepended as declarations, so
+// localVariables finds them.
+func scriptBody(f *powerscript.File, body powerscript.Span, params []*powerscript.Param) script {
+	var sb strings.Builder
+	for _, p := range params {
+		sb.WriteString(p.Type + " " + p.Name + "\n")
+	}
+	prefix := sb.Len()
+	sb.WriteString(f.Text(body))
+	return script{text: sb.String(), start: body.Start, prefix: prefix}
+}
+
+// localVariables returns the variables of vars extended by the local declarations of a script.
+func localVariables(code string, vars map[string]string) map[string]string {
+	locals := make(map[string]string, len(vars))
+	for k, v := range vars {
+		locals[k] = v
+	}
+	for _, m := range regexLocalVar.FindAllStringSubmatch(code, -1) {
+		if statementKeywords[strings.ToLower(m[1])] {
+			continue
+		}
+		for _, name := range strings.Split(regexArrayDims.ReplaceAllString(m[2], ""), ",") {
+			locals[strings.ToLower(strings.TrimSpace(name))] = m[1]
+		}
+	}
+	return locals
+}
+
+// stripStringsAndComments replaces string literals and comments of a script by blanks.
+func stripStringsAndComments(code string) string {
+	b := []byte(code)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '"' || b[i] == '\'':
+			q := b[i]
+			for i++; i < len(b) && b[i] != q && b[i] != '\n'; i++ {
+				if b[i] == '~' && i+1 < len(b) {
+					b[i] = ' '
+					i++
+				}
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			for ; i < len(b) && !(b[i] == '*' && i+1 < len(b) && b[i+1] == '/'); i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+			if i+1 < len(b) {
+				b[i], b[i+1] = ' ', ' '
+				i++
+			}
+		}
+	}
+	return string(b)
+}
+
+// analyzeDataWindow returns the nested reports of a DataWindow.
+func analyzeDataWindow(src string) ([]reference, error) {
+	dw, err := datawindow.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	var refs []reference
+	for _, item := range dw.Items {
+		if name := item.Get("dataobject"); name != "" {
+			// the items do not know their position, the first occurrence of the name is good enough
+			line := 1
+			for _, m := range regexDataObject.FindAllStringSubmatchIndex(src, -1) {
+				if strings.EqualFold(src[m[2]:m[3]], name) {
+					line = strings.Count(src[:m[0]], "\n") + 1
+					break
+				}
+			}
+			refs = append(refs, reference{KindDataObject, name, line})
+		}
+	}
+	return refs, nil
+}
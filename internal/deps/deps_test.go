@@ -0,0 +1,228 @@
+package deps
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const srcBase = `$PBExportHeader$u_base.sru
+forward
+global type u_base from nonvisualobject
+end type
+end forward
+
+global type u_base from nonvisualobject
+end type
+global u_base u_base
+
+forward prototypes
+public function integer of_run ()
+end prototypes
+
+public function integer of_run ();u_helper lu_helper
+lu_helper = create u_helper
+return lu_helper.of_help()
+end function
+`
+
+const srcHelper = `$PBExportHeader$u_helper.sru
+forward
+global type u_helper from nonvisualobject
+end type
+end forward
+
+global type u_helper from nonvisualobject
+end type
+global u_helper u_helper
+
+forward prototypes
+public function integer of_help ()
+end prototypes
+
+public function integer of_help ();// u_base is not used here: create u_base
+string ls_text = "create u_base"
+return f_help()
+end function
+`
+
+const srcWindow = `$PBExportHeader$w_main.srw
+forward
+global type w_main from window
+end type
+type dw_1 from datawindow within w_main
+end type
+end forward
+
+global type w_main from window
+end type
+global w_main w_main
+
+type variables
+u_base iu_base
+end variables
+
+on w_main.create
+this.dw_1=create dw_1
+end on
+
+event open;iu_base.event ue_start(1)
+end event
+
+type dw_1 from datawindow within w_main
+string dataobject = "d_main"
+end type
+`
+
+const srcFunction = `$PBExportHeader$f_help.srf
+global type f_help from function_object
+end type
+
+forward prototypes
+global function integer f_help ()
+end prototypes
+
+global function integer f_help ();return 1
+end function
+`
+
+const srcDw = `$PBExportHeader$d_main.srd
+release 22;
+datawindow(units=0 )
+report(band=detail dataobject="d_nested" x="0" y="0" height="100" width="100" name=dw_nested )
+`
+
+const srcNested = `$PBExportHeader$d_nested.srd
+release 22;
+datawindow(units=0 )
+`
+
+func TestBuild(t *testing.T) {
+	var warnings []string
+	g := Build([]Source{
+		{Library: "a.pbl", Name: "u_base.sru", Src: srcBase},
+		{Library: "b.pbl", Name: "u_helper.sru", Src: srcHelper},
+		{Library: "a.pbl", Name: "w_main.srw", Src: srcWindow},
+		{Library: "b.pbl", Name: "f_help.srf", Src: srcFunction},
+		{Library: "b.pbl", Name: "d_main.srd", Src: srcDw},
+		{Library: "a.pbl", Name: "d_nested.srd", Src: srcNested},
+		{Library: "b.pbl", Name: "w_main.srw", Src: "duplicate objects are ignored"},
+	}, func(s string) { warnings = append(warnings, s) })
+
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	tests := []struct {
+		name string
+		want []Dependency
+	}{
+		{"u_base", []Dependency{{"u_helper", KindCall, 17, []int{17}}, {"u_helper", KindCreate, 16, []int{16}}}},
+		{"u_helper", []Dependency{{"f_help", KindCall, 17, []int{17}}}},
+		{"w_main", []Dependency{{"d_main", KindDataObject, 25, []int{25}}, {"u_base", KindCall, 21, []int{21}}, {"u_base", KindVariable, 14, []int{14}}}},
+		{"f_help", nil},
+		{"d_main.srd", []Dependency{{"d_nested", KindDataObject, 4, []int{4}}}},
+	}
+	for _, tt := range tests {
+		obj := g.Object(tt.name)
+		if obj == nil {
+			t.Errorf("object %s not found", tt.name)
+			continue
+		}
+		if !reflect.DeepEqual(obj.Deps, tt.want) {
+			t.Errorf("%s: got dependencies %v, expected %v", tt.name, obj.Deps, tt.want)
+		}
+	}
+	if obj := g.Object("w_main"); obj.Library != "a.pbl" {
+		t.Errorf("w_main should be taken from the first library, got %s", obj.Library)
+	}
+
+	var dependants []string
+	for _, obj := range g.Dependants("u_helper") {
+		dependants = append(dependants, obj.Name)
+	}
+	if !reflect.DeepEqual(dependants, []string{"u_base"}) {
+		t.Errorf("wrong dependants of u_helper: %v", dependants)
+	}
+
+	cycles := g.LibraryCycles()
+	if len(cycles) != 1 || !reflect.DeepEqual(cycles[0].Libraries, []string{"a.pbl", "b.pbl"}) {
+		t.Fatalf("expected cycle between a.pbl and b.pbl, got %+v", cycles)
+	}
+	if len(cycles[0].Edges) != 2 || !strings.HasPrefix(cycles[0].Edges[1], "b.pbl -> a.pbl: d_main") {
+		t.Errorf("wrong edges of cycle: %v", cycles[0].Edges)
+	}
+
+	dot := g.Dot()
+	if !strings.Contains(dot, `"w_main" -> "d_main" [label="dataobject"];`) || !strings.Contains(dot, `label="b.pbl";`) {
+		t.Errorf("unexpected dot output:\n%s", dot)
+	}
+}
+
+func TestBuildInvalidSource(t *testing.T) {
+	var warnings []string
+	g := Build([]Source{{Library: "a.pbl", Name: "d_broken.srd", Src: "no datawindow"}}, func(s string) { warnings = append(warnings, s) })
+	if len(warnings) != 1 || g.Object("d_broken") == nil {
+		t.Errorf("expected a warning and the object without dependencies, got %v", warnings)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	src := func(name, body string) Source {
+		return Source{Library: "a.pbl", Name: name + ".sru", Src: "global type " + name + " from nonvisualobject\nend type\n\n" +
+			"forward prototypes\npublic subroutine of_run ()\nend prototypes\n\n" +
+			"public subroutine of_run ();" + body + "\nend subroutine\n"}
+	}
+	g := Build([]Source{
+		src("u_a", "create u_b"),
+		src("u_b", "create u_c"),
+		src("u_c", "create u_b"),
+		src("u_d", ""),
+		{Library: "a.pbl", Name: "q_query.srq", Src: "not analyzed"},
+	}, func(s string) { t.Errorf("unexpected warning: %s", s) })
+
+	var got [][]string
+	for _, c := range g.Components() {
+		var names []string
+		for _, obj := range c {
+			names = append(names, obj.Name)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{{"u_b", "u_c"}, {"u_a"}, {"u_d"}, {"q_query"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got components %v, expected %v", got, want)
+	}
+}
+
+func TestLayerViolations(t *testing.T) {
+	g := Build([]Source{
+		{Library: "app1.pbl", Name: "w_main.srw", Src: srcWindow},
+		{Library: "base1.pbl", Name: "u_base.sru", Src: srcBase},
+		{Library: "base2.pbl", Name: "u_helper.sru", Src: srcHelper},
+		{Library: "core1.pbl", Name: "f_help.srf", Src: srcFunction},
+		{Library: "other.pbl", Name: "d_main.srd", Src: srcDw},
+		{Library: "base2.pbl", Name: "u_twice.sru", Src: "global type u_twice from nonvisualobject\nend type\n\n" +
+			"forward prototypes\npublic subroutine of_run ()\nend prototypes\n\n" +
+			"public subroutine of_run ();f_help()\nif f_help() > 0 then return\nend subroutine\n"},
+	}, func(string) {})
+
+	layers := map[string]int{"app1.pbl": 0, "base1.pbl": 1, "base2.pbl": 2, "core1.pbl": 1}
+	violations := g.LayerViolations(func(library string) int {
+		if l, ok := layers[library]; ok {
+			return l
+		}
+		return -1
+	})
+
+	var got []string
+	for _, v := range violations {
+		got = append(got, fmt.Sprintf("%s:%d -> %s (%s)", v.Object.Name, v.Line, v.Target.Name, v.Dependency.Kind))
+	}
+	// u_helper (layer 2) calls f_help (layer 1), u_base calls u_helper of a lower layer which is fine and
+	// w_main uses d_main of an unknown library. Both calls of u_twice are reported.
+	want := []string{"u_helper:17 -> f_help (call)", "u_twice:8 -> f_help (call)", "u_twice:9 -> f_help (call)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got violations %v, want %v", got, want)
+	}
+}
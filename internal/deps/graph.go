@@ -0,0 +1,168 @@
+package deps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LibraryCycle is a set of libraries depending on each other. Edges contains an example dependency for every
+// dependency between two libraries of the cycle, e.g. "exf1.pbl -> net1.pbl: u_exf_ex -> u_net_mail (call)".
+type LibraryCycle struct {
+	Libraries []string `json:"libraries"`
+	Edges     []string `json:"edges"`
+}
+
+// LibraryCycles returns the libraries which depend on each other (strongly connected components with more than
+// one library).
+func (g *Graph) LibraryCycles() []LibraryCycle {
+	// edges between libraries with one example dependency each
+	edges := make(map[string]map[string]string)
+	var libs []string
+	for _, obj := range g.Objects {
+		if _, ok := edges[obj.Library]; !ok {
+			edges[obj.Library] = make(map[string]string)
+			libs = append(libs, obj.Library)
+		}
+	}
+	for _, obj := range g.Objects {
+		for _, dep := range obj.Deps {
+			target := g.Object(dep.Target)
+			if target.Library == obj.Library {
+				continue
+			}
+			if _, ok := edges[obj.Library][target.Library]; !ok {
+				edges[obj.Library][target.Library] = fmt.Sprintf("%s -> %s (%s)", obj.Name, target.Name, dep.Kind)
+			}
+		}
+	}
+
+	var cycles []LibraryCycle
+	for _, scc := range stronglyConnected(libs, func(lib string) []string {
+		var next []string
+		for target := range edges[lib] {
+			next = append(next, target)
+		}
+		sort.Strings(next)
+		return next
+	}) {
+		if len(scc) < 2 {
+			continue
+		}
+		sort.Strings(scc)
+		c := LibraryCycle{Libraries: scc}
+		for _, from := range scc {
+			for _, to := range scc {
+				if example, ok := edges[from][to]; ok {
+					c.Edges = append(c.Edges, fmt.Sprintf("%s -> %s: %s", from, to, example))
+				}
+			}
+		}
+		cycles = append(cycles, c)
+	}
+	return cycles
+}
+
+// Components returns the objects grouped by strongly connected components, i.e. objects depending on each other
+// (directly or indirectly) are in the same component. A component comes after all components it depends on, so
+// importing the components in this order imports the dependencies of an object first. Independent components keep
+// the order of the sources.
+func (g *Graph) Components() [][]*Object {
+	var names []string
+	order := make(map[*Object]int)
+	for i, obj := range g.Objects {
+		names = append(names, strings.ToLower(obj.Name))
+		order[obj] = i
+	}
+	sccs := stronglyConnected(names, func(name string) []string {
+		var next []string
+		for _, dep := range g.byName[name].Deps {
+			next = append(next, strings.ToLower(dep.Target))
+		}
+		return next
+	})
+	components := make([][]*Object, len(sccs))
+	for i, scc := range sccs {
+		for _, name := range scc {
+			components[i] = append(components[i], g.byName[name])
+		}
+		// keep the order of the sources within a component
+		sort.Slice(components[i], func(a, b int) bool {
+			return order[components[i][a]] < order[components[i][b]]
+		})
+	}
+	return components
+}
+
+// stronglyConnected returns the strongly connected components of a graph (Tarjan's algorithm). The components are
+// returned in reverse topological order, i.e. a component comes after all components it depends on.
+func stronglyConnected(nodes []string, next func(string) []string) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+
+	var visit func(n string)
+	visit = func(n string) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, m := range next(n) {
+			if _, visited := index[m]; !visited {
+				visit(m)
+				low[n] = min(low[n], low[m])
+			} else if onStack[m] {
+				low[n] = min(low[n], index[m])
+			}
+		}
+		if low[n] == index[n] {
+			var scc []string
+			for {
+				m := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[m] = false
+				scc = append(scc, m)
+				if m == n {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, n := range nodes {
+		if _, visited := index[n]; !visited {
+			visit(n)
+		}
+	}
+	return sccs
+}
+
+// Dot returns the graph in the DOT language of Graphviz. The objects are grouped by library.
+func (g *Graph) Dot() string {
+	var sb strings.Builder
+	sb.WriteString("digraph deps {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	var libs []string
+	byLib := make(map[string][]*Object)
+	for _, obj := range g.Objects {
+		if _, ok := byLib[obj.Library]; !ok {
+			libs = append(libs, obj.Library)
+		}
+		byLib[obj.Library] = append(byLib[obj.Library], obj)
+	}
+	for i, lib := range libs {
+		fmt.Fprintf(&sb, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i, lib)
+		for _, obj := range byLib[lib] {
+			fmt.Fprintf(&sb, "\t\t%q;\n", obj.Name)
+		}
+		sb.WriteString("\t}\n")
+	}
+	for _, obj := range g.Objects {
+		for _, dep := range obj.Deps {
+			fmt.Fprintf(&sb, "\t%q -> %q [label=%q];\n", obj.Name, dep.Target, dep.Kind)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package deps
+
+import "strings"
+
+// LayerViolation is a dependency of an object on an object of a higher layer.
+type LayerViolation struct {
+	Object      *Object
+	Dependency  Dependency
+	Target      *Object
+	Layer       int // layer of the object
+	TargetLayer int // layer of the target, a higher layer (smaller index) than Layer
+	Line        int // line of the reference, one of Dependency.Lines
+}
+
+// LayerViolations returns the dependencies of objects on objects of a higher layer. layerOf returns the layer of
+// a library, 0 is the highest layer, objects of a layer may only depend on objects of the same or a lower layer (greater index).
+// Libraries with a negative layer (unknown libraries) are not checked. Every line referencing the target is a
+// violation of its own.
+func (g *Graph) LayerViolations(layerOf func(library string) int) []LayerViolation {
+	layers := make(map[string]int)
+	layer := func(library string) int {
+		l, ok := layers[library]
+		if !ok {
+			l = layerOf(library)
+			layers[library] = l
+		}
+		return l
+	}
+
+	var violations []LayerViolation
+	for _, obj := range g.Objects {
+		objLayer := layer(obj.Library)
+		if objLayer < 0 {
+			continue
+		}
+		for _, dep := range obj.Deps {
+			target := g.Object(dep.Target)
+			if strings.EqualFold(target.Library, obj.Library) {
+				continue
+			}
+			targetLayer := layer(target.Library)
+			if targetLayer < 0 || targetLayer >= objLayer {
+				continue
+			}
+			for _, line := range dep.Lines {
+				violations = append(violations, LayerViolation{
+					Object:      obj,
+					Dependency:  dep,
+					Target:      target,
+					Layer:       objLayer,
+					TargetLayer: targetLayer,
+					Line:        line,
+				})
+			}
+		}
+	}
+	return violations
+}
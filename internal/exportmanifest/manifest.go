@@ -0,0 +1,133 @@
+// Package exportmanifest records which version of each object has been exported, so an incremental export only
+// writes the objects which changed in the library since the last export.
+package exportmanifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the manifest in the export folder.
+const FileName = ".pbmanager-export.json"
+
+// Entry is an exported object.
+type Entry struct {
+	Modified time.Time `json:"modified"` // modification time of the library entry
+	Size     uint32    `json:"size"`     // size of the library entry
+	Hash     string    `json:"hash"`     // hash of the written file
+	File     string    `json:"file"`     // written file, relative to the export folder
+}
+
+// Manifest lists the exported objects per library (file name of the library, e.g. inf1.pbl) and entry name.
+// It may be used by several goroutines exporting different libraries.
+type Manifest struct {
+	Libraries map[string]map[string]Entry `json:"libraries"`
+
+	dir string
+	mu  sync.Mutex
+}
+
+// Load reads the manifest of an export folder. If there is none, an empty manifest is returned.
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{Libraries: make(map[string]map[string]Entry), dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read export manifest: %v", err)
+	}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse export manifest %s: %v", filepath.Join(dir, FileName), err)
+	}
+	if m.Libraries == nil {
+		m.Libraries = make(map[string]map[string]Entry)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to the export folder.
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(m.dir, FileName), data, 0o664)
+	if err != nil {
+		return fmt.Errorf("could not write export manifest: %v", err)
+	}
+	return nil
+}
+
+// Hash returns the hash of the content of an exported file.
+func Hash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// Entries returns the exported objects of a library keyed by the lower case entry name.
+func (m *Manifest) Entries(library string) map[string]Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make(map[string]Entry)
+	for k, e := range m.Libraries[strings.ToLower(library)] {
+		entries[k] = e
+	}
+	return entries
+}
+
+// Unchanged returns true if the library entry has the same modification time and size as at the last export and
+// the exported file has not been changed or deleted since.
+func (m *Manifest) Unchanged(library, entry string, modified time.Time, size uint32) bool {
+	m.mu.Lock()
+	e, ok := m.Libraries[strings.ToLower(library)][strings.ToLower(entry)]
+	m.mu.Unlock()
+	if !ok || !e.Modified.Equal(modified) || e.Size != size {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(m.dir, e.File))
+	return err == nil && Hash(data) == e.Hash
+}
+
+// FileUnchanged returns true if the file exists with the given content.
+func FileUnchanged(path string, data []byte) bool {
+	old, err := os.ReadFile(path)
+	return err == nil && Hash(old) == Hash(data)
+}
+
+// Set records an exported object, path is the written file.
+func (m *Manifest) Set(library, entry string, modified time.Time, size uint32, path string, data []byte) {
+	rel, err := filepath.Rel(m.dir, path)
+	if err != nil {
+		rel = path
+	}
+	lib := strings.ToLower(library)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Libraries[lib] == nil {
+		m.Libraries[lib] = make(map[string]Entry)
+	}
+	m.Libraries[lib][strings.ToLower(entry)] = Entry{Modified: modified, Size: size, Hash: Hash(data), File: rel}
+}
+
+// Remove removes an object from the manifest.
+func (m *Manifest) Remove(library, entry string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Libraries[strings.ToLower(library)], strings.ToLower(entry))
+}
+
+// Path returns the absolute path of an exported file.
+func (m *Manifest) Path(e Entry) string {
+	return filepath.Join(m.dir, e.File)
+}
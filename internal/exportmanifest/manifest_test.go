@@ -0,0 +1,70 @@
+package exportmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	path := filepath.Join(dir, "inf1.pbl", "u_a.sru")
+	data := []byte("forward\r\nend forward\r\n")
+	if err = os.MkdirAll(filepath.Dir(path), 0o775); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0o664); err != nil {
+		t.Fatal(err)
+	}
+	m.Set("INF1.pbl", "u_a.sru", modified, 120, path, data)
+	if err = m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		entry    string
+		modified time.Time
+		size     uint32
+		want     bool
+	}{
+		{"unchanged", "U_A.sru", modified, 120, true},
+		{"modified", "u_a.sru", modified.Add(time.Second), 120, false},
+		{"size", "u_a.sru", modified, 121, false},
+		{"unknown", "u_b.sru", modified, 120, false},
+	}
+	for _, tt := range tests {
+		if got := m.Unchanged("inf1.pbl", tt.entry, tt.modified, tt.size); got != tt.want {
+			t.Errorf("%s: Unchanged() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if !FileUnchanged(path, data) || FileUnchanged(path, []byte("x")) {
+		t.Errorf("FileUnchanged() does not compare the content")
+	}
+	if err = os.WriteFile(path, []byte("edited"), 0o664); err != nil {
+		t.Fatal(err)
+	}
+	if m.Unchanged("inf1.pbl", "u_a.sru", modified, 120) {
+		t.Errorf("Unchanged() = true for an edited file")
+	}
+
+	e := m.Entries("inf1.pbl")["u_a.sru"]
+	if m.Path(e) != path {
+		t.Errorf("Path() = %s, want %s", m.Path(e), path)
+	}
+	m.Remove("inf1.pbl", "U_A.sru")
+	if len(m.Entries("inf1.pbl")) != 0 {
+		t.Errorf("Remove() did not remove the entry")
+	}
+}
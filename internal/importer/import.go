@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,90 +13,209 @@ import (
 
 	_ "embed"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/buildlog"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/deps"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
-	pborca "github.com/informaticon/lib.go.base.pborca"
 )
 
 //go:embed pbdom.pbl
 var pbdomPbl []byte // can't be imported by source
 
-// Import tries to import into multiple pbls.
-// It tries to do it multiple times, so it also works from circular dependencies.
-// TODO it is not needed anymore for backporting: If this changes:
-//   - sort pbls revers the lib list, keep track of pbls that are imported completely to skip those in further iterations.
-//   - it might help to start with inf1 until no error reduction can be achieved
-//   - keeping track of source .sr* files (import errors) does not help. They must all be import not only partially
-//   - sorting after src type helps (structures first, ...)
-//   - if the orca server crashes on layer 2 pbls, try to import layer one only until no error reduction can be observed.
-func Import(orcaServer *pborca.Orca, pbtFilePath string, srcFiles, pblFiles []string) error {
-	minRun := 15
-	maxRun := len(srcFiles) * 3
-	lastErrCount := 5000
-	var errs []error
-
-	// To speed up the backporting process, only import pbls with error counter > 0 and keep track of errors per PBL.
-	// i.e. avoid importing 5 times one PBL that is already completely imported.
-	errLookUpMap := make(map[string]int)
-	// lastInf1Err := -1 // handle inf1 first to reduce initial errors
-
-	for iteration := range maxRun {
-		t1 := time.Now()
-		// collected errors of one iteration, number should decrease with each iteration
-		errs = make([]error, 0)
-		for i, pblFilePath := range pblFiles {
-			if errCount, ok := errLookUpMap[filepath.Base(pblFilePath)]; ok {
-				if errCount == 0 {
-					fmt.Println("skip", filepath.Base(pblFilePath), "has already 0 import errors")
-					continue
-				}
-			}
+// maxPasses limits how often the objects of a component (and the failed objects at the end) are imported again.
+const maxPasses = 5
 
-			// first feed inf1 to reduce later errors
-			/*if !strings.Contains(filepath.Base(pblFilePath), "inf1.pbl") && lastInf1Err == -1 {
-				continue
-			} else if lastInf1Err == -1 {
-				errInf1 := processPbl(pblFilePath, srcFiles[i], pbtFilePath, orcaServer, &srcMap)
-				fmt.Println("\terror counter:", len(errInf1))
-				for len(errInf1) != lastInf1Err {
-					lastInf1Err = len(errInf1)
-					errInf1 = processPbl(pblFilePath, srcFiles[i], pbtFilePath, orcaServer, &srcMap)
-					fmt.Println("\terror counter:", len(errInf1))
-				}
-			}*/
-
-			errSources := processPbl(pblFilePath, srcFiles[i], pbtFilePath, orcaServer)
-			fmt.Println("\terror counter:", len(errSources))
-			errLookUpMap[filepath.Base(pblFilePath)] = len(errSources)
-			errs = append(errs, errSources...)
-		}
+// srcObject is a source file to import into a pbl.
+type srcObject struct {
+	pblFilePath string
+	srcFilePath string
+	binFilePath string // .bin file with the OLE data of the object or empty
+	srcData     []byte
+}
 
-		fmt.Printf("Run %d took %s\n", iteration, time.Since(t1).Truncate(time.Second).String())
-		if len(errs) == 0 {
-			return nil
+func (o *srcObject) name() string {
+	return strings.TrimSuffix(filepath.Base(o.srcFilePath), filepath.Ext(o.srcFilePath))
+}
+
+// Failure is an object which could not be imported.
+type Failure struct {
+	Library string
+	Object  string
+	Err     error
+}
+
+// ImportError lists the objects which could not be imported.
+type ImportError struct {
+	Failures []Failure
+}
+
+func (e *ImportError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d objects could not be imported:", len(e.Failures))
+	for _, f := range e.Failures {
+		fmt.Fprintf(&sb, "\n\t%s/%s: %v", f.Library, f.Object, f.Err)
+	}
+	return sb.String()
+}
+
+// Messages returns the compiler messages of the failed objects.
+func (e *ImportError) Messages() []buildlog.Message {
+	var msgs []buildlog.Message
+	for _, f := range e.Failures {
+		msgs = append(msgs, buildlog.FromError(f.Library, f.Object, f.Err)...)
+	}
+	return msgs
+}
+
+// Import imports the source folders srcFiles into the pbls pblFiles (srcFiles[i] belongs to pblFiles[i]).
+// The objects are imported in the order of their dependencies (see deps.Graph.Components), so an object is
+// imported after its ancestors and the objects it uses. Objects depending on each other are imported together and
+// repeated as long as the number of errors decreases. Objects which still fail are reported by an *ImportError.
+// importedFunc is called for every imported object, it may be nil.
+func Import(orcaServer backend.Backend, pbtFilePath string, srcFiles, pblFiles []string, importedFunc func(library, object string)) error {
+	var objs []*srcObject
+	for i, pblFilePath := range pblFiles {
+		if filepath.Base(pblFilePath) == "pbdom.pbl" {
+			fmt.Println("use embedded pbdom.pbl, skip source import")
+			err := os.WriteFile(pblFilePath, pbdomPbl, 0o644)
+			if err != nil {
+				return err
+			}
+			continue
 		}
-		if len(errs) >= lastErrCount && iteration > minRun {
-			return fmt.Errorf("compilation errors occured (multiple tries did not help): %v", errs)
+		found, err := collectSrcObjects(pblFilePath, srcFiles[i])
+		if err != nil {
+			return err
 		}
+		objs = append(objs, found...)
+	}
+	return importSrcObjects(orcaServer, pbtFilePath, objs, importedFunc)
+}
+
+// Source is the source of an object to import which is not read from a source folder, e.g. the result of a merge.
+type Source struct {
+	Library string // path of the pbl
+	Entry   string // entry name, e.g. w_main.srw
+	Src     []byte
+}
 
-		lastErrCount = len(errs)
-		fmt.Printf("Got %d errors in run %d. Retry...\n", lastErrCount, iteration)
+// ImportSources imports the sources like Import does with the files of source folders.
+func ImportSources(orcaServer backend.Backend, pbtFilePath string, sources []Source, importedFunc func(library, object string)) error {
+	var objs []*srcObject
+	for _, src := range sources {
+		objs = append(objs, &srcObject{pblFilePath: src.Library, srcFilePath: src.Entry, srcData: src.Src})
 	}
-	return fmt.Errorf("compilation errors occured: %v", errs)
+	return importSrcObjects(orcaServer, pbtFilePath, objs, importedFunc)
 }
 
-// processPbl imports all source file of one pbl directory and returns all obtained import errors.
-func processPbl(pblFilePath, srcFilePath, pbtFilePath string, orcaServer *pborca.Orca) (errs []error) {
-	if filepath.Base(pblFilePath) == "pbdom.pbl" {
-		fmt.Println("use embedded pbdom.pbl, skip source import")
-		err := os.WriteFile(pblFilePath, pbdomPbl, 0o644)
-		if err != nil {
-			log.Fatal(err)
+// importSrcObjects imports the objects in the order of their dependencies (see Import).
+func importSrcObjects(orcaServer backend.Backend, pbtFilePath string, objs []*srcObject, importedFunc func(library, object string)) error {
+	t1 := time.Now()
+	var sources []deps.Source
+	byKey := make(map[string]*srcObject)
+	for _, obj := range objs {
+		sources = append(sources, deps.Source{Library: filepath.Base(obj.pblFilePath), Name: filepath.Base(obj.srcFilePath), Src: string(obj.srcData)})
+		byKey[objKey(filepath.Base(obj.pblFilePath), filepath.Base(obj.srcFilePath))] = obj
+	}
+	graph := deps.Build(sources, func(msg string) {
+		fmt.Println("WARN: ", msg)
+	})
+	var components [][]*srcObject
+	for _, c := range graph.Components() {
+		var comp []*srcObject
+		for _, o := range c {
+			comp = append(comp, byKey[objKey(o.Library, o.Entry)])
+			delete(byKey, objKey(o.Library, o.Entry))
 		}
+		components = append(components, comp)
+	}
+	// objects existing in several pbls are only once in the graph, the others are imported at the end
+	for _, obj := range objs {
+		if _, ok := byKey[objKey(filepath.Base(obj.pblFilePath), filepath.Base(obj.srcFilePath))]; ok {
+			components = append(components, []*srcObject{obj})
+		}
+	}
+	fmt.Printf("importing %d objects in %d steps\n", len(objs), len(components))
+
+	var failed []*srcObject
+	errs := make(map[*srcObject]error)
+	for _, comp := range components {
+		failed = append(failed, importObjects(orcaServer, pbtFilePath, comp, errs, importedFunc)...)
+	}
+	if len(failed) > 0 {
+		// the dependency analysis may miss dependencies (e.g. dynamic calls), so the failed objects get another try
+		fmt.Printf("%d objects failed, retry...\n", len(failed))
+		failed = importObjects(orcaServer, pbtFilePath, failed, errs, importedFunc)
+	}
+	fmt.Printf("import of %d objects took %s\n", len(objs), time.Since(t1).Truncate(time.Second).String())
+
+	if len(failed) == 0 {
 		return nil
 	}
+	importErr := &ImportError{}
+	for _, obj := range failed {
+		importErr.Failures = append(importErr.Failures, Failure{Library: filepath.Base(obj.pblFilePath), Object: obj.name(), Err: errs[obj]})
+	}
+	return importErr
+}
+
+func objKey(library, entry string) string {
+	return strings.ToLower(library + "/" + entry)
+}
 
-	fmt.Println("backport", filepath.Base(pblFilePath))
+// importObjects imports the objects as long as the number of failed objects decreases (at most maxPasses times).
+// The errors of the last try are stored in errs, the failed objects are returned.
+func importObjects(orcaServer backend.Backend, pbtFilePath string, objs []*srcObject, errs map[*srcObject]error, importedFunc func(library, object string)) []*srcObject {
+	for pass := 0; pass < maxPasses && len(objs) > 0; pass++ {
+		var failed []*srcObject
+		for _, obj := range objs {
+			err := importObject(orcaServer, pbtFilePath, obj)
+			if err != nil {
+				errs[obj] = err
+				failed = append(failed, obj)
+				continue
+			}
+			delete(errs, obj)
+			if importedFunc != nil {
+				importedFunc(filepath.Base(obj.pblFilePath), obj.name())
+			}
+		}
+		if len(failed) == len(objs) {
+			return failed
+		}
+		objs = failed
+	}
+	return objs
+}
 
+// importObject imports the source of an object and the OLE data of its .bin file.
+func importObject(orcaServer backend.Backend, pbtFilePath string, obj *srcObject) error {
+	// If .bin counterpart is existent, first import only the source part up to
+	// "Start of PowerBuilder Binary Data Section..." as actual object type (pbe_datawindow, pbe_window, ...)
+	// In a second step call the same function immediately after containing the binary data part as PBORCA_BINARY.
+	// Since bin data part is not real part of source file. ONe can simply use srcData for the first step.
+	errSrc := orcaServer.SetObjSource(pbtFilePath, obj.pblFilePath, obj.name(), obj.srcData)
+	if errSrc != nil && strings.Contains(errSrc.Error(), "connectex") { // server crashed
+		panic(errSrc)
+	}
+	if obj.binFilePath == "" {
+		return errSrc
+	}
+	binSection, err := GetBinarySectionFromBin(obj.binFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to set OLE binary section to matching bin file %s: %v",
+			obj.binFilePath, errors.Join(err, errSrc))
+	}
+	err = orcaServer.SetObjBinary(pbtFilePath, obj.pblFilePath, obj.name(), binSection)
+	if err != nil {
+		return fmt.Errorf("failed to import binary data section in a second step %s: %v",
+			obj.binFilePath, errors.Join(err, errSrc))
+	}
+	return errSrc
+}
+
+// collectSrcObjects returns the source files of one pbl directory in the order of sortSrcTypeName.
+func collectSrcObjects(pblFilePath, srcFilePath string) ([]*srcObject, error) {
 	var foundSrcFiles []string
 	err := filepath.WalkDir(srcFilePath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -108,71 +226,43 @@ func processPbl(pblFilePath, srcFilePath, pbtFilePath string, orcaServer *pborca
 			return nil
 		}
 		if !d.IsDir() {
-			// first collect all files, the order of importing matters, e.g. some.bin after some.srw
 			foundSrcFiles = append(foundSrcFiles, path)
 		}
 		return nil
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// order according to file type
 	foundSrcFiles = sortSrcTypeName(foundSrcFiles)
-	fmt.Println("\tfound source files:", len(foundSrcFiles))
+	fmt.Printf("found %d source files for %s\n", len(foundSrcFiles), filepath.Base(pblFilePath))
 
-	// get all .bin files, might be several; key filename, val index
-	binFiles := make(map[string]int)
-	for i, foundSrcFile := range foundSrcFiles {
+	// get all .bin files, might be several
+	binFiles := make(map[string]string)
+	for _, foundSrcFile := range foundSrcFiles {
 		if filepath.Ext(foundSrcFile) == ".bin" {
-			binFiles[strings.TrimSuffix(filepath.Base(foundSrcFile), filepath.Ext(foundSrcFile))] = i
+			binFiles[strings.TrimSuffix(foundSrcFile, filepath.Ext(foundSrcFile))] = foundSrcFile
 		}
 	}
 
+	var objs []*srcObject
 	for _, foundSrcFile := range foundSrcFiles {
 		if filepath.Ext(foundSrcFile) == ".bin" {
 			continue
 		}
-
-		objName := strings.TrimSuffix(filepath.Base(foundSrcFile), filepath.Ext(filepath.Base(foundSrcFile)))
 		srcData, err := utils.ReadPbSource(foundSrcFile)
 		if err != nil {
-			log.Fatal(err) // nothing to collect: no compile error but backport failure.
-		}
-
-		// If .bin counterpart is existent, first import only the source part up to
-		// "Start of PowerBuilder Binary Data Section..." as actual object type (pbe_datawindow, pbe_window, ...)
-		// In a second step call the same function immediately after containing the binary data part as PBORCA_BINARY.
-		// Since bin data part is not real part of source file. ONe can simply use srcData for the first step.
-		errSrc := orcaServer.SetObjSource(pbtFilePath, pblFilePath, filepath.Base(objName), srcData)
-		if errSrc != nil {
-			errs = append(errs, errSrc)
-			// ignore trivial errors due to missing dependency resolving
-			if !strings.Contains(errSrc.Error(), "Compilation failed") {
-				fmt.Println("set source failed for", filepath.Base(objName), errSrc)
-				fmt.Println("data:", string(srcData))
-				if strings.Contains(errSrc.Error(), "connectex") { // server crashed
-					panic(errSrc)
-				}
-			}
-		}
-
-		var errSetBin error
-		if _, hasBin := binFiles[strings.TrimSuffix(filepath.Base(foundSrcFile), filepath.Ext(foundSrcFile))]; hasBin {
-			binFile := strings.TrimSuffix(foundSrcFile, filepath.Ext(foundSrcFile)) + ".bin"
-			binSection, errGetBin := GetBinarySectionFromBin(binFile)
-			if errGetBin != nil {
-				errs = append(errs, fmt.Errorf("failed to set OLE binary section to matching bin file %s: %v",
-					binFile, errors.Join(errGetBin, errSrc)))
-			}
-			errSetBin = orcaServer.SetObjBinary(pbtFilePath, pblFilePath, filepath.Base(objName), binSection)
-			if errSetBin != nil {
-				errs = append(errs, fmt.Errorf("failed to import binary data section in a second step %s: %v",
-					binFile, errors.Join(errSetBin, errSrc)))
-			}
+			return nil, err
 		}
+		objs = append(objs, &srcObject{
+			pblFilePath: pblFilePath,
+			srcFilePath: foundSrcFile,
+			binFilePath: binFiles[strings.TrimSuffix(foundSrcFile, filepath.Ext(foundSrcFile))],
+			srcData:     srcData,
+		})
 	}
-	return errs
+	return objs, nil
 }
 
 // sortSrcTypeName is used to import the source file not in arbitrary order or according to their name, but according to
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	pblFile := filepath.Join(dir, "inf1.pbl")
+	srcDir := filepath.Join(dir, "src", "inf1.pbl")
+	sources := map[string]string{
+		"u_base.sru":  "$PBExportHeader$u_base.sru\r\nglobal type u_base from nonvisualobject\r\nend type\r\n",
+		"u_child.sru": "$PBExportHeader$u_child.sru\r\nglobal type u_child from u_base\r\nend type\r\n",
+		"u_flaky.sru": "$PBExportHeader$u_flaky.sru\r\nglobal type u_flaky from nonvisualobject\r\nend type\r\n",
+		"u_bad.sru":   "$PBExportHeader$u_bad.sru\r\nglobal type u_bad from nonvisualobject\r\nend type\r\n",
+	}
+	if err := os.MkdirAll(srcDir, 0o775); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range sources {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(src), 0o664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake := backend.NewFake(map[string]map[string]string{pblFile: {}})
+	tries := make(map[string]int)
+	fake.Compile = func(pbl, name string, src []byte) error {
+		tries[name]++
+		switch {
+		case name == "u_child":
+			if _, ok := fake.Source(pbl, "u_base.sru"); !ok {
+				return errors.New("C0001: ancestor u_base not found")
+			}
+		case name == "u_flaky" && tries[name] == 1:
+			return errors.New("C0015: undefined variable: ll_dynamic")
+		case name == "u_bad":
+			return errors.New("C0031: syntax error")
+		}
+		return nil
+	}
+
+	var imported []string
+	err := Import(fake, filepath.Join(dir, "a3.pbt"), []string{srcDir}, []string{pblFile}, func(library, object string) {
+		imported = append(imported, object)
+	})
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("expected ImportError, got %v", err)
+	}
+	if len(importErr.Failures) != 1 || importErr.Failures[0].Object != "u_bad" || importErr.Failures[0].Library != "inf1.pbl" {
+		t.Errorf("unexpected failures %+v", importErr.Failures)
+	}
+	if len(imported) != 3 {
+		t.Errorf("expected 3 imported objects, got %v", imported)
+	}
+	for _, name := range []string{"u_base.sru", "u_child.sru", "u_flaky.sru"} {
+		if src, ok := fake.Source(pblFile, name); !ok || src != sources[name] {
+			t.Errorf("%s was not imported", name)
+		}
+	}
+	if tries["u_child"] != 1 {
+		t.Errorf("u_child must be imported after its ancestor, got %d tries", tries["u_child"])
+	}
+	// once in its component, then twice in the retry: with u_flaky and alone, until there is no more progress
+	if tries["u_bad"] != 3 {
+		t.Errorf("u_bad: got %d tries, want 3", tries["u_bad"])
+	}
+}
+
+func TestImportSources(t *testing.T) {
+	dir := t.TempDir()
+	inf1 := filepath.Join(dir, "inf1.pbl")
+	adr1 := filepath.Join(dir, "adr1.pbl")
+	// the descendant comes first and lives in another library than its ancestor
+	sources := []Source{
+		{Library: adr1, Entry: "u_child.sru", Src: []byte("$PBExportHeader$u_child.sru\r\nglobal type u_child from u_base\r\nend type\r\n")},
+		{Library: inf1, Entry: "u_base.sru", Src: []byte("$PBExportHeader$u_base.sru\r\nglobal type u_base from nonvisualobject\r\nend type\r\n")},
+		{Library: inf1, Entry: "u_bad.sru", Src: []byte("$PBExportHeader$u_bad.sru\r\nglobal type u_bad from nonvisualobject\r\nend type\r\n")},
+	}
+
+	fake := backend.NewFake(map[string]map[string]string{inf1: {}, adr1: {}})
+	tries := make(map[string]int)
+	fake.Compile = func(pbl, name string, src []byte) error {
+		tries[name]++
+		switch name {
+		case "u_child":
+			if _, ok := fake.Source(inf1, "u_base.sru"); !ok {
+				return errors.New("C0001: ancestor u_base not found")
+			}
+		case "u_bad":
+			return errors.New("C0031: syntax error")
+		}
+		return nil
+	}
+
+	var imported []string
+	err := ImportSources(fake, filepath.Join(dir, "a3.pbt"), sources, func(library, object string) {
+		imported = append(imported, object)
+	})
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("expected ImportError, got %v", err)
+	}
+	if len(importErr.Failures) != 1 || importErr.Failures[0].Object != "u_bad" || importErr.Failures[0].Library != "inf1.pbl" {
+		t.Errorf("unexpected failures %+v", importErr.Failures)
+	}
+	if len(imported) != 2 {
+		t.Errorf("expected 2 imported objects, got %v", imported)
+	}
+	if _, ok := fake.Source(adr1, "u_child.sru"); !ok {
+		t.Errorf("u_child.sru was not imported into adr1.pbl")
+	}
+	if tries["u_child"] != 1 {
+		t.Errorf("u_child must be imported after its ancestor, got %d tries", tries["u_child"])
+	}
+}
@@ -0,0 +1,168 @@
+// Package orcapool runs jobs concurrently on a limited number of ORCA sessions.
+//
+// ORCA sessions are expensive to start and can only be used by one goroutine at a time, so every worker of the
+// pool opens its own session and reuses it for all of its jobs. The pool is generic over the session type, the
+// commands use it with backend.Backend.
+package orcapool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultSize is the number of sessions used if no size is configured.
+const DefaultSize = 4
+
+// Pool runs jobs on up to Size sessions.
+type Pool[S any] struct {
+	open     func() (S, error)
+	close    func(S)
+	warnFunc func(string)
+	size     int
+	timeout  time.Duration
+}
+
+// New returns a pool with size sessions (DefaultSize if size < 1). open starts a session, close releases it.
+// If timeout is greater than zero, a job which runs longer is aborted with an error and its session is not
+// used anymore, the worker continues with a new session.
+func New[S any](size int, timeout time.Duration, open func() (S, error), close func(S)) *Pool[S] {
+	if size < 1 {
+		size = DefaultSize
+	}
+	return &Pool[S]{open: open, close: close, size: size, timeout: timeout}
+}
+
+// WithWarnFunc sets the function that gets the errors of sessions which could not be started while the jobs
+// still ran on the other sessions. Without it, these errors are dropped.
+func (p *Pool[S]) WithWarnFunc(warnFunc func(string)) *Pool[S] {
+	p.warnFunc = warnFunc
+	return p
+}
+
+// Size returns the maximum number of sessions.
+func (p *Pool[S]) Size() int {
+	return p.size
+}
+
+// Run calls fn for every job (e.g. the path of a library). The jobs are run concurrently, every call gets a
+// session of its own. All jobs are run even if some of them fail, the errors of all jobs are returned (see
+// errors.Join). If sessions can not be started, the jobs are run on the remaining sessions and the errors of the
+// session starts are passed to the warn function (see WithWarnFunc). They are only returned if jobs were left
+// unrun, e.g. because no session could be started at all.
+func (p *Pool[S]) Run(jobs []string, fn func(s S, job string) error) error {
+	queue := make(chan string, len(jobs))
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+
+	workers := p.size
+	if len(jobs) < workers {
+		workers = len(jobs)
+	}
+
+	var mu sync.Mutex
+	var errs, startErrs []error
+	addErr := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	addStartErr := func(err error) {
+		mu.Lock()
+		startErrs = append(startErrs, err)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(queue, fn, addErr, addStartErr)
+		}()
+	}
+	wg.Wait()
+
+	// jobs left in the queue were not run because no session could be started
+	left := len(queue)
+	if left > 0 {
+		errs = append(errs, startErrs...)
+		errs = append(errs, fmt.Errorf("%d of %d jobs were not run", left, len(jobs)))
+	} else if p.warnFunc != nil {
+		for _, err := range startErrs {
+			p.warnFunc(err.Error())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// work runs the jobs of the queue on one session until the queue is empty.
+func (p *Pool[S]) work(queue <-chan string, fn func(s S, job string) error, addErr, addStartErr func(error)) {
+	var session S
+	opened := false
+	defer func() {
+		if opened {
+			p.close(session)
+		}
+	}()
+
+	for {
+		if !opened {
+			var err error
+			session, err = p.open()
+			if err != nil {
+				// the jobs are left to the other workers
+				addStartErr(fmt.Errorf("could not start ORCA session: %v", err))
+				return
+			}
+			opened = true
+		}
+		job, ok := <-queue
+		if !ok {
+			return
+		}
+		err, timedOut := p.runJob(session, job, fn)
+		if timedOut {
+			// the session is still busy with the job, it is closed as soon as the job returns
+			opened = false
+		}
+		if err != nil {
+			addErr(fmt.Errorf("%s: %v", job, err))
+		}
+	}
+}
+
+// runJob runs a job with the timeout of the pool. If the job times out, the session is closed after the job
+// returned.
+func (p *Pool[S]) runJob(session S, job string, fn func(s S, job string) error) (err error, timedOut bool) {
+	if p.timeout <= 0 {
+		return runSafe(session, job, fn), false
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- runSafe(session, job, fn)
+	}()
+	select {
+	case err = <-done:
+		return err, false
+	case <-time.After(p.timeout):
+		go func() {
+			<-done
+			p.close(session)
+		}()
+		return fmt.Errorf("timed out after %v", p.timeout), true
+	}
+}
+
+// runSafe calls fn and turns a panic into an error, so one failing job does not stop the whole process.
+func runSafe[S any](session S, job string, fn func(s S, job string) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(session, job)
+}
@@ -0,0 +1,152 @@
+package orcapool
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type session struct {
+	id     int
+	closed bool
+}
+
+type sessions struct {
+	mu      sync.Mutex
+	all     []*session
+	failing int // number of failing starts
+}
+
+func (s *sessions) open() (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing > 0 {
+		s.failing--
+		return nil, errors.New("no license")
+	}
+	ses := &session{id: len(s.all)}
+	s.all = append(s.all, ses)
+	return ses, nil
+}
+
+func (s *sessions) close(ses *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses.closed = true
+}
+
+func (s *sessions) allClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ses := range s.all {
+		if !ses.closed {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRun(t *testing.T) {
+	jobs := []string{"a.pbl", "b.pbl", "c.pbl", "d.pbl", "e.pbl", "f.pbl", "g.pbl"}
+	tests := []struct {
+		name       string
+		size       int
+		failing    int
+		wantErrs   []string
+		wantWarns  []string
+		wantRun    int
+		maxSession int
+	}{
+		{name: "all", size: 3, wantRun: 7, maxSession: 3},
+		{name: "more sessions than jobs", size: 20, wantRun: 7, maxSession: 7},
+		{name: "one session fails", size: 3, failing: 1, wantRun: 7, maxSession: 2, wantWarns: []string{"could not start ORCA session: no license"}},
+		{name: "no session", size: 2, failing: 2, wantRun: 0, wantErrs: []string{"no license", "7 of 7 jobs were not run"}},
+	}
+	for _, tt := range tests {
+		s := &sessions{failing: tt.failing}
+		var running, maxRunning, run int32
+		var warns []string
+		pool := New(tt.size, 0, s.open, s.close).WithWarnFunc(func(msg string) { warns = append(warns, msg) })
+		err := pool.Run(jobs, func(ses *session, job string) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&run, 1)
+			return nil
+		})
+		if int(run) != tt.wantRun {
+			t.Errorf("%s: %d jobs run, want %d", tt.name, run, tt.wantRun)
+		}
+		if int(maxRunning) > tt.maxSession || len(s.all) > tt.maxSession {
+			t.Errorf("%s: %d concurrent jobs on %d sessions, want at most %d", tt.name, maxRunning, len(s.all), tt.maxSession)
+		}
+		if !s.allClosed() {
+			t.Errorf("%s: not all sessions closed", tt.name)
+		}
+		for _, want := range tt.wantErrs {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, want)
+			}
+		}
+		if len(tt.wantErrs) == 0 && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if strings.Join(warns, "\n") != strings.Join(tt.wantWarns, "\n") {
+			t.Errorf("%s: got warnings %q, want %q", tt.name, warns, tt.wantWarns)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	s := &sessions{}
+	err := New(2, 0, s.open, s.close).Run([]string{"a.pbl", "b.pbl", "c.pbl"}, func(ses *session, job string) error {
+		switch job {
+		case "a.pbl":
+			return errors.New("locked")
+		case "b.pbl":
+			panic("nil pointer")
+		}
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "a.pbl: locked") || !strings.Contains(err.Error(), "b.pbl: panic: nil pointer") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	s := &sessions{}
+	release := make(chan bool)
+	var ran []int
+	var mu sync.Mutex
+	err := New(1, 20*time.Millisecond, s.open, s.close).Run([]string{"slow.pbl", "fast.pbl"}, func(ses *session, job string) error {
+		mu.Lock()
+		ran = append(ran, ses.id)
+		mu.Unlock()
+		if job == "slow.pbl" {
+			<-release
+		}
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "slow.pbl: timed out") {
+		t.Errorf("got error %v, want timeout", err)
+	}
+	if len(ran) != 2 || ran[0] == ran[1] {
+		t.Errorf("job after a timeout must run on a new session, got sessions %v", ran)
+	}
+	close(release)
+	for i := 0; i < 100 && !s.allClosed(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if !s.allClosed() {
+		t.Errorf("session of the timed out job was not closed")
+	}
+}
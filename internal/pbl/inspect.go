@@ -0,0 +1,126 @@
+package pbl
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Info summarizes the format and the content of a library.
+type Info struct {
+	Path              string         `json:"path"`
+	Unicode           bool           `json:"unicode"`
+	FormatVersion     string         `json:"formatVersion"`
+	PbRelease         string         `json:"pbRelease"` // best guess, e.g. "22" or "" if unknown
+	Comment           string         `json:"comment"`
+	Modified          time.Time      `json:"modified"`
+	Blocks            int            `json:"blocks"`
+	FreeBlocks        int            `json:"freeBlocks"`
+	FragmentedEntries int            `json:"fragmentedEntries"`
+	TypeCounts        map[string]int `json:"typeCounts"`
+	Objects           []ObjectInfo   `json:"objects"`
+}
+
+// ObjectInfo combines the source and the compiled entry of an object.
+type ObjectInfo struct {
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	Comment          string     `json:"comment"`
+	SourceSize       uint32     `json:"sourceSize"`
+	SourceModified   *time.Time `json:"sourceModified,omitempty"`
+	CompiledSize     uint32     `json:"compiledSize"`
+	CompiledModified *time.Time `json:"compiledModified,omitempty"`
+}
+
+var (
+	regexRelease    = regexp.MustCompile(`(?m)^release (\d+)(\.\d+)?;`)
+	regexAppRuntime = regexp.MustCompile(`(?im)appruntimeversion\s*=\s*"(\d+)\.`)
+)
+
+// Inspect collects the Info of the library.
+func (l *Library) Inspect() *Info {
+	info := &Info{
+		Path:          l.Path,
+		Unicode:       l.Unicode,
+		FormatVersion: l.FormatVersion,
+		PbRelease:     l.PbRelease(),
+		Comment:       l.Comment,
+		Modified:      l.Modified,
+		Blocks:        l.Blocks,
+		FreeBlocks:    l.FreeBlocks,
+		TypeCounts:    make(map[string]int),
+	}
+
+	objects := make(map[string]*ObjectInfo)
+	for _, e := range l.Entries {
+		if e.Fragments > 1 {
+			info.FragmentedEntries++
+		}
+		if e.ObjType() == "other" {
+			continue
+		}
+		key := strings.ToLower(e.ObjName()) + "/" + e.ObjType()
+		obj, ok := objects[key]
+		if !ok {
+			obj = &ObjectInfo{Name: e.ObjName(), Type: e.ObjType()}
+			objects[key] = obj
+			info.TypeCounts[obj.Type]++
+		}
+		modified := e.Modified
+		if e.IsSource() {
+			obj.SourceSize = e.Size - uint32(e.commentBytes)
+			obj.SourceModified = &modified
+			obj.Comment = e.Comment
+		} else {
+			obj.CompiledSize = e.Size - uint32(e.commentBytes)
+			obj.CompiledModified = &modified
+			if obj.Comment == "" {
+				obj.Comment = e.Comment
+			}
+		}
+	}
+	for _, obj := range objects {
+		info.Objects = append(info.Objects, *obj)
+	}
+	sort.Slice(info.Objects, func(i, j int) bool {
+		if info.Objects[i].Type != info.Objects[j].Type {
+			return info.Objects[i].Type < info.Objects[j].Type
+		}
+		return strings.ToLower(info.Objects[i].Name) < strings.ToLower(info.Objects[j].Name)
+	})
+	return info
+}
+
+// PbRelease guesses the PowerBuilder release which saved the library (e.g. "22").
+// DataWindow sources contain a 'release N;' line, the application object contains the appruntimeversion.
+// If no release can be determined, an empty string is returned.
+func (l *Library) PbRelease() string {
+	counts := make(map[string]int)
+	for _, e := range l.SourceEntries() {
+		var regex *regexp.Regexp
+		switch strings.ToLower(filepath.Ext(e.Name)) {
+		case ".srd":
+			regex = regexRelease
+		case ".sra":
+			regex = regexAppRuntime
+		default:
+			continue
+		}
+		data, err := l.ReadData(e)
+		if err != nil {
+			continue
+		}
+		if m := regex.FindStringSubmatch(l.decodeString(data)); m != nil {
+			counts[m[1]]++
+		}
+	}
+	release := ""
+	for r, n := range counts {
+		if n > counts[release] || (n == counts[release] && r > release) {
+			release = r
+		}
+	}
+	return release
+}
@@ -0,0 +1,155 @@
+// Package pbl reads PowerBuilder library files (.pbl) directly, without the need of ORCA.
+//
+// A library consists of blocks of 512 bytes. The header occupies one (ANSI) or two (Unicode) blocks and is followed
+// by the free-block bitmap and the root node of the entry directory:
+//
+// +--------------------------------------------------------------+
+// I Library Header Block (512 Byte ANSI, 1024 Byte Unicode)      I
+// +-----------+------------+-------------------------------------+
+// I Pos.      I Type       I Information                         I
+// +-----------+------------+-------------------------------------+
+// I   1 - 4   I Char(4)    I 'HDR*'                              I
+// I   5 - 18  I Char(14)   I 'PowerBuilder' + 0x00 + 0x00        I
+// I  19 - 22  I Char(4)    I PBL Format Version (0400/0500/0600) I
+// I  23 - 26  I Long       I Creation/Optimization Datetime      I
+// I  27 - 28  I Integer    I ?                                   I
+// I  29 - 284 I Char(256)  I Library Comment                     I
+// I 285 - 288 I Long       I Offset of first SCC data block      I
+// I 289 - 292 I Long       I Size (Net size of SCC data)         I
+// +-----------+------------+-------------------------------------+
+// In Unicode libraries all Char fields are UTF-16LE and therefore twice as long.
+//
+// +--------------------------------------------------------------+
+// I Bitmap Block (512 Byte)                                      I
+// +-----------+------------+-------------------------------------+
+// I   1 - 4   I Char(4)    I 'FRE*'                              I
+// I   5 - 8   I Long       I Offset of next block or 0           I
+// I   9 - 512 I Bit(504*8) I Utilization (one bit for each block)I
+// +-----------+------------+-------------------------------------+
+//
+// +--------------------------------------------------------------+
+// I Node Block (3072 Byte)                                       I
+// +-----------+------------+-------------------------------------+
+// I   1 - 4   I Char(4)    I 'NOD*'                              I
+// I   5 - 8   I Long       I Offset of next (left) block or 0    I
+// I   9 - 12  I Long       I Offset of parent block or 0         I
+// I  13 - 16  I Long       I Offset of next (right) block or 0   I
+// I  17 - 18  I Integer    I Space left in block, initial = 3040 I
+// I  19 - 20  I Integer    I Position of alphabetically first    I
+// I           I            I Objectname in this block            I
+// I  21 - 22  I Integer    I Count of entries in that node       I
+// I  23 - 24  I Integer    I Position of alphabetically last     I
+// I           I            I Objectname in this block            I
+// I  25 - 32  I Char(8)    I ?                                   I
+// I  33 - 3072I Chunks     I 'ENT*'-Chunks                       I
+// +-----------+------------+-------------------------------------+
+//
+// +--------------------------------------------------------------+
+// I Entry Chunk (Variable Length)                                I
+// +-----------+------------+-------------------------------------+
+// I   1 - 4   I Char(4)    I 'ENT*'                              I
+// I   5 - 8   I Char(4)    I PBL Format Version (0400/0500/0600) I
+// I   9 - 12  I Long       I Offset of first data block          I
+// I  13 - 16  I Long       I Objectsize (Net size of data)       I
+// I  17 - 20  I Long       I Unix datetime                       I
+// I  21 - 22  I Integer    I Length of Comment (characters)      I
+// I  23 - 24  I Integer    I Length of Objectname (bytes)        I
+// I  25 - XXX I String     I Objectname (null terminated)        I
+// +-----------+------------+-------------------------------------+
+//
+// The data of an entry is stored in a chain of DAT* blocks (see importer.sortBinBytesHex). The comment of the entry
+// precedes the actual object data within that chain.
+package pbl
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	blockSize     = 512
+	nodeSize      = 3072
+	nodeHeaderLen = 32
+	dataHeaderLen = 10
+	dataLen       = blockSize - dataHeaderLen // 502
+	bitmapLen     = blockSize - 8             // 504
+	commentLen    = 256
+)
+
+var (
+	markerHeader = []byte("HDR*")
+	markerBitmap = []byte("FRE*")
+	markerNode   = []byte("NOD*")
+	markerEntry  = []byte("ENT*")
+	markerData   = []byte("DAT*")
+)
+
+// Library is the parsed representation of a pbl file.
+type Library struct {
+	Path          string
+	Unicode       bool      // true for libraries of PB10 and newer
+	FormatVersion string    // e.g. "0600"
+	Modified      time.Time // creation or last optimization
+	Comment       string
+	SccOffset     uint32
+	SccSize       uint32
+	Entries       []*Entry // sorted by name
+	Blocks        int      // number of 512 byte blocks in the file
+	FreeBlocks    int      // number of blocks marked as unused in the bitmap
+
+	data []byte
+}
+
+// Entry is a single directory entry of a library, e.g. w_main.srw (source) or w_main.win (compiled).
+type Entry struct {
+	Name          string // file name of the entry, e.g. w_main.srw
+	FormatVersion string
+	Offset        uint32 // offset of the first data block
+	Size          uint32 // size of the data including the comment
+	Modified      time.Time
+	Comment       string
+	DataBlocks    int // number of DAT* blocks used by the entry
+	Fragments     int // number of non-contiguous parts of the DAT* chain
+
+	commentBytes int
+	raw          []byte // data including comment, only set for entries to write
+}
+
+// objTypes maps the extension of an entry to the type of the object.
+var objTypes = map[string]string{
+	".sra": "application", ".apl": "application",
+	".srd": "datawindow", ".dwo": "datawindow",
+	".srf": "function", ".fun": "function",
+	".srm": "menu", ".men": "menu",
+	".srq": "query", ".qry": "query",
+	".srs": "structure", ".str": "structure",
+	".sru": "userobject", ".udo": "userobject",
+	".srw": "window", ".win": "window",
+	".srp": "pipeline", ".pip": "pipeline",
+	".srj": "project", ".prj": "project",
+	".srx": "proxyobject", ".prx": "proxyobject",
+}
+
+// ObjName returns the name of the object without extension, e.g. w_main
+func (e *Entry) ObjName() string {
+	return strings.TrimSuffix(e.Name, filepath.Ext(e.Name))
+}
+
+// ObjType returns the type of the object, e.g. window. Unknown (internal) entries return "other".
+func (e *Entry) ObjType() string {
+	if t, ok := objTypes[strings.ToLower(filepath.Ext(e.Name))]; ok {
+		return t
+	}
+	return "other"
+}
+
+// IsSource returns true if the entry contains PowerScript source (.sr* files).
+func (e *Entry) IsSource() bool {
+	return strings.HasPrefix(strings.ToLower(filepath.Ext(e.Name)), ".sr")
+}
+
+// IsCompiled returns true if the entry contains a compiled object (e.g. .win, .udo).
+func (e *Entry) IsCompiled() bool {
+	return !e.IsSource() && e.ObjType() != "other"
+}
@@ -0,0 +1,114 @@
+package pbl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	lib, err := Open("testdata/manage_files.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lib.Unicode || lib.FormatVersion != "0600" {
+		t.Errorf("wrong format: unicode=%v, version=%s", lib.Unicode, lib.FormatVersion)
+	}
+	var names []string
+	for _, e := range lib.Entries {
+		names = append(names, e.Name)
+	}
+	want := "manage_files.apl manage_files.pra manage_files.prp manage_files.sra"
+	if strings.Join(names, " ") != want {
+		t.Errorf("wrong entries, expected %s, got %s", want, names)
+	}
+
+	e, ok := lib.Entry("manage_files")
+	if !ok {
+		t.Fatal("source entry manage_files not found")
+	}
+	if e.Name != "manage_files.sra" || e.ObjType() != "application" || !e.IsSource() {
+		t.Errorf("wrong source entry: %s (%s)", e.Name, e.ObjType())
+	}
+	if e.Comment != "Generated Application Object" {
+		t.Errorf("wrong comment: %q", e.Comment)
+	}
+	if e.DataBlocks != 6 {
+		t.Errorf("expected 6 data blocks, got %d", e.DataBlocks)
+	}
+}
+
+func TestSource(t *testing.T) {
+	lib, err := Open("testdata/manage_files.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := lib.Source("manage_files.sra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "$PBExportHeader$manage_files.sra\r\n$PBExportComments$Generated Application Object\r\nforward\r\nglobal type manage_files from application\r\n"
+	if !strings.HasPrefix(src, prefix) {
+		t.Errorf("source does not start with %q: %q", prefix, src[:len(prefix)])
+	}
+	if !strings.Contains(src, "sqlca=create transaction") {
+		t.Errorf("source is incomplete")
+	}
+
+	_, err = lib.Source("manage_files.apl")
+	if err == nil {
+		t.Errorf("expected error for compiled entry")
+	}
+	_, err = lib.Source("does_not_exist")
+	if err == nil {
+		t.Errorf("expected error for missing entry")
+	}
+}
+
+func TestOpenEmpty(t *testing.T) {
+	lib, err := Open("testdata/empty.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(lib.Entries))
+	}
+	if lib.Blocks != 9 || lib.FreeBlocks != 0 {
+		t.Errorf("expected 9 blocks, 0 free, got %d, %d", lib.Blocks, lib.FreeBlocks)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte("no pbl"))
+	if err == nil {
+		t.Errorf("expected error for invalid data")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	lib, err := Open("testdata/manage_files.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := lib.Inspect()
+	if info.PbRelease != "22" {
+		t.Errorf("expected release 22, got %q", info.PbRelease)
+	}
+	if len(info.Objects) != 1 || info.TypeCounts["application"] != 1 {
+		t.Fatalf("expected one application object, got %v", info.TypeCounts)
+	}
+	obj := info.Objects[0]
+	if obj.Name != "manage_files" || obj.SourceModified == nil || obj.CompiledModified == nil {
+		t.Errorf("source and compiled entry were not combined: %+v", obj)
+	}
+	if obj.SourceSize == 0 || obj.CompiledSize == 0 {
+		t.Errorf("missing sizes: %+v", obj)
+	}
+
+	empty, err := Open("testdata/empty.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := empty.PbRelease(); r != "" {
+		t.Errorf("expected unknown release for empty library, got %q", r)
+	}
+}
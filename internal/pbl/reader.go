@@ -0,0 +1,312 @@
+package pbl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Open reads and parses the library at path.
+func Open(path string) (*Library, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lib, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse library %s: %v", path, err)
+	}
+	lib.Path = path
+	return lib, nil
+}
+
+// Parse parses the content of a pbl file.
+func Parse(data []byte) (*Library, error) {
+	l := &Library{data: data, Blocks: len(data) / blockSize}
+	if len(data) < 2*blockSize || !bytes.Equal(data[:4], markerHeader) {
+		return nil, fmt.Errorf("no HDR* block found, not a PowerBuilder library")
+	}
+	// In Unicode libraries, 'PowerBuilder' is stored as UTF-16LE, i.e. the second char is a zero byte.
+	l.Unicode = data[5] == 0
+
+	err := l.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	l.FreeBlocks, err = l.countFreeBlocks()
+	if err != nil {
+		return nil, err
+	}
+	err = l.readNodes(l.rootNodeOffset(), make(map[uint32]bool))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(l.Entries, func(i, j int) bool {
+		return strings.ToLower(l.Entries[i].Name) < strings.ToLower(l.Entries[j].Name)
+	})
+	return l, nil
+}
+
+func (l *Library) headerBlocks() int {
+	if l.Unicode {
+		return 2
+	}
+	return 1
+}
+
+func (l *Library) rootNodeOffset() uint32 {
+	return uint32((l.headerBlocks() + 1) * blockSize)
+}
+
+// charSize is the number of bytes used per character in strings.
+func (l *Library) charSize() int {
+	if l.Unicode {
+		return 2
+	}
+	return 1
+}
+
+func (l *Library) readHeader() error {
+	c := l.charSize()
+	pos := 4 + 14*c
+	l.FormatVersion = l.decodeString(l.data[pos : pos+4*c])
+	pos += 4 * c
+	l.Modified = time.Unix(int64(binary.LittleEndian.Uint32(l.data[pos:])), 0)
+	pos += 4 + 2
+	l.Comment = strings.TrimRight(l.decodeString(l.data[pos:pos+commentLen*c]), "\x00")
+	pos += commentLen * c
+	l.SccOffset = binary.LittleEndian.Uint32(l.data[pos:])
+	l.SccSize = binary.LittleEndian.Uint32(l.data[pos+4:])
+	return nil
+}
+
+// countFreeBlocks walks through the chain of FRE* blocks and counts the unused blocks.
+func (l *Library) countFreeBlocks() (int, error) {
+	used := 0
+	offset := uint32(l.headerBlocks() * blockSize)
+	for i := 0; offset != 0; i++ {
+		block, err := l.block(offset, markerBitmap, blockSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, b := range block[8:] {
+			used += bits.OnesCount8(b)
+		}
+		offset = binary.LittleEndian.Uint32(block[4:])
+		if i > l.Blocks {
+			return 0, fmt.Errorf("FRE* chain is cyclic")
+		}
+	}
+	if used > l.Blocks {
+		return 0, nil
+	}
+	return l.Blocks - used, nil
+}
+
+// readNodes reads the entries of the node at offset and all its child nodes.
+func (l *Library) readNodes(offset uint32, visited map[uint32]bool) error {
+	if offset == 0 || visited[offset] {
+		return nil
+	}
+	visited[offset] = true
+	node, err := l.block(offset, markerNode, nodeSize)
+	if err != nil {
+		return err
+	}
+	left := binary.LittleEndian.Uint32(node[4:])
+	right := binary.LittleEndian.Uint32(node[12:])
+	count := int(binary.LittleEndian.Uint16(node[20:]))
+
+	pos := nodeHeaderLen
+	for range count {
+		entry, size, err := l.readEntry(node[pos:])
+		if err != nil {
+			return fmt.Errorf("node at 0x%x: %v", offset, err)
+		}
+		l.Entries = append(l.Entries, entry)
+		pos += size
+	}
+
+	err = l.readNodes(left, visited)
+	if err != nil {
+		return err
+	}
+	return l.readNodes(right, visited)
+}
+
+// readEntry parses an ENT* chunk and returns the entry and the size of the chunk.
+func (l *Library) readEntry(chunk []byte) (*Entry, int, error) {
+	c := l.charSize()
+	if len(chunk) < 4+4*c+16 || !bytes.Equal(chunk[:4], markerEntry) {
+		return nil, 0, fmt.Errorf("no ENT* chunk found")
+	}
+	e := &Entry{FormatVersion: l.decodeString(chunk[4 : 4+4*c])}
+	pos := 4 + 4*c
+	e.Offset = binary.LittleEndian.Uint32(chunk[pos:])
+	e.Size = binary.LittleEndian.Uint32(chunk[pos+4:])
+	e.Modified = time.Unix(int64(binary.LittleEndian.Uint32(chunk[pos+8:])), 0)
+	e.commentBytes = int(binary.LittleEndian.Uint16(chunk[pos+12:])) * c
+	nameLen := int(binary.LittleEndian.Uint16(chunk[pos+14:]))
+	pos += 16
+	if pos+nameLen > len(chunk) {
+		return nil, 0, fmt.Errorf("entry name exceeds node")
+	}
+	e.Name = strings.TrimRight(l.decodeString(chunk[pos:pos+nameLen]), "\x00")
+
+	raw, blocks, err := l.readChain(e.Offset, e.Size)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not read data of %s: %v", e.Name, err)
+	}
+	e.DataBlocks = len(blocks)
+	e.Fragments = 1
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i] != blocks[i-1]+blockSize {
+			e.Fragments++
+		}
+	}
+	if e.commentBytes > len(raw) {
+		return nil, 0, fmt.Errorf("comment of %s exceeds data", e.Name)
+	}
+	e.Comment = l.decodeString(raw[:e.commentBytes])
+	return e, pos + nameLen, nil
+}
+
+// readChain reads size bytes from the chain of DAT* blocks starting at offset.
+// It also returns the offsets of the blocks of the chain.
+func (l *Library) readChain(offset uint32, size uint32) ([]byte, []uint32, error) {
+	data := make([]byte, 0, size)
+	var blocks []uint32
+	for offset != 0 && uint32(len(data)) < size {
+		block, err := l.block(offset, markerData, blockSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.LittleEndian.Uint16(block[8:]))
+		if n > dataLen {
+			return nil, nil, fmt.Errorf("invalid length %d of DAT* block at 0x%x", n, offset)
+		}
+		data = append(data, block[dataHeaderLen:dataHeaderLen+n]...)
+		blocks = append(blocks, offset)
+		offset = binary.LittleEndian.Uint32(block[4:])
+		if len(blocks) > l.Blocks {
+			return nil, nil, fmt.Errorf("DAT* chain is cyclic")
+		}
+	}
+	if uint32(len(data)) < size {
+		return nil, nil, fmt.Errorf("DAT* chain ended after %d of %d bytes", len(data), size)
+	}
+	return data[:size], blocks, nil
+}
+
+// block returns the block at offset after checking its marker.
+func (l *Library) block(offset uint32, marker []byte, size int) ([]byte, error) {
+	if int(offset)+size > len(l.data) {
+		return nil, fmt.Errorf("block %s at 0x%x exceeds file size", marker, offset)
+	}
+	block := l.data[offset : int(offset)+size]
+	if !bytes.Equal(block[:4], marker) {
+		return nil, fmt.Errorf("expected %s block at 0x%x, found %q", marker, offset, block[:4])
+	}
+	return block, nil
+}
+
+// decodeString converts a string of the library (UTF-16LE or Windows-1252) to UTF-8.
+func (l *Library) decodeString(b []byte) string {
+	if !l.Unicode {
+		s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+		if err != nil {
+			return string(b)
+		}
+		return string(s)
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// Entry returns the entry with the given name (e.g. w_main.srw). If name has no extension, the source entry of the
+// object is returned. Names are compared case-insensitive as PowerBuilder does.
+func (l *Library) Entry(name string) (*Entry, bool) {
+	hasExt := filepath.Ext(name) != ""
+	for _, e := range l.Entries {
+		if hasExt && strings.EqualFold(e.Name, name) {
+			return e, true
+		}
+		if !hasExt && e.IsSource() && strings.EqualFold(e.ObjName(), name) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// ReadSources returns the sources of all objects of the library file, keyed by the entry name (e.g. w_main.srw).
+func ReadSources(path string) (map[string]string, error) {
+	lib, err := Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	sources := make(map[string]string)
+	for _, entry := range lib.SourceEntries() {
+		src, err := lib.Source(entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s from %s: %v", entry.Name, path, err)
+		}
+		sources[entry.Name] = src
+	}
+	return sources, nil
+}
+
+// SourceEntries returns all entries containing PowerScript source.
+func (l *Library) SourceEntries() []*Entry {
+	var entries []*Entry
+	for _, e := range l.Entries {
+		if e.IsSource() {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// ReadData returns the raw data of the entry without the comment.
+func (l *Library) ReadData(e *Entry) ([]byte, error) {
+	raw, _, err := l.readChain(e.Offset, e.Size)
+	if err != nil {
+		return nil, fmt.Errorf("could not read data of %s: %v", e.Name, err)
+	}
+	return raw[e.commentBytes:], nil
+}
+
+// Source returns the source of an object in the same format as ORCA exports it, i.e. starting with
+// $PBExportHeader$ and $PBExportComments$ (if there is a comment).
+func (l *Library) Source(name string) (string, error) {
+	e, ok := l.Entry(name)
+	if !ok {
+		return "", fmt.Errorf("object %s not found in %s", name, filepath.Base(l.Path))
+	}
+	if !e.IsSource() {
+		return "", fmt.Errorf("entry %s does not contain source", e.Name)
+	}
+	data, err := l.ReadData(e)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("$PBExportHeader$" + e.Name + "\r\n")
+	if e.Comment != "" {
+		sb.WriteString("$PBExportComments$" + e.Comment + "\r\n")
+	}
+	// some entries are terminated with a null character, ORCA does not export it
+	sb.WriteString(strings.TrimRight(l.decodeString(data), "\x00"))
+	return sb.String(), nil
+}
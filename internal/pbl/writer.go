@@ -0,0 +1,364 @@
+package pbl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	unicodeFormatVersion = "0600"
+	nodeCapacity         = nodeSize - nodeHeaderLen // 3040
+	blocksPerBitmap      = bitmapLen * 8            // 4032
+	blocksPerNode        = nodeSize / blockSize     // 6
+)
+
+// New returns an empty Unicode library. The library is only kept in memory until Save or WriteFile is called.
+func New(comment string) *Library {
+	return &Library{
+		Unicode:       true,
+		FormatVersion: unicodeFormatVersion,
+		Modified:      time.Now(),
+		Comment:       comment,
+	}
+}
+
+// Create creates an empty Unicode library at path.
+func Create(path string, comment string) (*Library, error) {
+	l := New(comment)
+	err := l.WriteFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetSource adds or replaces the source entry name (e.g. w_main.srw) with src.
+// src may be in the format exported by ORCA: The $PBExportHeader$ line is removed and a $PBExportComments$ line
+// becomes the comment of the entry. If name has no extension, the name from the $PBExportHeader$ line is used.
+// The object is not compiled, a compiled entry of the same object stays untouched.
+func (l *Library) SetSource(name string, src string) error {
+	if !l.Unicode {
+		return fmt.Errorf("writing to ANSI libraries is not supported")
+	}
+	src = strings.TrimPrefix(src, "\uFEFF")
+	comment := ""
+	if rest, ok := strings.CutPrefix(src, "$PBExportHeader$"); ok {
+		header, body, _ := strings.Cut(rest, "\n")
+		header = strings.TrimSpace(header)
+		if filepath.Ext(name) == "" && header != "" {
+			name = header
+		}
+		src = body
+	}
+	if rest, ok := strings.CutPrefix(src, "$PBExportComments$"); ok {
+		line, body, _ := strings.Cut(rest, "\n")
+		comment = strings.TrimRight(line, "\r")
+		src = body
+	}
+	if !strings.HasPrefix(strings.ToLower(filepath.Ext(name)), ".sr") {
+		return fmt.Errorf("%s is not a source entry name (.sr*)", name)
+	}
+
+	commentData := encodeUTF16(comment)
+	e := &Entry{
+		Name:          name,
+		FormatVersion: l.FormatVersion,
+		Size:          uint32(len(commentData) + 2*len(utf16.Encode([]rune(src)))),
+		Modified:      time.Now(),
+		Comment:       comment,
+		commentBytes:  len(commentData),
+		raw:           append(commentData, encodeUTF16(src)...),
+	}
+	for i, old := range l.Entries {
+		if strings.EqualFold(old.Name, name) {
+			e.Name = old.Name
+			l.Entries[i] = e
+			return nil
+		}
+	}
+	l.Entries = append(l.Entries, e)
+	sort.Slice(l.Entries, func(i, j int) bool {
+		return strings.ToLower(l.Entries[i].Name) < strings.ToLower(l.Entries[j].Name)
+	})
+	return nil
+}
+
+// Delete removes the entry name (e.g. w_main.win) from the library. If name has no extension, all entries of the
+// object (source and compiled) are removed.
+func (l *Library) Delete(name string) error {
+	hasExt := filepath.Ext(name) != ""
+	var kept []*Entry
+	for _, e := range l.Entries {
+		if (hasExt && strings.EqualFold(e.Name, name)) || (!hasExt && strings.EqualFold(e.ObjName(), name)) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if len(kept) == len(l.Entries) {
+		return fmt.Errorf("object %s not found in %s", name, filepath.Base(l.Path))
+	}
+	l.Entries = kept
+	return nil
+}
+
+// Save writes the library back to the file it was read from.
+func (l *Library) Save() error {
+	if l.Path == "" {
+		return fmt.Errorf("library has no path, use WriteFile")
+	}
+	return l.WriteFile(l.Path)
+}
+
+// WriteFile writes the library to path. The library is written compacted, i.e. without unused blocks.
+func (l *Library) WriteFile(path string) error {
+	data, err := l.Marshal()
+	if err != nil {
+		return err
+	}
+	tmpFile := path + ".tmp"
+	err = os.WriteFile(tmpFile, data, 0o664)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpFile, path)
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	newLib, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("written library %s is invalid: %v", path, err)
+	}
+	*l = *newLib
+	l.Path = path
+	return nil
+}
+
+// Marshal returns the library in the pbl file format. All entries are packed into a tree of nodes, followed by their
+// data blocks. The bitmap marks all blocks as used.
+func (l *Library) Marshal() ([]byte, error) {
+	if !l.Unicode {
+		return nil, fmt.Errorf("writing ANSI libraries is not supported")
+	}
+	for _, e := range l.Entries {
+		if e.raw != nil {
+			continue
+		}
+		raw, _, err := l.readChain(e.Offset, e.Size)
+		if err != nil {
+			return nil, fmt.Errorf("could not read data of %s: %v", e.Name, err)
+		}
+		e.raw = raw
+	}
+	var scc []byte
+	if l.SccOffset != 0 && l.SccSize > 0 {
+		var err error
+		scc, _, err = l.readChain(l.SccOffset, l.SccSize)
+		if err != nil {
+			return nil, fmt.Errorf("could not read SCC data: %v", err)
+		}
+	}
+
+	nodes, err := packNodes(l.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	// block layout: header (2), bitmap (1), nodes (6 each), data, additional bitmaps
+	next := 3
+	nodeOffsets := make([]uint32, len(nodes))
+	for i := range nodes {
+		nodeOffsets[i] = uint32(next * blockSize)
+		next += blocksPerNode
+	}
+	entryOffsets := make([]uint32, len(l.Entries))
+	for i, e := range l.Entries {
+		entryOffsets[i] = uint32(next * blockSize)
+		next += chainBlocks(len(e.raw))
+	}
+	var sccOffset uint32
+	if scc != nil {
+		sccOffset = uint32(next * blockSize)
+		next += chainBlocks(len(scc))
+	}
+	extraBitmaps := 0
+	for (next+extraBitmaps+blocksPerBitmap-1)/blocksPerBitmap > 1+extraBitmaps {
+		extraBitmaps++
+	}
+	bitmapOffsets := []uint32{uint32(2 * blockSize)}
+	for range extraBitmaps {
+		bitmapOffsets = append(bitmapOffsets, uint32(next*blockSize))
+		next++
+	}
+
+	data := make([]byte, next*blockSize)
+	l.writeHeader(data, sccOffset, uint32(len(scc)))
+	for i, offset := range bitmapOffsets {
+		var nextBitmap uint32
+		if i+1 < len(bitmapOffsets) {
+			nextBitmap = bitmapOffsets[i+1]
+		}
+		writeBitmap(data[offset:offset+blockSize], nextBitmap, i*blocksPerBitmap, next)
+	}
+	entryIndex := make(map[*Entry]int)
+	for i, e := range l.Entries {
+		entryIndex[e] = i
+		writeChain(data, entryOffsets[i], e.raw)
+	}
+	if scc != nil {
+		writeChain(data, sccOffset, scc)
+	}
+
+	root := buildTree(0, len(nodes)-1)
+	var writeNode func(t *nodeTree, parent uint32) uint32
+	writeNode = func(t *nodeTree, parent uint32) uint32 {
+		if t == nil {
+			return 0
+		}
+		offset := nodeOffsets[t.index]
+		left := writeNode(t.left, offset)
+		right := writeNode(t.right, offset)
+		block := data[offset : offset+nodeSize]
+		copy(block, markerNode)
+		binary.LittleEndian.PutUint32(block[4:], left)
+		binary.LittleEndian.PutUint32(block[8:], parent)
+		binary.LittleEndian.PutUint32(block[12:], right)
+		pos, last := nodeHeaderLen, 0
+		for _, e := range nodes[t.index] {
+			last = pos - nodeHeaderLen
+			pos += writeEntry(block[pos:], e, entryOffsets[entryIndex[e]])
+		}
+		binary.LittleEndian.PutUint16(block[16:], uint16(nodeSize-pos))
+		binary.LittleEndian.PutUint16(block[18:], 0)
+		binary.LittleEndian.PutUint16(block[20:], uint16(len(nodes[t.index])))
+		binary.LittleEndian.PutUint16(block[22:], uint16(last))
+		return offset
+	}
+	// the root node is always the node directly after the first bitmap block
+	nodeOffsets[0], nodeOffsets[root.index] = nodeOffsets[root.index], nodeOffsets[0]
+	writeNode(root, 0)
+	return data, nil
+}
+
+func (l *Library) writeHeader(data []byte, sccOffset, sccSize uint32) {
+	pos := copy(data, markerHeader)
+	pos += copy(data[pos:], encodeUTF16("PowerBuilder\x00\x00"))
+	pos += copy(data[pos:], encodeUTF16(l.FormatVersion))
+	binary.LittleEndian.PutUint32(data[pos:], uint32(l.Modified.Unix()))
+	binary.LittleEndian.PutUint16(data[pos+4:], 1)
+	pos += 6
+	comment := encodeUTF16(l.Comment)
+	if len(comment) > 2*(commentLen-1) {
+		comment = comment[:2*(commentLen-1)]
+	}
+	copy(data[pos:], comment)
+	pos += 2 * commentLen
+	binary.LittleEndian.PutUint32(data[pos:], sccOffset)
+	binary.LittleEndian.PutUint32(data[pos+4:], sccSize)
+}
+
+// writeBitmap fills a FRE* block. The bit of every block below total is set (MSB first).
+func writeBitmap(block []byte, next uint32, first int, total int) {
+	copy(block, markerBitmap)
+	binary.LittleEndian.PutUint32(block[4:], next)
+	for i := range blocksPerBitmap {
+		if first+i >= total {
+			break
+		}
+		block[8+i/8] |= 0x80 >> (i % 8)
+	}
+}
+
+// writeChain writes raw into consecutive DAT* blocks starting at offset. Each block points to the next one.
+func writeChain(data []byte, offset uint32, raw []byte) {
+	blocks := chainBlocks(len(raw))
+	for i := range blocks {
+		block := data[offset : offset+blockSize]
+		copy(block, markerData)
+		n := min(len(raw), dataLen)
+		if i+1 < blocks {
+			binary.LittleEndian.PutUint32(block[4:], offset+blockSize)
+		}
+		binary.LittleEndian.PutUint16(block[8:], uint16(n))
+		copy(block[dataHeaderLen:], raw[:n])
+		raw = raw[n:]
+		offset += blockSize
+	}
+}
+
+// writeEntry writes the ENT* chunk of e and returns its size.
+func writeEntry(chunk []byte, e *Entry, offset uint32) int {
+	pos := copy(chunk, markerEntry)
+	pos += copy(chunk[pos:], encodeUTF16(unicodeFormatVersion))
+	binary.LittleEndian.PutUint32(chunk[pos:], offset)
+	binary.LittleEndian.PutUint32(chunk[pos+4:], uint32(len(e.raw)))
+	binary.LittleEndian.PutUint32(chunk[pos+8:], uint32(e.Modified.Unix()))
+	binary.LittleEndian.PutUint16(chunk[pos+12:], uint16(e.commentBytes/2))
+	name := encodeUTF16(e.Name + "\x00")
+	binary.LittleEndian.PutUint16(chunk[pos+14:], uint16(len(name)))
+	pos += 16
+	pos += copy(chunk[pos:], name)
+	return pos
+}
+
+func entryChunkSize(e *Entry) int {
+	return 4 + 2*len(unicodeFormatVersion) + 16 + len(encodeUTF16(e.Name+"\x00"))
+}
+
+// chainBlocks returns the number of DAT* blocks needed to store size bytes (at least one).
+func chainBlocks(size int) int {
+	return max(1, (size+dataLen-1)/dataLen)
+}
+
+// packNodes distributes the (sorted) entries to as few nodes as possible.
+func packNodes(entries []*Entry) ([][]*Entry, error) {
+	var nodes [][]*Entry
+	var current []*Entry
+	used := 0
+	for _, e := range entries {
+		size := entryChunkSize(e)
+		if size > nodeCapacity {
+			return nil, fmt.Errorf("entry name %s is too long", e.Name)
+		}
+		if used+size > nodeCapacity {
+			nodes = append(nodes, current)
+			current, used = nil, 0
+		}
+		current = append(current, e)
+		used += size
+	}
+	if len(current) > 0 || len(nodes) == 0 {
+		nodes = append(nodes, current)
+	}
+	return nodes, nil
+}
+
+// nodeTree is a binary search tree over the (sorted) nodes: Nodes with smaller names are on the left side.
+type nodeTree struct {
+	index       int
+	left, right *nodeTree
+}
+
+func buildTree(lo, hi int) *nodeTree {
+	if lo > hi {
+		return nil
+	}
+	mid := (lo + hi) / 2
+	return &nodeTree{index: mid, left: buildTree(lo, mid-1), right: buildTree(mid+1, hi)}
+}
+
+func encodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := bytes.NewBuffer(make([]byte, 0, 2*len(u)))
+	for _, c := range u {
+		binary.Write(b, binary.LittleEndian, c)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,150 @@
+package pbl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewMatchesEmpty(t *testing.T) {
+	want, err := os.ReadFile("testdata/empty.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := Parse(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New("")
+	l.Modified = empty.Modified
+	got, err := l.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("empty library differs from testdata/empty.pbl")
+	}
+}
+
+func TestSetSource(t *testing.T) {
+	pblFile := filepath.Join(t.TempDir(), "manage_files.pbl")
+	data, err := os.ReadFile("testdata/manage_files.pbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(pblFile, data, 0o664)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib, err := Open(pblFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := lib.Source("manage_files.sra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	appData, err := lib.ReadData(lib.Entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSrc := "$PBExportHeader$w_test.srw\r\n$PBExportComments$Test Window\r\nforward\r\nglobal type w_test from window\r\nend type\r\n" +
+		strings.Repeat("// filler line to span several data blocks äöü\r\n", 50)
+	err = lib.SetSource("w_test", newSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lib.SetSource("manage_files.sra", appSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lib.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err = Open(pblFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(lib.Entries))
+	}
+	got, err := lib.Source("w_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != newSrc {
+		t.Errorf("source of w_test differs:\n%s", got)
+	}
+	got, err = lib.Source("manage_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != appSrc {
+		t.Errorf("source of manage_files differs after replacing it")
+	}
+	e, _ := lib.Entry("manage_files.apl")
+	gotData, err := lib.ReadData(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, appData) {
+		t.Errorf("compiled entry manage_files.apl was changed")
+	}
+
+	err = lib.Delete("w_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lib.Delete("w_test")
+	if err == nil {
+		t.Errorf("expected error when deleting a missing object")
+	}
+	err = lib.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lib.Entry("w_test.srw"); ok || len(lib.Entries) != 4 {
+		t.Errorf("w_test.srw was not deleted")
+	}
+}
+
+func TestManyEntries(t *testing.T) {
+	pblFile := filepath.Join(t.TempDir(), "many.pbl")
+	lib, err := Create(pblFile, "many entries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 500 {
+		err = lib.SetSource(fmt.Sprintf("f_test_function_%03d.srf", i), fmt.Sprintf("global function integer f_test_function_%03d ()\r\nreturn %d\r\nend function\r\n", i, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = lib.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err = Open(pblFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lib.Comment != "many entries" || len(lib.Entries) != 500 || lib.FreeBlocks != 0 {
+		t.Fatalf("unexpected library: comment %q, %d entries, %d free blocks", lib.Comment, len(lib.Entries), lib.FreeBlocks)
+	}
+	for _, i := range []int{0, 123, 499} {
+		src, err := lib.Source(fmt.Sprintf("f_test_function_%03d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(src, fmt.Sprintf("return %d\r\n", i)) {
+			t.Errorf("wrong source for function %d: %s", i, src)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package pblmerge merges three versions of a library object by object (see textdiff.MergeObjects) and imports
+// the result into a library of a target.
+package pblmerge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/importer"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/textdiff"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+)
+
+// Files are the libraries of a merge.
+type Files struct {
+	Base, Mine, Theirs string
+	// Merged is the file the result is written to, Mine if empty.
+	Merged string
+	// WorkingCopy is the library in the working copy if the other files are temp files (e.g. of a git merge
+	// driver), empty otherwise. ORCA can only import into libraries of the target, so the objects are imported
+	// into the working copy and the result is copied to Merged.
+	WorkingCopy string
+}
+
+// Result lists what a merge did.
+type Result struct {
+	Library     string                  // library the objects were imported into
+	Deleted     []string                // entry names
+	Imported    []string                // object names
+	Conflicts   []textdiff.ObjectResult // objects which could not be merged, written to ConflictDir
+	ConflictDir string
+	Errs        []error // failed deletions and imports
+}
+
+// Merge merges the changes from base to theirs into mine. Objects changed on only one side are taken as they are,
+// objects changed on both sides are merged line by line. The result is imported into the merged library with the
+// target pbtFilePath. Objects which could not be merged are written with conflict markers to a folder next to the
+// library (see Result.ConflictDir) and are not imported.
+func Merge(b backend.Backend, pbtFilePath string, files Files, labels textdiff.Labels) (res *Result, err error) {
+	if files.Merged == "" {
+		files.Merged = files.Mine
+	}
+	library := files.Merged
+	if files.WorkingCopy != "" {
+		library = files.WorkingCopy
+	}
+	res = &Result{Library: library}
+
+	srcBase, err := pbl.ReadSources(files.Base)
+	if err != nil {
+		return nil, err
+	}
+	srcMine, err := pbl.ReadSources(files.Mine)
+	if err != nil {
+		return nil, err
+	}
+	srcTheirs, err := pbl.ReadSources(files.Theirs)
+	if err != nil {
+		return nil, err
+	}
+	results := textdiff.MergeObjects(srcBase, srcMine, srcTheirs, labels)
+
+	if filepath.Clean(library) != filepath.Clean(files.Mine) {
+		err = utils.CopyFile(files.Mine, library)
+		if err != nil {
+			return nil, fmt.Errorf("could not copy %s to %s: %v", files.Mine, library, err)
+		}
+	}
+	if filepath.Clean(library) != filepath.Clean(files.Merged) {
+		defer func() {
+			cerr := utils.CopyFile(library, files.Merged)
+			if cerr != nil && err == nil {
+				err = fmt.Errorf("could not copy %s to %s: %v", library, files.Merged, cerr)
+			}
+		}()
+	}
+
+	var sources []importer.Source
+	for _, r := range results {
+		switch r.Action {
+		case textdiff.Update:
+			sources = append(sources, importer.Source{Library: library, Entry: r.Name, Src: []byte(r.Source)})
+		case textdiff.Conflict:
+			res.Conflicts = append(res.Conflicts, r)
+		case textdiff.Remove:
+			err = b.DeleteObj(library, r.Name)
+			if err != nil {
+				res.Errs = append(res.Errs, fmt.Errorf("deletion of %s failed: %v", r.Name, err))
+				continue
+			}
+			res.Deleted = append(res.Deleted, r.Name)
+		}
+	}
+
+	// objects may depend on each other, so they are imported in the order of their dependencies
+	err = importer.ImportSources(b, pbtFilePath, sources, func(_, object string) {
+		res.Imported = append(res.Imported, object)
+	})
+	var importErr *importer.ImportError
+	if errors.As(err, &importErr) {
+		for _, f := range importErr.Failures {
+			res.Errs = append(res.Errs, fmt.Errorf("import of %s failed: %v", f.Object, f.Err))
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(res.Conflicts) > 0 {
+		res.ConflictDir = library + ".conflicts"
+		err = os.MkdirAll(res.ConflictDir, 0o775)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range res.Conflicts {
+			err = os.WriteFile(filepath.Join(res.ConflictDir, r.Name), []byte(r.Source), 0o664)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,126 @@
+package pblmerge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/textdiff"
+)
+
+func TestMerge(t *testing.T) {
+	src := func(name string, lines ...string) string {
+		return "$PBExportHeader$" + name + "\r\n" + strings.Join(lines, "\r\n") + "\r\n"
+	}
+	base := map[string]string{
+		"u_keep.sru":     src("u_keep.sru", "global type u_keep from nonvisualobject", "end type"),
+		"u_theirs.sru":   src("u_theirs.sru", "global type u_theirs from nonvisualobject", "end type"),
+		"u_removed.sru":  src("u_removed.sru", "global type u_removed from nonvisualobject", "end type"),
+		"u_conflict.sru": src("u_conflict.sru", "global type u_conflict from nonvisualobject", "end type"),
+	}
+	mine := map[string]string{
+		"u_keep.sru":     base["u_keep.sru"],
+		"u_theirs.sru":   base["u_theirs.sru"],
+		"u_removed.sru":  base["u_removed.sru"],
+		"u_conflict.sru": src("u_conflict.sru", "global type u_conflict from u_keep", "end type"),
+	}
+	theirs := map[string]string{
+		"u_keep.sru":     base["u_keep.sru"],
+		"u_theirs.sru":   src("u_theirs.sru", "global type u_theirs from u_keep", "end type"),
+		"u_new.sru":      src("u_new.sru", "global type u_new from u_theirs", "end type"),
+		"u_conflict.sru": src("u_conflict.sru", "global type u_conflict from u_theirs", "end type"),
+	}
+
+	// git runs a merge driver with temp files next to the working copy, %A is mine and receives the result
+	dir := t.TempDir()
+	files := Files{
+		Base:        filepath.Join(dir, ".merge_file_a00001"),
+		Mine:        filepath.Join(dir, ".merge_file_a00002"),
+		Theirs:      filepath.Join(dir, ".merge_file_a00003"),
+		WorkingCopy: filepath.Join(dir, "inf1.pbl"),
+	}
+	for path, sources := range map[string]map[string]string{files.Base: base, files.Mine: mine, files.Theirs: theirs} {
+		lib, err := pbl.Create(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, s := range sources {
+			if err := lib.SetSource(name, s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := lib.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(files.WorkingCopy, nil, 0o664); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the working copy is a library of the target, writes to the temp files fail
+	fake := backend.NewFake(map[string]map[string]string{files.WorkingCopy: mine})
+	res, err := Merge(fake, filepath.Join(dir, "a3.pbt"), files, textdiff.Labels{Base: "base", Mine: "ours", Theirs: "theirs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Errs) != 0 {
+		t.Fatalf("unexpected errors %v", res.Errs)
+	}
+	if res.Library != files.WorkingCopy {
+		t.Errorf("got library %s, want %s", res.Library, files.WorkingCopy)
+	}
+	for _, w := range fake.Writes() {
+		if w.Library != files.WorkingCopy {
+			t.Errorf("%s of %s written to %s", w.Action, w.Object, w.Library)
+		}
+	}
+
+	if strings.Join(res.Deleted, ",") != "u_removed.sru" {
+		t.Errorf("got deleted %v", res.Deleted)
+	}
+	if _, ok := fake.Source(files.WorkingCopy, "u_removed.sru"); ok {
+		t.Errorf("u_removed.sru was not deleted")
+	}
+	if strings.Join(res.Imported, ",") != "u_theirs,u_new" {
+		t.Errorf("got imported %v, want u_theirs before u_new", res.Imported)
+	}
+	for _, name := range []string{"u_theirs.sru", "u_new.sru"} {
+		if s, ok := fake.Source(files.WorkingCopy, name); !ok || s != theirs[name] {
+			t.Errorf("%s was not imported", name)
+		}
+	}
+	if s, _ := fake.Source(files.WorkingCopy, "u_conflict.sru"); s != mine["u_conflict.sru"] {
+		t.Errorf("u_conflict.sru must not be imported")
+	}
+
+	if len(res.Conflicts) != 1 || res.Conflicts[0].Name != "u_conflict.sru" {
+		t.Fatalf("got conflicts %+v", res.Conflicts)
+	}
+	if res.ConflictDir != files.WorkingCopy+".conflicts" {
+		t.Errorf("got conflict dir %s", res.ConflictDir)
+	}
+	conflict, err := os.ReadFile(filepath.Join(res.ConflictDir, "u_conflict.sru"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(conflict, []byte("<<<<<<< ours")) || !bytes.Contains(conflict, []byte(">>>>>>> theirs")) {
+		t.Errorf("conflict markers missing in\n%s", conflict)
+	}
+
+	// the result is copied back to %A
+	wc, err := os.ReadFile(files.WorkingCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := os.ReadFile(files.Mine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(wc, merged) {
+		t.Errorf("%s differs from the working copy", files.Mine)
+	}
+}
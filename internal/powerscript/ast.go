@@ -0,0 +1,220 @@
+// Package powerscript parses exported PowerScript sources (.sra, .srf, .srm, .srs, .sru, .srw) into a syntax tree.
+//
+// The parser covers the structure of an object: forward declarations, type blocks, variable blocks, prototypes,
+// functions and subroutines, events and the on create/destroy sections. The script bodies themselves are not parsed.
+// Every node keeps the byte offsets of its source, so a fix can replace exactly the function or event it wants to
+// change instead of matching the whole object with a regex:
+//
+//	f, err := powerscript.Parse(src)
+//	fn := f.Function("of_check_version")
+//	src = f.Replace(fn.Body, newBody)
+package powerscript
+
+import "strings"
+
+// Span is a range of bytes within the source. End points behind the last byte, for whole constructs
+// (e.g. a function) this includes the line break of the closing line.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Len returns the number of bytes in the span.
+func (s Span) Len() int {
+	return s.End - s.Start
+}
+
+// File is the syntax tree of one exported object.
+type File struct {
+	Src        string
+	Name       string // from $PBExportHeader$, e.g. w_main.srw
+	Comment    string // from $PBExportComments$, may span several lines
+	Forward    *Forward
+	Types      []*TypeDecl // the global type first, followed by controls, menu items and local structures
+	Variables  []*VarBlock
+	Prototypes []*PrototypeBlock
+	Functions  []*Function
+	Events     []*Event
+	OnBlocks   []*OnBlock
+}
+
+// Forward is the 'forward ... end forward' section.
+type Forward struct {
+	Span
+	Types   []*TypeDecl
+	Globals []*Variable // global variables of applications, e.g. 'global transaction sqlca'
+}
+
+// TypeDecl is a 'type ... end type' block, e.g. 'global type w_main from window' or
+// 'type cb_ok from commandbutton within w_main'.
+type TypeDecl struct {
+	Span
+	Global     bool
+	Name       string
+	Ancestor   string
+	Within     string       // parent object of controls, empty for the global type
+	Native     string       // dll of native (PBNI) types
+	Events     []*EventDecl // user defined events declared in the type
+	Prototypes []*Prototype // functions declared in native types
+	Body       Span         // the lines between the type and the end type line
+}
+
+// EventDecl is the declaration of a user event within a type block, e.g. 'event ue_expand ( )'.
+type EventDecl struct {
+	Span
+	Name       string
+	ReturnType string // empty if the event has no return value
+	EventID    string // e.g. pbm_keydown, empty for custom events
+	Params     []*Param
+}
+
+// VarBlock is a 'type variables', 'shared variables' or 'global variables' block.
+type VarBlock struct {
+	Span
+	Scope string // one of instance, shared, global
+	Vars  []*Variable
+}
+
+// Variable is a single variable declared in a variable block. Declarations of several variables in one
+// statement (e.g. 'long ll_a, ll_b') result in one Variable each, all sharing the span of the statement.
+type Variable struct {
+	Span
+	Access   string // e.g. protected, empty if not specified
+	Constant bool
+	Type     string
+	Name     string
+	Array    string // array dimensions, e.g. [] or [10], empty for scalars
+	Value    string // initial value, empty if not specified
+}
+
+// PrototypeBlock is a 'forward prototypes' or 'type prototypes' (external functions) block.
+type PrototypeBlock struct {
+	Span
+	External   bool // true for 'type prototypes'
+	Prototypes []*Prototype
+}
+
+// Signature is the signature of a function or subroutine.
+type Signature struct {
+	Access     string // public, protected, private or empty
+	Global     bool   // global functions (.srf)
+	Kind       string // function or subroutine
+	ReturnType string // empty for subroutines
+	Name       string
+	Params     []*Param
+	Throws     string // e.g. u_exf_ex
+	Library    string // dll of external functions
+	Alias      string // external name of external functions
+}
+
+// Prototype is a single line of a prototype block.
+type Prototype struct {
+	Span
+	Signature
+}
+
+// Function is the implementation of a function or subroutine.
+type Function struct {
+	Span
+	Signature
+	Header Span // the signature including the terminating semicolon
+	Body   Span // the script between the header and the end function line
+}
+
+// Event is the implementation of an event, e.g. 'event clicked;...end event'.
+type Event struct {
+	Span
+	Object     string // name of the type the event belongs to (the global type or a control)
+	Name       string
+	ReturnType string
+	Params     []*Param
+	Header     Span
+	Body       Span
+}
+
+// OnBlock is an 'on w_main.create ... end on' section.
+type OnBlock struct {
+	Span
+	Object string
+	Name   string // create or destroy
+	Body   Span
+}
+
+// Param is a parameter of a function, subroutine or event.
+type Param struct {
+	Ref      bool
+	ReadOnly bool
+	Type     string
+	Name     string
+	Array    string
+}
+
+// Text returns the source of the span.
+func (f *File) Text(s Span) string {
+	return f.Src[s.Start:s.End]
+}
+
+// Line returns the line number (starting with 1) of a byte offset within the source.
+func (f *File) Line(offset int) int {
+	return strings.Count(f.Src[:min(offset, len(f.Src))], "\n") + 1
+}
+
+// Replace returns the source with the span replaced by text. The tree itself is not updated.
+func (f *File) Replace(s Span, text string) string {
+	return f.Src[:s.Start] + text + f.Src[s.End:]
+}
+
+// Type returns the type declaration with the given name (not the forward declaration) or nil.
+func (f *File) Type(name string) *TypeDecl {
+	for _, t := range f.Types {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Function returns the first implementation of the function or subroutine with the given name or nil.
+// Use Overloads to get all implementations of an overloaded function.
+func (f *File) Function(name string) *Function {
+	if fns := f.Overloads(name); len(fns) > 0 {
+		return fns[0]
+	}
+	return nil
+}
+
+// Overloads returns all implementations of the function or subroutine with the given name.
+func (f *File) Overloads(name string) []*Function {
+	var fns []*Function
+	for _, fn := range f.Functions {
+		if strings.EqualFold(fn.Name, name) {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// Event returns the implementation of the event of object or nil. An empty object refers to the global type.
+func (f *File) Event(object, name string) *Event {
+	if object == "" && len(f.Types) > 0 {
+		object = f.Types[0].Name
+	}
+	for _, e := range f.Events {
+		if strings.EqualFold(e.Object, object) && strings.EqualFold(e.Name, name) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Variable returns the instance, shared or global variable with the given name or nil.
+func (f *File) Variable(name string) *Variable {
+	for _, b := range f.Variables {
+		for _, v := range b.Vars {
+			if strings.EqualFold(v.Name, name) {
+				return v
+			}
+		}
+	}
+	return nil
+}
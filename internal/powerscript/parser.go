@@ -0,0 +1,541 @@
+package powerscript
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	regexType      = regexp.MustCompile(`(?i)^(global[ \t]+)?type[ \t]+(\w+)[ \t]+from[ \t]+(\S+)(?:[ \t]+within[ \t]+(\w+))?(?:[ \t]+native[ \t]+"([^"]*)")?[ \t]*$`)
+	regexSignature = regexp.MustCompile(`(?is)^(?:(global|public|protected|private)[ \t]+)?(function|subroutine)[ \t]+([^(]*?)[ \t]*\((.*?)\)(.*)$`)
+	regexEventDecl = regexp.MustCompile(`(?i)^event[ \t]+(?:type[ \t]+(\S+)[ \t]+)?(\w+)[ \t]*(?:\((.*?)\)|([ \t]+\w+))?[ \t]*$`)
+	regexEventImpl = regexp.MustCompile(`(?is)^event[ \t]+(?:type[ \t]+(\S+)[ \t]+)?(?:(\w+)::)?(\w+)[ \t]*(?:\((.*?)\))?[ \t]*;`)
+	regexOn        = regexp.MustCompile(`(?i)^on[ \t]+(\w+)\.(\w+)[ \t]*$`)
+	regexThrows    = regexp.MustCompile(`(?i)\bthrows[ \t]+([\w, \t]+?)[ \t]*(?:$|;)`)
+	regexLibrary   = regexp.MustCompile(`(?i)\blibrary[ \t]+["']([^"']*)["']`)
+	regexAlias     = regexp.MustCompile(`(?i)\balias[ \t]+for[ \t]+["']([^"']*)["']`)
+)
+
+var accessModifiers = map[string]bool{
+	"public": true, "protected": true, "private": true,
+	"protectedread": true, "protectedwrite": true, "privateread": true, "privatewrite": true,
+}
+
+// line is a single line of the source. text does not contain the line break.
+type line struct {
+	text  string
+	start int // offset of the first byte
+	end   int // offset after the line break
+}
+
+type parser struct {
+	f      *File
+	lines  []line
+	i      int
+	object string // type the following events belong to
+}
+
+// Parse parses an exported PowerScript object. A $PBExportHeader$ line is optional.
+func Parse(src string) (*File, error) {
+	p := &parser{f: &File{Src: src}, lines: splitLines(src)}
+	err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return p.f, nil
+}
+
+func splitLines(src string) []line {
+	var lines []line
+	for pos := 0; pos < len(src); {
+		end := strings.IndexByte(src[pos:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += pos + 1
+		}
+		// sources read from a pbl may end with a null byte
+		lines = append(lines, line{text: strings.TrimRight(src[pos:end], "\r\n\x00"), start: pos, end: end})
+		pos = end
+	}
+	return lines
+}
+
+func (p *parser) parse() error {
+	commentStart := -1
+	for ; p.i < len(p.lines); p.i++ {
+		l := p.lines[p.i]
+		text := strings.TrimSpace(l.text)
+		if p.i == 0 {
+			text = strings.TrimPrefix(text, "\uFEFF")
+		}
+		lower := strings.ToLower(text)
+
+		var err error
+		switch {
+		case strings.HasPrefix(text, "$PBExportHeader$"):
+			p.f.Name = strings.TrimPrefix(text, "$PBExportHeader$")
+		case strings.HasPrefix(text, "$PBExportComments$"):
+			p.f.Comment = strings.TrimPrefix(text, "$PBExportComments$")
+			commentStart = l.start + strings.Index(l.text, "$PBExportComments$") + len("$PBExportComments$")
+			continue
+		case lower == "forward":
+			err = p.parseForward()
+		case lower == "forward prototypes" || lower == "type prototypes":
+			err = p.parsePrototypes(lower == "type prototypes")
+		case lower == "type variables" || lower == "shared variables" || lower == "global variables":
+			err = p.parseVariables(strings.Fields(lower)[0])
+		case regexType.MatchString(text):
+			var t *TypeDecl
+			t, err = p.parseType(true)
+			if err == nil {
+				p.f.Types = append(p.f.Types, t)
+				p.object = t.Name
+			}
+		case regexSignature.MatchString(text):
+			err = p.parseFunction()
+		case regexEventImpl.MatchString(text):
+			err = p.parseEvent()
+		case regexOn.MatchString(text):
+			err = p.parseOn()
+		default:
+			if commentStart >= 0 {
+				// the comment continues until the first known construct
+				p.f.Comment = strings.TrimRight(p.f.Src[commentStart:l.end], "\r\n")
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		commentStart = -1
+	}
+	return nil
+}
+
+// until searches the line closing the block started at the current line and returns its index.
+func (p *parser) until(end string) (int, error) {
+	for j := p.i + 1; j < len(p.lines); j++ {
+		if strings.EqualFold(strings.TrimSpace(p.lines[j].text), end) {
+			return j, nil
+		}
+	}
+	return 0, fmt.Errorf("line %d: missing '%s' for '%s'", p.i+1, end, strings.TrimSpace(p.lines[p.i].text))
+}
+
+// span returns the span from the start of the current line to the end of line j.
+func (p *parser) span(j int) Span {
+	return Span{Start: p.lines[p.i].start, End: p.lines[j].end}
+}
+
+// statement returns the statement starting at line j without comments. Statements continued with & on the
+// following lines are joined. The index of the last line of the statement is returned as well.
+func (p *parser) statement(j, end int, inComment *bool) (string, int) {
+	stmt := ""
+	for ; j < end; j++ {
+		var text string
+		text, *inComment = stripComment(p.lines[j].text, *inComment)
+		text = strings.TrimSpace(text)
+		if !strings.HasSuffix(text, "&") {
+			return stmt + text, j
+		}
+		stmt += strings.TrimSuffix(text, "&") + " "
+	}
+	return strings.TrimSpace(stmt), end - 1
+}
+
+func (p *parser) parseForward() error {
+	end, err := p.until("end forward")
+	if err != nil {
+		return err
+	}
+	fw := &Forward{Span: p.span(end)}
+	for p.i++; p.i < end; p.i++ {
+		text := strings.TrimSpace(p.lines[p.i].text)
+		if regexType.MatchString(text) {
+			t, err := p.parseType(false)
+			if err != nil {
+				return err
+			}
+			fw.Types = append(fw.Types, t)
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(text), "global ") {
+			vars := parseVarStatement(strings.TrimSpace(text[len("global "):]), Span{p.lines[p.i].start, p.lines[p.i].end})
+			fw.Globals = append(fw.Globals, vars...)
+		}
+	}
+	p.f.Forward = fw
+	return nil
+}
+
+// parseType parses a type block. Event declarations are only collected if withEvents is set (i.e. not in the
+// forward section).
+func (p *parser) parseType(withEvents bool) (*TypeDecl, error) {
+	m := regexType.FindStringSubmatch(strings.TrimSpace(p.lines[p.i].text))
+	end, err := p.until("end type")
+	if err != nil {
+		return nil, err
+	}
+	t := &TypeDecl{
+		Span:     p.span(end),
+		Global:   m[1] != "",
+		Name:     m[2],
+		Ancestor: m[3],
+		Within:   m[4],
+		Native:   m[5],
+		Body:     Span{Start: p.lines[p.i].end, End: p.lines[end].start},
+	}
+	for j := p.i + 1; j < end && withEvents; j++ {
+		text := strings.TrimSpace(p.lines[j].text)
+		if sig, ok := parseSignature(text); ok && t.Native != "" {
+			t.Prototypes = append(t.Prototypes, &Prototype{Span: Span{Start: p.lines[j].start, End: p.lines[j].end}, Signature: sig})
+			continue
+		}
+		mm := regexEventDecl.FindStringSubmatch(text)
+		if mm == nil {
+			continue
+		}
+		t.Events = append(t.Events, &EventDecl{
+			Span:       Span{Start: p.lines[j].start, End: p.lines[j].end},
+			Name:       mm[2],
+			ReturnType: mm[1],
+			EventID:    strings.TrimSpace(mm[4]),
+			Params:     parseParams(mm[3]),
+		})
+	}
+	p.i = end
+	return t, nil
+}
+
+func (p *parser) parseVariables(scope string) error {
+	end, err := p.until("end variables")
+	if err != nil {
+		return err
+	}
+	if scope == "type" {
+		scope = "instance"
+	}
+	b := &VarBlock{Span: p.span(end), Scope: scope}
+	access := ""
+	inComment := false
+	for j := p.i + 1; j < end; j++ {
+		start := p.lines[j].start
+		var stmt string
+		stmt, j = p.statement(j, end, &inComment)
+		if stmt == "" {
+			continue
+		}
+		span := Span{Start: start, End: p.lines[j].end}
+		if strings.HasSuffix(stmt, ":") && !strings.ContainsAny(stmt, " \t") {
+			// access label like 'protected:'
+			access = strings.ToLower(strings.TrimSuffix(stmt, ":"))
+			continue
+		}
+		vars := parseVarStatement(stmt, span)
+		for _, v := range vars {
+			if v.Access == "" {
+				v.Access = access
+			}
+		}
+		b.Vars = append(b.Vars, vars...)
+	}
+	p.f.Variables = append(p.f.Variables, b)
+	p.i = end
+	return nil
+}
+
+func (p *parser) parsePrototypes(external bool) error {
+	end, err := p.until("end prototypes")
+	if err != nil {
+		return err
+	}
+	b := &PrototypeBlock{Span: p.span(end), External: external}
+	inComment := false
+	for j := p.i + 1; j < end; j++ {
+		start := p.lines[j].start
+		var text string
+		text, j = p.statement(j, end, &inComment)
+		if text == "" {
+			continue
+		}
+		sig, ok := parseSignature(text)
+		if !ok {
+			return fmt.Errorf("line %d: invalid prototype '%s'", j+1, text)
+		}
+		b.Prototypes = append(b.Prototypes, &Prototype{
+			Span:      Span{Start: start, End: p.lines[j].end},
+			Signature: sig,
+		})
+	}
+	p.f.Prototypes = append(p.f.Prototypes, b)
+	p.i = end
+	return nil
+}
+
+func (p *parser) parseFunction() error {
+	l := p.lines[p.i]
+	header := strings.TrimSpace(l.text)
+	sig, ok := parseSignature(header[:headerLen(header)])
+	if !ok {
+		return fmt.Errorf("line %d: invalid function header '%s'", p.i+1, header)
+	}
+	end, err := p.until("end " + sig.Kind)
+	if err != nil {
+		return err
+	}
+	headerEnd := l.start + strings.Index(l.text, header) + headerLen(header)
+	p.f.Functions = append(p.f.Functions, &Function{
+		Span:      p.span(end),
+		Signature: sig,
+		Header:    Span{Start: l.start, End: headerEnd},
+		Body:      Span{Start: headerEnd, End: p.lines[end].start},
+	})
+	p.i = end
+	return nil
+}
+
+func (p *parser) parseEvent() error {
+	l := p.lines[p.i]
+	header := strings.TrimSpace(l.text)
+	m := regexEventImpl.FindStringSubmatch(header)
+	if m == nil {
+		return fmt.Errorf("line %d: invalid event header '%s'", p.i+1, header)
+	}
+	end, err := p.until("end event")
+	if err != nil {
+		return err
+	}
+	object := p.object
+	if m[2] != "" {
+		object = m[2]
+	}
+	headerEnd := l.start + strings.Index(l.text, header) + len(m[0])
+	p.f.Events = append(p.f.Events, &Event{
+		Span:       p.span(end),
+		Object:     object,
+		Name:       m[3],
+		ReturnType: m[1],
+		Params:     parseParams(m[4]),
+		Header:     Span{Start: l.start, End: headerEnd},
+		Body:       Span{Start: headerEnd, End: p.lines[end].start},
+	})
+	p.i = end
+	return nil
+}
+
+func (p *parser) parseOn() error {
+	m := regexOn.FindStringSubmatch(strings.TrimSpace(p.lines[p.i].text))
+	end, err := p.until("end on")
+	if err != nil {
+		return err
+	}
+	p.f.OnBlocks = append(p.f.OnBlocks, &OnBlock{
+		Span:   p.span(end),
+		Object: m[1],
+		Name:   m[2],
+		Body:   Span{Start: p.lines[p.i].end, End: p.lines[end].start},
+	})
+	p.i = end
+	return nil
+}
+
+// headerLen returns the length of a function header including the semicolon (or the whole line for prototypes).
+func headerLen(header string) int {
+	inString := rune(0)
+	for i, c := range header {
+		switch {
+		case inString != 0:
+			if c == inString {
+				inString = 0
+			}
+		case c == '"' || c == '\'':
+			inString = c
+		case c == ';':
+			return i + 1
+		}
+	}
+	return len(header)
+}
+
+func parseSignature(text string) (Signature, bool) {
+	m := regexSignature.FindStringSubmatch(strings.TrimSuffix(strings.TrimSpace(text), ";"))
+	if m == nil {
+		return Signature{}, false
+	}
+	sig := Signature{Kind: strings.ToLower(m[2]), Params: parseParams(m[4])}
+	if strings.EqualFold(m[1], "global") {
+		sig.Global = true
+	} else {
+		sig.Access = strings.ToLower(m[1])
+	}
+	fields := strings.Fields(m[3])
+	switch {
+	case sig.Kind == "function" && len(fields) == 2:
+		sig.ReturnType, sig.Name = fields[0], fields[1]
+	case sig.Kind == "subroutine" && len(fields) == 1:
+		sig.Name = fields[0]
+	default:
+		return Signature{}, false
+	}
+	tail := m[5]
+	if mm := regexThrows.FindStringSubmatch(tail); mm != nil {
+		sig.Throws = strings.TrimSpace(mm[1])
+	}
+	if mm := regexLibrary.FindStringSubmatch(tail); mm != nil {
+		sig.Library = mm[1]
+	}
+	if mm := regexAlias.FindStringSubmatch(tail); mm != nil {
+		sig.Alias = mm[1]
+	}
+	return sig, true
+}
+
+func parseParams(text string) []*Param {
+	var params []*Param
+	for _, part := range splitTopLevel(text) {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		param := &Param{}
+		for ; len(fields) > 1; fields = fields[1:] {
+			if strings.EqualFold(fields[0], "ref") {
+				param.Ref = true
+			} else if strings.EqualFold(fields[0], "readonly") {
+				param.ReadOnly = true
+			} else {
+				break
+			}
+		}
+		if len(fields) == 1 {
+			// variable number of arguments (...)
+			param.Type = fields[0]
+		} else {
+			param.Type = strings.Join(fields[:len(fields)-1], " ")
+			param.Name, param.Array = splitArray(fields[len(fields)-1])
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// parseVarStatement parses a declaration like 'protected constant long CL_X = 1' or 'long ll_a, ll_b[]'.
+func parseVarStatement(stmt string, span Span) []*Variable {
+	var modifiers []string
+	constant := false
+	typ, rest := nextWord(stmt)
+	for {
+		word := strings.ToLower(typ)
+		if accessModifiers[word] {
+			modifiers = append(modifiers, word)
+		} else if word == "constant" {
+			constant = true
+		} else {
+			break
+		}
+		typ, rest = nextWord(rest)
+	}
+	access := strings.Join(modifiers, " ")
+	if strings.HasPrefix(strings.TrimSpace(rest), "{") {
+		// precision, e.g. decimal {2}
+		end := strings.Index(rest, "}")
+		if end >= 0 {
+			typ += strings.TrimSpace(rest[:end+1])
+			rest = rest[end+1:]
+		}
+	}
+	if typ == "" || strings.TrimSpace(rest) == "" {
+		return nil
+	}
+	var vars []*Variable
+	for _, part := range splitTopLevel(rest) {
+		name, value, _ := strings.Cut(part, "=")
+		v := &Variable{Span: span, Access: access, Constant: constant, Type: typ, Value: strings.TrimSpace(value)}
+		v.Name, v.Array = splitArray(strings.TrimSpace(name))
+		if v.Name != "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+func nextWord(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t")
+	end := strings.IndexAny(s, " \t")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// splitArray splits 'ls_names[10]' into 'ls_names' and '[10]'.
+func splitArray(name string) (string, string) {
+	if i := strings.Index(name, "["); i >= 0 {
+		return strings.TrimSpace(name[:i]), strings.ReplaceAll(name[i:], " ", "")
+	}
+	return name, ""
+}
+
+// splitTopLevel splits s at commas which are not within strings or brackets.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth := 0
+	inString := rune(0)
+	start := 0
+	for i, c := range s {
+		switch {
+		case inString != 0:
+			if c == inString {
+				inString = 0
+			}
+		case c == '"' || c == '\'':
+			inString = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// stripComment removes // and /* */ comments from a line. inComment tells whether the line starts within a
+// block comment, the returned bool whether the next line does.
+func stripComment(text string, inComment bool) (string, bool) {
+	var sb strings.Builder
+	inString := byte(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inComment:
+			if c == '*' && i+1 < len(text) && text[i+1] == '/' {
+				inComment = false
+				i++
+			}
+		case inString != 0:
+			sb.WriteByte(c)
+			if c == inString {
+				inString = 0
+			}
+		case c == '"' || c == '\'':
+			inString = c
+			sb.WriteByte(c)
+		case c == '/' && i+1 < len(text) && text[i+1] == '/':
+			return sb.String(), false
+		case c == '/' && i+1 < len(text) && text[i+1] == '*':
+			inComment = true
+			i++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), inComment
+}
@@ -0,0 +1,147 @@
+package powerscript
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func parseFile(t *testing.T, name string) *File {
+	t.Helper()
+	src, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParseWindow(t *testing.T) {
+	f := parseFile(t, "exf1_w_exf_error_message.srw")
+	if f.Name != "exf1_w_exf_error_message.srw" {
+		t.Errorf("wrong name %s", f.Name)
+	}
+	if f.Forward == nil || len(f.Forward.Types) != 14 || len(f.Types) != 14 {
+		t.Fatalf("expected 14 forward and type declarations")
+	}
+	w := f.Types[0]
+	if !w.Global || w.Name != "exf1_w_exf_error_message" || w.Ancestor != "window" || w.Within != "" {
+		t.Errorf("wrong global type: %+v", w)
+	}
+	if len(w.Events) != 4 || w.Events[3].Name != "ue_shortcut" || len(w.Events[3].Params) != 2 {
+		t.Errorf("wrong event declarations: %+v", w.Events)
+	}
+	if cb := f.Type("cb_send"); cb == nil || cb.Ancestor != "commandbutton" || cb.Within != w.Name {
+		t.Errorf("wrong control type cb_send: %+v", cb)
+	}
+
+	v := f.Variable("CL_MESSAGE_TYPE_ERROR")
+	if v == nil || !v.Constant || v.Type != "long" || v.Value != "2" {
+		t.Errorf("wrong constant: %+v", v)
+	}
+	if v := f.Variable("pbo_expanded"); v == nil || v.Access != "protected" || v.Type != "boolean" {
+		t.Errorf("wrong instance variable: %+v", v)
+	}
+
+	if len(f.Prototypes) != 1 || len(f.Prototypes[0].Prototypes) != 5 || len(f.Functions) != 5 {
+		t.Fatalf("expected 5 prototypes and functions")
+	}
+	fn := f.Function("pf_is_inherited_from")
+	if fn == nil || fn.Access != "protected" || fn.ReturnType != "boolean" || len(fn.Params) != 2 || !fn.Params[0].Ref {
+		t.Fatalf("wrong function: %+v", fn)
+	}
+	text := f.Text(fn.Span)
+	if !strings.HasPrefix(text, "protected function boolean pf_is_inherited_from (ref powerobject apo_object") || !strings.HasSuffix(text, "end function\r\n") {
+		t.Errorf("wrong span of function: %q", text)
+	}
+	if !strings.HasSuffix(f.Text(fn.Header), "string as_parent_classname);") || !strings.HasPrefix(f.Text(fn.Body), "//Zweck") {
+		t.Errorf("wrong header/body split: %q", f.Text(fn.Header))
+	}
+
+	if e := f.Event("cb_send", "clicked"); e == nil || !strings.Contains(f.Text(e.Body), "of_report_case") {
+		t.Errorf("event clicked of cb_send not found")
+	}
+	if e := f.Event("", "ue_set_type"); e == nil || len(e.Params) != 1 || e.Params[0].Name != "al_message_type" {
+		t.Errorf("wrong event ue_set_type: %+v", e)
+	}
+	if len(f.OnBlocks) != 2 || f.OnBlocks[1].Name != "destroy" {
+		t.Errorf("wrong on blocks")
+	}
+}
+
+func TestParseExternalFunctions(t *testing.T) {
+	f := parseFile(t, "liq1_u_codereader.sru")
+	var ext *PrototypeBlock
+	for _, b := range f.Prototypes {
+		if b.External {
+			ext = b
+		}
+	}
+	if ext == nil {
+		t.Fatal("type prototypes not found")
+	}
+	p := ext.Prototypes[0]
+	if p.Name != "pef_create_reader" || p.Library != "lib.ext.base.dtk-barreader.dll" || p.Alias != "BarcodeReader_Create" || len(p.Params) != 1 {
+		t.Errorf("wrong external function: %+v", p)
+	}
+	if !strings.HasSuffix(f.Text(p.Span), "alias for 'BarcodeReader_Create'\r\n") {
+		t.Errorf("span of multi line prototype is wrong: %q", f.Text(p.Span))
+	}
+}
+
+func TestParseGlobalFunction(t *testing.T) {
+	f := parseFile(t, "gf_get_stacktrace.srf")
+	if f.Comment != "Der direkte Aufruf dieser Funktion ist verboten!\r\nStattdessen muss man u_exf_error_manager.of_get_stacktrace() verwenden." {
+		t.Errorf("wrong comment: %q", f.Comment)
+	}
+	p := f.Prototypes[0].Prototypes[0]
+	if !p.Global || p.Library != "exf1.dll" || p.Params[0].Array != "[]" || !p.Params[0].Ref {
+		t.Errorf("wrong prototype: %+v", p)
+	}
+}
+
+func TestParseApplication(t *testing.T) {
+	f := parseFile(t, "manage_files.sra")
+	if len(f.Forward.Globals) != 5 || f.Forward.Globals[0].Type != "transaction" || f.Forward.Globals[0].Name != "sqlca" {
+		t.Errorf("wrong globals in forward section: %+v", f.Forward.Globals)
+	}
+	if v := f.Variable("gu_e"); v == nil || f.Variables[0].Scope != "global" {
+		t.Errorf("global variable gu_e not found")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	f := parseFile(t, "u_net_http_response_buffered.sru")
+	fn := f.Function("of_receive")
+	if fn == nil || fn.Throws != "u_exf_ex" {
+		t.Fatalf("wrong function of_receive: %+v", fn)
+	}
+	src := f.Replace(fn.Body, "\r\nreturn blob('')\r\n")
+	f, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Text(f.Function("of_receive").Body); got != "\r\nreturn blob('')\r\n" {
+		t.Errorf("body was not replaced: %q", got)
+	}
+	if f.Function("of_get_pbni_object") == nil || f.Event("", "constructor") == nil {
+		t.Errorf("following functions and events are missing after replacement")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"public function long of_x ();\r\nreturn 1\r\n",
+		"forward\r\nglobal type w_x from window\r\nend type\r\n",
+		"event open;\r\nend function\r\n",
+	}
+	for _, src := range tests {
+		_, err := Parse(src)
+		if err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package profile describes how the applications of a product are migrated and backported: which fixes run
+// after the migration, how the libraries are ordered, which projects are moved and which workspace file is
+// replaced.
+//
+// The profiles a3, lohn and generic are built in. Custom profiles are read from a JSON file:
+//
+//	{
+//	  "profiles": [
+//	    {
+//	      "name": "xyz",
+//	      "apps": ["xyz"],
+//	      "fixes": ["runtime-folder", "pbdom-build-options", "pb-init", "datawindows"],
+//	      "libraryOrder": [["base"], ["xyz"]]
+//	    }
+//	  ]
+//	}
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Names of the fixes applied after the migration (see upgrade)
+const (
+	FixRegistry           = "registry"             // migrate.FixRegistry
+	FixRuntimeFolder      = "runtime-folder"       // migrate.FixRuntimeFolder
+	FixPbdomBuildOptions  = "pbdom-build-options"  // migrate.ChangePbdomBuildOptions for every project
+	FixLifProcess         = "lif-process"          // migrate.FixLifProcess
+	FixHttpClient         = "http-client"          // migrate.FixHttpClient
+	FixMirrorObjects      = "mirror-objects"       // migrate.AddMirrorObjects
+	FixUncommonFiles      = "uncommon-files"       // warn about files in the pbdk folder (migrate.CheckForUncommonFiles)
+	FixRemoveFiles        = "remove-files"         // migrate.RemoveFiles
+	FixPbdk               = "pbdk"                 // migrate.InsertNewPbdk
+	FixPayrollXmlDecl     = "payroll-xml-decl"     // migrate.FixPayrollXmlDecl
+	FixPayrollXmlEncoding = "payroll-xml-encoding" // migrate.FixPayrollXmlEncoding
+	FixArf                = "arf"                  // migrate.FixArf
+	FixPbInit             = "pb-init"              // migrate.FixPbInit
+	FixPbw                = "pbw"                  // replace the workspace file (see Profile.PbwFile)
+	FixDatawindows        = "datawindows"          // migrate.FixDatawindows with all DataWindow fixes
+)
+
+// Fixes are all known fixes.
+var Fixes = []string{
+	FixRegistry, FixRuntimeFolder, FixPbdomBuildOptions, FixLifProcess, FixHttpClient, FixMirrorObjects,
+	FixUncommonFiles, FixRemoveFiles, FixPbdk, FixPayrollXmlDecl, FixPayrollXmlEncoding, FixArf, FixPbInit, FixPbw,
+	FixDatawindows,
+}
+
+// Profile defines the product specific parts of the migration of an application.
+type Profile struct {
+	Name string   `json:"name"`
+	Apps []string `json:"apps,omitempty"` // application names the profile is used for if no profile is given
+
+	// Fixes are applied after the migration in the given order.
+	Fixes []string `json:"fixes,omitempty"`
+	// RequiredProjects are the projects whose build options must be fixed, failures of other projects are ignored.
+	RequiredProjects []string `json:"requiredProjects,omitempty"`
+	// ProjectMoves move projects to another library if their project object does not exist in the library.
+	ProjectMoves []ProjectMove `json:"projectMoves,omitempty"`
+	// PbwFile is the workspace file (relative to the folder of the target) replaced by the fix pbw.
+	PbwFile string `json:"pbwFile,omitempty"`
+	// PbwTemplate is the file the workspace is replaced with. If empty, the file embedded in pbmanager with the
+	// name of PbwFile is used.
+	PbwTemplate string `json:"pbwTemplate,omitempty"`
+	// LibraryOrder lists the library base names (e.g. inf for inf1.pbl) in groups. Libraries of later groups are
+	// placed behind libraries of earlier groups in the library list, unlisted libraries are placed first.
+	LibraryOrder [][]string `json:"libraryOrder,omitempty"`
+}
+
+// ProjectMove moves a project to another library.
+type ProjectMove struct {
+	Project string `json:"project"`
+	From    string `json:"from"` // e.g. inf2.pbl
+	To      string `json:"to"`   // e.g. inf1.pbl
+}
+
+// a3LibraryOrder is the ordering of the "Packages und Verantwortlichkeiten" of a3 and lohn.
+var a3LibraryOrder = [][]string{
+	{
+		"avd", "bbp", "bst", "con", "das", "dbe", "dka", "dma", "dmi", "dta", "dto", "dwh", "dws",
+		"egm", "kas", "kat", "kng", "mie", "pro", "reg", "sdi", "ser", "sfm", "wae", "wgb", "wss",
+	},
+	{
+		"adr", "arc", "art", "bde", "biz", "dgm", "dis", "drucken", "dzb", "ecp", "ein", "ger",
+		"kon", "lag", "lda", "map", "mit", "obj", "ord", "pos", "prj", "rap", "res", "sal",
+	},
+	{"anl", "deb", "fib", "fin", "fre", "kor", "kre", "mai", "mve", "tbs", "zea", "zei", "zek", "zes"},
+	{"cfg_lohn", "elm", "elmg", "elmp", "elx", "loh", "lor", "spe", "stm_lohn"},
+	{"bai", "kim"},
+	{"sfi"},
+	{"dss", "eft", "exf", "fsu", "grp", "liq", "net", "osu", "str", "szn"},
+	{"arf", "cfg", "inf", "kal", "nfy", "pbdom", "sti", "stm", "tse"},
+	{"jif"},
+	{"lif"},
+}
+
+// builtins are the profiles which are always available.
+var builtins = []*Profile{
+	{
+		Name: "a3",
+		Apps: []string{"a3"},
+		Fixes: []string{
+			FixRegistry, FixRuntimeFolder, FixPbdomBuildOptions, FixLifProcess, FixHttpClient, FixMirrorObjects,
+			FixUncommonFiles, FixRemoveFiles, FixPbdk, FixArf, FixPbInit, FixDatawindows,
+		},
+		RequiredProjects: []string{"a3", "loh"},
+		ProjectMoves:     []ProjectMove{{Project: "a3", From: "inf2.pbl", To: "inf1.pbl"}},
+		LibraryOrder:     a3LibraryOrder,
+	},
+	{
+		Name: "lohn",
+		Apps: []string{"loh"},
+		Fixes: []string{
+			FixRuntimeFolder, FixPbdomBuildOptions, FixLifProcess, FixHttpClient, FixMirrorObjects,
+			FixUncommonFiles, FixRemoveFiles, FixPbdk, FixPayrollXmlDecl, FixPayrollXmlEncoding, FixArf, FixPbInit,
+			FixPbw, FixDatawindows,
+		},
+		RequiredProjects: []string{"a3", "loh"},
+		ProjectMoves:     []ProjectMove{{Project: "a3", From: "inf2.pbl", To: "inf1.pbl"}},
+		PbwFile:          "a3_lohn.pbw",
+		LibraryOrder:     a3LibraryOrder,
+	},
+	{
+		// applications without a profile of their own are backported with the ordering of a3
+		Name:         "generic",
+		LibraryOrder: a3LibraryOrder,
+	},
+}
+
+// Builtin returns the built-in profile with the given name.
+func Builtin(name string) (*Profile, error) {
+	return (&Set{profiles: builtins}).Get(name)
+}
+
+// Layer returns the index of the group of the library order containing the library, -1 if the library is not
+// listed. Folders, the extension (.pbl, .pbd, .pbl.src) and the number of the library are ignored, e.g. the
+// library C:/a3/lib/inf1.pbl is listed as inf.
+func Layer(libraryOrder [][]string, library string) int {
+	name := strings.ToLower(library)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, ".src")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".pbl"), ".pbd")
+	name = strings.TrimRight(name, "123")
+	for i, group := range libraryOrder {
+		if slices.ContainsFunc(group, func(s string) bool { return strings.EqualFold(s, name) }) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set is a list of profiles, the built-in ones and the custom ones of a profile file.
+type Set struct {
+	profiles []*Profile
+}
+
+// file is the format of a profile file.
+type file struct {
+	Profiles []*Profile `json:"profiles"`
+}
+
+// Load returns the built-in profiles and the profiles of the file. Custom profiles replace built-in profiles
+// with the same name. If file is empty, only the built-in profiles are returned.
+func Load(filePath string) (*Set, error) {
+	s := &Set{profiles: slices.Clone(builtins)}
+	if filePath == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read profile file: %v", err)
+	}
+	var f file
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse profile file %s: %v", filePath, err)
+	}
+	for _, p := range f.Profiles {
+		err = p.validate()
+		if err != nil {
+			return nil, fmt.Errorf("profile file %s: %v", filePath, err)
+		}
+		s.profiles = slices.DeleteFunc(s.profiles, func(b *Profile) bool { return strings.EqualFold(b.Name, p.Name) })
+		s.profiles = append(s.profiles, p)
+	}
+	return s, nil
+}
+
+func (p *Profile) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("profile without name")
+	}
+	for _, fix := range p.Fixes {
+		if !slices.Contains(Fixes, fix) {
+			return fmt.Errorf("profile %s: unknown fix %s, use one of %s", p.Name, fix, strings.Join(Fixes, ", "))
+		}
+	}
+	if slices.Contains(p.Fixes, FixPbw) && p.PbwFile == "" {
+		return fmt.Errorf("profile %s: fix %s needs a pbwFile", p.Name, FixPbw)
+	}
+	for _, m := range p.ProjectMoves {
+		if m.Project == "" || m.From == "" || m.To == "" {
+			return fmt.Errorf("profile %s: project moves need project, from and to", p.Name)
+		}
+	}
+	return nil
+}
+
+// All returns the profiles of the set.
+func (s *Set) All() []*Profile {
+	return s.profiles
+}
+
+// Get returns the profile with the given name.
+func (s *Set) Get(name string) (*Profile, error) {
+	for _, p := range s.profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	var names []string
+	for _, p := range s.profiles {
+		names = append(names, p.Name)
+	}
+	return nil, fmt.Errorf("unknown profile %s, use one of %s", name, strings.Join(names, ", "))
+}
+
+// ForApp returns the profile used for an application, the generic profile if no profile lists the application.
+// Custom profiles take precedence over the built-in ones.
+func (s *Set) ForApp(appName string) *Profile {
+	for i := len(s.profiles) - 1; i >= 0; i-- {
+		if slices.ContainsFunc(s.profiles[i].Apps, func(a string) bool { return strings.EqualFold(a, appName) }) {
+			return s.profiles[i]
+		}
+	}
+	p, _ := s.Get("generic")
+	if p == nil {
+		p = &Profile{Name: "generic"}
+	}
+	return p
+}
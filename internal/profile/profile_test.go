@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := Load("testdata/profiles.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		app   string
+		want  string
+		fixes int
+	}{
+		{"a3", "a3", 12},
+		{"loh", "lohn", 14},
+		{"XYZ_TEST", "xyz", 3},
+		{"other", "generic", 1}, // replaced by the profile file
+	}
+	for _, tc := range testCases {
+		p := s.ForApp(tc.app)
+		if p.Name != tc.want || len(p.Fixes) != tc.fixes {
+			t.Errorf("ForApp(%q) = %s with %d fixes, want %s with %d fixes", tc.app, p.Name, len(p.Fixes), tc.want, tc.fixes)
+		}
+	}
+	if len(s.All()) != 4 {
+		t.Errorf("expected 4 profiles, got %d", len(s.All()))
+	}
+	if _, err := s.Get("abc"); err == nil || !strings.Contains(err.Error(), "a3, lohn, xyz, generic") {
+		t.Errorf("unexpected error for unknown profile: %v", err)
+	}
+
+	// the built-in profiles are not changed by the profile file
+	b, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := b.ForApp("other"); p.Name != "generic" || len(p.Fixes) != 0 {
+		t.Errorf("built-in generic profile has been changed: %v", p.Fixes)
+	}
+	if p := b.ForApp("other"); len(p.LibraryOrder) == 0 {
+		t.Errorf("built-in generic profile has no library order")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := Load("testdata/invalid.json")
+	if err == nil || !strings.Contains(err.Error(), "unknown fix coffee") {
+		t.Errorf("expected error for unknown fix, got %v", err)
+	}
+}
+
+func TestBuiltinFixes(t *testing.T) {
+	for _, p := range builtins {
+		if err := p.validate(); err != nil {
+			t.Errorf("built-in profile %s is invalid: %v", p.Name, err)
+		}
+	}
+	a3, _ := Builtin("a3")
+	lohn, _ := Builtin("lohn")
+	if !slices.Contains(a3.Fixes, FixRegistry) || slices.Contains(lohn.Fixes, FixRegistry) {
+		t.Errorf("only a3 has a registry object")
+	}
+}
+
+func TestLayer(t *testing.T) {
+	order := [][]string{{"app", "adr"}, {"inf"}, {"lif"}}
+	testCases := []struct {
+		library string
+		want    int
+	}{
+		{"app1.pbl", 0},
+		{`C:\a3\lib\INF1.PBL`, 1},
+		{"inf3.pbl.src", 1},
+		{"lif1.pbd", 2},
+		{"lif", 2},
+		{"xyz1.pbl", -1},
+	}
+	for _, tc := range testCases {
+		if got := Layer(order, tc.library); got != tc.want {
+			t.Errorf("Layer(%q) = %d, want %d", tc.library, got, tc.want)
+		}
+	}
+}
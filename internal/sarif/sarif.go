@@ -0,0 +1,135 @@
+// Package sarif writes results of checks in the Static Analysis Results Interchange Format (SARIF) 2.1.0, so CI
+// systems (e.g. GitHub code scanning or GitLab) can show them as annotations.
+//
+// Only the parts of the format needed by pbmanager are implemented.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	version = "2.1.0"
+)
+
+// Levels of a result
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Log is a SARIF file with a single run.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*Run `json:"runs"`
+}
+
+// Run is the result of one tool.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes the tool and its rules.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the tool itself.
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+// Rule is a check of the tool.
+type Rule struct {
+	ID               string  `json:"id"`
+	ShortDescription Message `json:"shortDescription"`
+}
+
+// Message is a text.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is a single finding.
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+// Location is the place of a finding.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a position in a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file.
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a position within a file.
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+// New returns a log for the tool with the given rules.
+func New(toolName, toolVersion string, rules ...Rule) *Log {
+	return &Log{
+		Schema:  schema,
+		Version: version,
+		Runs: []*Run{{
+			Tool:    Tool{Driver: Driver{Name: toolName, Version: toolVersion, Rules: rules}},
+			Results: []Result{},
+		}},
+	}
+}
+
+// Add adds a result. file is a path (relative paths are kept relative, so they are resolved against the root of
+// the repository by the CI), line is 0 if unknown.
+func (l *Log) Add(ruleID, level, message, file string, line int) {
+	r := Result{RuleID: ruleID, Level: level, Message: Message{Text: message}}
+	if file != "" {
+		loc := PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: URI(file)}}
+		if line > 0 {
+			loc.Region = &Region{StartLine: line}
+		}
+		r.Locations = []Location{{PhysicalLocation: loc}}
+	}
+	l.Runs[0].Results = append(l.Runs[0].Results, r)
+}
+
+// Write writes the log as indented JSON.
+func (l *Log) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+// URI returns the uri of a file path, absolute Windows paths like C:\a3\lib become file:///C:/a3/lib.
+func URI(path string) string {
+	uri := strings.ReplaceAll(filepath.ToSlash(path), `\`, "/")
+	if len(uri) >= 2 && uri[1] == ':' {
+		return "file:///" + uri
+	}
+	if strings.HasPrefix(uri, "/") {
+		return "file://" + uri
+	}
+	return uri
+}
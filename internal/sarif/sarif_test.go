@@ -0,0 +1,52 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	l := New("pbmanager", "1.0.0", Rule{ID: "layer-violation", ShortDescription: Message{Text: "reference to a higher layer"}})
+	l.Add("layer-violation", LevelError, "u_a references u_b", `lif1.pbl\u_a.sru`, 17)
+	l.Add("layer-violation", LevelWarning, "no location", "", 0)
+
+	var buf bytes.Buffer
+	if err := l.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["version"] != "2.1.0" {
+		t.Errorf("unexpected version %v", got["version"])
+	}
+	results := got["runs"].([]any)[0].(map[string]any)["results"].([]any)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	loc := results[0].(map[string]any)["locations"].([]any)[0].(map[string]any)["physicalLocation"].(map[string]any)
+	if uri := loc["artifactLocation"].(map[string]any)["uri"]; uri != "lif1.pbl/u_a.sru" {
+		t.Errorf("unexpected uri %v", uri)
+	}
+	if line := loc["region"].(map[string]any)["startLine"]; line != float64(17) {
+		t.Errorf("unexpected line %v", line)
+	}
+	if _, ok := results[1].(map[string]any)["locations"]; ok {
+		t.Errorf("result without file must not have a location")
+	}
+}
+
+func TestURI(t *testing.T) {
+	testCases := map[string]string{
+		`C:\a3\lib\inf1.pbl`: "file:///C:/a3/lib/inf1.pbl",
+		"/tmp/a.sru":         "file:///tmp/a.sru",
+		`src\a.sru`:          "src/a.sru",
+	}
+	for path, want := range testCases {
+		if got := URI(path); got != want {
+			t.Errorf("URI(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Package snapshot saves the files of a workspace to a zip file before they are modified and restores them.
+//
+// A snapshot contains a manifest (manifest.json) listing every saved path. Files are stored with their content,
+// directories are stored with the list of their files, so files created after the snapshot are removed by
+// Restore. Paths which did not exist when the snapshot was taken are removed by Restore as well.
+package snapshot
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirName is the folder (relative to the workspace) where the snapshots are stored by default.
+const DirName = ".pbmanager/snapshots"
+
+const manifestName = "manifest.json"
+
+// Kinds of manifest entries
+const (
+	KindFile    = "file"
+	KindDir     = "dir"     // directory including all subdirectories
+	KindFlatDir = "flatdir" // directory without subdirectories
+	KindMissing = "missing" // path which did not exist
+)
+
+// skipDirs are never saved nor cleaned up by Restore.
+var skipDirs = map[string]bool{".git": true, ".svn": true, ".pbmanager": true}
+
+// Path is a file or directory to save.
+type Path struct {
+	Name string
+	Flat bool // only the files directly in the directory, not its subdirectories
+}
+
+// Manifest describes the content of a snapshot.
+type Manifest struct {
+	Command string    `json:"command"`
+	Created time.Time `json:"created"`
+	Entries []Entry   `json:"entries"`
+
+	file string // path of the snapshot
+}
+
+// Entry is a saved path.
+type Entry struct {
+	Path    string      `json:"path"`
+	Kind    string      `json:"kind"`
+	Data    string      `json:"data,omitempty"` // name of the zip entry holding the content of a file
+	Mode    fs.FileMode `json:"mode,omitempty"`
+	ModTime time.Time   `json:"modTime,omitempty"`
+}
+
+// File returns the path of the snapshot.
+func (m *Manifest) File() string {
+	return m.file
+}
+
+// Create saves the paths to a new snapshot in dir and returns its manifest. command describes the command which
+// is about to modify the files, it is part of the file name.
+func Create(dir, command string, paths []Path) (*Manifest, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create snapshot folder: %v", err)
+	}
+	m := &Manifest{Command: command, Created: time.Now()}
+	m.file = filepath.Join(dir, fmt.Sprintf("%s-%s.zip", m.Created.Format("20060102-150405.000"), sanitize(command)))
+
+	f, err := os.Create(m.file)
+	if err != nil {
+		return nil, fmt.Errorf("could not create snapshot: %v", err)
+	}
+	w := &writer{zip: zip.NewWriter(f), manifest: m, seen: make(map[string]bool)}
+	for _, p := range paths {
+		err = w.add(p)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.writeManifest()
+	}
+	if err == nil {
+		err = w.zip.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(m.file)
+		return nil, fmt.Errorf("could not create snapshot: %v", err)
+	}
+	return m, nil
+}
+
+type writer struct {
+	zip      *zip.Writer
+	manifest *Manifest
+	seen     map[string]bool
+}
+
+func (w *writer) add(p Path) error {
+	info, err := os.Stat(p.Name)
+	if os.IsNotExist(err) {
+		w.addEntry(Entry{Path: p.Name, Kind: KindMissing})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return w.addFile(p.Name, info)
+	}
+	if p.Flat {
+		w.addEntry(Entry{Path: p.Name, Kind: KindFlatDir, Mode: info.Mode()})
+		entries, err := os.ReadDir(p.Name)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			err = w.addFile(filepath.Join(p.Name, e.Name()), info)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return filepath.Walk(p.Name, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if skipDirs[info.Name()] && path != p.Name {
+				return filepath.SkipDir
+			}
+			w.addEntry(Entry{Path: path, Kind: KindDir, Mode: info.Mode()})
+			return nil
+		}
+		return w.addFile(path, info)
+	})
+}
+
+func (w *writer) addEntry(e Entry) bool {
+	key := strings.ToLower(filepath.Clean(e.Path)) + "\x00" + e.Kind
+	if e.Kind == KindFile || e.Kind == KindMissing {
+		key = strings.ToLower(filepath.Clean(e.Path))
+	}
+	if w.seen[key] {
+		return false
+	}
+	w.seen[key] = true
+	w.manifest.Entries = append(w.manifest.Entries, e)
+	return true
+}
+
+func (w *writer) addFile(path string, info fs.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	e := Entry{Path: path, Kind: KindFile, Mode: info.Mode(), ModTime: info.ModTime(),
+		Data: fmt.Sprintf("files/%06d", len(w.manifest.Entries))}
+	if !w.addEntry(e) {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := w.zip.CreateHeader(&zip.FileHeader{Name: e.Data, Method: zip.Deflate, Modified: e.ModTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func (w *writer) writeManifest() error {
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	dst, err := w.zip.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(data)
+	return err
+}
+
+// Open reads the manifest of a snapshot.
+func Open(file string) (*Manifest, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open snapshot %s: %v", file, err)
+	}
+	defer r.Close()
+	return readManifest(&r.Reader, file)
+}
+
+func readManifest(r *zip.Reader, file string) (*Manifest, error) {
+	f, err := r.Open(manifestName)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a snapshot: %v", file, err)
+	}
+	defer f.Close()
+	m := &Manifest{file: file}
+	err = json.NewDecoder(f).Decode(m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %v", file, err)
+	}
+	return m, nil
+}
+
+// Restore resets all paths saved in the snapshot to the state of the snapshot. printFunc is called for every
+// changed path.
+func Restore(file string, printFunc func(string)) error {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("could not open snapshot %s: %v", file, err)
+	}
+	defer r.Close()
+	m, err := readManifest(&r.Reader, file)
+	if err != nil {
+		return err
+	}
+
+	// everything which is part of the snapshot, files and directories which are not, have been created later
+	known := make(map[string]bool)
+	for _, e := range m.Entries {
+		known[strings.ToLower(filepath.Clean(e.Path))] = true
+	}
+	for _, e := range m.Entries {
+		switch e.Kind {
+		case KindDir, KindFlatDir:
+			err = removeUnknown(e.Path, e.Kind == KindFlatDir, known, printFunc)
+		case KindMissing:
+			if _, serr := os.Lstat(e.Path); serr == nil {
+				printFunc(fmt.Sprintf("remove %s", e.Path))
+				err = os.RemoveAll(e.Path)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("restore failed: %v", err)
+		}
+	}
+
+	for _, e := range m.Entries {
+		switch e.Kind {
+		case KindDir, KindFlatDir:
+			err = os.MkdirAll(e.Path, 0o755)
+		case KindFile:
+			err = restoreFile(&r.Reader, e, printFunc)
+		}
+		if err != nil {
+			return fmt.Errorf("restore failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeUnknown deletes the files (and subdirectories) in dir which are not part of the snapshot.
+func removeUnknown(dir string, flat bool, known map[string]bool, printFunc func(string)) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() && (flat || skipDirs[e.Name()]) {
+			continue
+		}
+		if known[strings.ToLower(path)] {
+			// known subdirectories have an entry of their own
+			continue
+		}
+		printFunc(fmt.Sprintf("remove %s", path))
+		err = os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreFile(r *zip.Reader, e Entry, printFunc func(string)) error {
+	src, err := r.Open(e.Data)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(e.Path); err == nil && string(old) == string(data) {
+		return nil
+	}
+	printFunc(fmt.Sprintf("restore %s", e.Path))
+	err = os.MkdirAll(filepath.Dir(e.Path), 0o755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(e.Path, data, e.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(e.Path, e.ModTime, e.ModTime)
+}
+
+// List returns the manifests of the snapshots in dir, the newest first.
+func List(dir string) ([]*Manifest, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.zip"))
+	if err != nil {
+		return nil, err
+	}
+	var manifests []*Manifest
+	for _, file := range files {
+		m, err := Open(file)
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Created.After(manifests[j].Created)
+	})
+	return manifests, nil
+}
+
+// Prune deletes all but the newest keep snapshots in dir and returns the deleted files. keep <= 0 keeps all.
+func Prune(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for i := keep; i < len(manifests); i++ {
+		err = os.Remove(manifests[i].file)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, manifests[i].file)
+	}
+	return deleted, nil
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, s)
+}
@@ -0,0 +1,113 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ws := t.TempDir()
+	lib := filepath.Join(ws, "lib")
+	pbdk := filepath.Join(lib, "pbdk")
+	writeFiles(t, map[string]string{
+		filepath.Join(lib, "a3.pbt"):       "pbt",
+		filepath.Join(lib, "inf1.pbl"):     "inf1",
+		filepath.Join(lib, "pb.ini"):       "ini",
+		filepath.Join(lib, "sub", "x.txt"): "not saved",
+		filepath.Join(pbdk, "pbvm.dll"):    "vm",
+		filepath.Join(pbdk, "x", "y.dll"):  "y",
+	})
+	created := filepath.Join(ws, "ws_objects")
+
+	m, err := Create(filepath.Join(ws, DirName), "upgrade", []Path{
+		{Name: lib, Flat: true},
+		{Name: pbdk},
+		{Name: created},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// modify the workspace
+	writeFiles(t, map[string]string{
+		filepath.Join(lib, "inf1.pbl"):     "migrated",
+		filepath.Join(lib, "exf1.pbl"):     "new",
+		filepath.Join(lib, "sub", "x.txt"): "changed, but not saved",
+		filepath.Join(pbdk, "new.dll"):     "new",
+		filepath.Join(created, "a.sru"):    "new",
+	})
+	os.Remove(filepath.Join(lib, "pb.ini"))
+	os.RemoveAll(filepath.Join(pbdk, "x"))
+
+	var changes []string
+	err = Restore(m.File(), func(s string) { changes = append(changes, s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := map[string]string{
+		filepath.Join(lib, "a3.pbt"):       "pbt",
+		filepath.Join(lib, "inf1.pbl"):     "inf1",
+		filepath.Join(lib, "pb.ini"):       "ini",
+		filepath.Join(lib, "sub", "x.txt"): "changed, but not saved",
+		filepath.Join(pbdk, "pbvm.dll"):    "vm",
+		filepath.Join(pbdk, "x", "y.dll"):  "y",
+	}
+	for path, want := range wants {
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Errorf("%s: got %q (%v), want %q", path, got, err, want)
+		}
+	}
+	for _, path := range []string{filepath.Join(lib, "exf1.pbl"), filepath.Join(pbdk, "new.dll"), created} {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("%s has not been removed", path)
+		}
+	}
+	if len(changes) != 6 {
+		t.Errorf("expected 6 changes, got %v", changes)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, DirName)
+	var files []string
+	for i := 0; i < 4; i++ {
+		m, err := Create(dir, "delete", []Path{{Name: filepath.Join(ws, "a.pbl")}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, m.File())
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	deleted, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 || deleted[0] != files[1] || deleted[1] != files[0] {
+		t.Errorf("unexpected deleted snapshots %v", deleted)
+	}
+	manifests, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 2 || manifests[0].File() != files[3] || manifests[0].Command != "delete" {
+		t.Errorf("unexpected snapshots after prune: %d", len(manifests))
+	}
+}
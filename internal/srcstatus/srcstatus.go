@@ -0,0 +1,102 @@
+// Package srcstatus compares the objects of libraries with the source files exported by export, so changes
+// made in the IDE without exporting the objects again can be detected.
+package srcstatus
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Status of an object
+const (
+	Modified  = "modified"
+	OnlyInPbl = "only-in-pbl"
+	OnlyInSrc = "only-in-src"
+)
+
+// Change is an object which differs between the library and the source folder.
+type Change struct {
+	Library string `json:"library"` // file name of the library, e.g. inf1.pbl
+	Object  string `json:"object"`  // entry name, e.g. u_base.sru
+	Status  string `json:"status"`
+	Path    string `json:"path,omitempty"` // source file, empty for objects only in the library
+}
+
+// regexSrcExt matches the extensions of source files, see pborca.GetObjSuffixFromType.
+var regexSrcExt = regexp.MustCompile(`(?i)^\.sr[adfjmpqsuwx]$`)
+
+// Normalize converts an exported source to UTF-8 without BOM with LF line endings. Sources without BOM which
+// are not valid UTF-8 are read as Windows-1252 (see export --output-encoding cp1252).
+func Normalize(data []byte) (string, error) {
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte("\xFF\xFE")):
+		data, _, err = transform.Bytes(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder(), data)
+	case bytes.HasPrefix(data, []byte("\xFE\xFF")):
+		data, _, err = transform.Bytes(unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder(), data)
+	case !utf8.Valid(data):
+		data, _, err = transform.Bytes(charmap.Windows1252.NewDecoder(), data)
+	}
+	if err != nil {
+		return "", err
+	}
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	return strings.TrimRight(s, "\n"), nil
+}
+
+// Compare compares the sources of a library (keyed by the entry name, see pbl.Library.Source) with the source
+// files in srcDir. A missing srcDir is treated like an empty folder. The changes are sorted by object name.
+func Compare(library string, pblSources map[string]string, srcDir string) ([]Change, error) {
+	srcFiles := make(map[string]string) // lower case entry name -> path
+	entries, err := os.ReadDir(srcDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not read source folder %s: %v", srcDir, err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !regexSrcExt.MatchString(filepath.Ext(e.Name())) {
+			continue
+		}
+		srcFiles[strings.ToLower(e.Name())] = filepath.Join(srcDir, e.Name())
+	}
+
+	var changes []Change
+	for name, src := range pblSources {
+		path, ok := srcFiles[strings.ToLower(name)]
+		if !ok {
+			changes = append(changes, Change{Library: library, Object: name, Status: OnlyInPbl})
+			continue
+		}
+		delete(srcFiles, strings.ToLower(name))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		exported, err := Normalize(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s: %v", path, err)
+		}
+		current, _ := Normalize([]byte(src))
+		if exported != current {
+			changes = append(changes, Change{Library: library, Object: name, Status: Modified, Path: path})
+		}
+	}
+	for _, path := range srcFiles {
+		changes = append(changes, Change{Library: library, Object: filepath.Base(path), Status: OnlyInSrc, Path: path})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return strings.ToLower(changes[i].Object) < strings.ToLower(changes[j].Object)
+	})
+	return changes, nil
+}
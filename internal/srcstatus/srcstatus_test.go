@@ -0,0 +1,79 @@
+package srcstatus
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestNormalize(t *testing.T) {
+	want := "$PBExportHeader$u_ä.sru\nglobal type u_ä from nonvisualobject"
+	src := "$PBExportHeader$u_ä.sru\r\nglobal type u_ä from nonvisualobject\r\n"
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(src)
+	cp1252, _ := charmap.Windows1252.NewEncoder().String(src)
+	testCases := map[string]string{
+		"utf8":    src,
+		"utf8bom": "\xEF\xBB\xBF" + src,
+		"utf16":   utf16,
+		"cp1252":  cp1252,
+		"lf":      "$PBExportHeader$u_ä.sru\nglobal type u_ä from nonvisualobject\n",
+	}
+	for name, data := range testCases {
+		got, err := Normalize([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "inf1.pbl")
+	if err := os.MkdirAll(srcDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"u_same.sru":    "\xEF\xBB\xBF$PBExportHeader$u_same.sru\nsame\n",
+		"u_changed.sru": "$PBExportHeader$u_changed.sru\r\nold\r\n",
+		"u_deleted.sru": "$PBExportHeader$u_deleted.sru\r\n",
+		"readme.txt":    "not a source",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pblSources := map[string]string{
+		"u_same.sru":    "$PBExportHeader$u_same.sru\r\nsame\r\n",
+		"u_changed.sru": "$PBExportHeader$u_changed.sru\r\nnew\r\n",
+		"u_new.sru":     "$PBExportHeader$u_new.sru\r\n",
+	}
+
+	got, err := Compare("inf1.pbl", pblSources, srcDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Library: "inf1.pbl", Object: "u_changed.sru", Status: Modified, Path: filepath.Join(srcDir, "u_changed.sru")},
+		{Library: "inf1.pbl", Object: "u_deleted.sru", Status: OnlyInSrc, Path: filepath.Join(srcDir, "u_deleted.sru")},
+		{Library: "inf1.pbl", Object: "u_new.sru", Status: OnlyInPbl},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare()\n got %+v\nwant %+v", got, want)
+	}
+
+	got, err = Compare("inf1.pbl", pblSources, filepath.Join(srcDir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Status != OnlyInPbl {
+		t.Errorf("expected all objects only in the pbl for a missing folder, got %+v", got)
+	}
+}
@@ -0,0 +1,197 @@
+// Package srcsync synchronises the objects of libraries with a folder of exported sources in both directions.
+//
+// The state file records the hashes of the library object and of the source file of every object after the
+// last synchronisation. An object which changed on one side only is copied to the other side, an object which
+// changed on both sides (to different sources) is a conflict and is left alone.
+package srcsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Actions of a step
+const (
+	Unchanged = "unchanged"  // both sides are equal
+	Export    = "export"     // changed in the library, the source file is written
+	Import    = "import"     // changed in the source folder, the object is imported
+	DeleteSrc = "delete-src" // deleted from the library, the source file is deleted
+	DeletePbl = "delete-pbl" // source file deleted, the object is deleted from the library
+	Conflict  = "conflict"   // changed on both sides
+)
+
+// Version is the state of an object on one side.
+type Version struct {
+	Name string // library/entry, e.g. inf1.pbl/u_base.sru
+	Hash string // see Hash
+}
+
+// Step is the action needed to synchronise an object.
+type Step struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"` // for conflicts
+}
+
+// Baseline are the hashes of both sides after the last synchronisation.
+type Baseline struct {
+	Pbl string `json:"pbl"`
+	Src string `json:"src"`
+}
+
+// State is the state file of a source folder.
+type State struct {
+	Objects map[string]Baseline `json:"objects"`
+
+	path string
+}
+
+// StatePath returns the path of the state file of a source folder, e.g. C:/a3/lib/src.sync.json for
+// C:/a3/lib/src.
+func StatePath(srcDir string) string {
+	srcDir = filepath.Clean(srcDir)
+	return filepath.Join(filepath.Dir(srcDir), filepath.Base(srcDir)+".sync.json")
+}
+
+// Key returns the key of an object in the state, the lower case library/entry.
+func Key(library, entry string) string {
+	return strings.ToLower(library + "/" + entry)
+}
+
+// Hash returns the hash of a normalized source (see srcstatus.Normalize).
+func Hash(src string) string {
+	h := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(h[:])
+}
+
+// LoadState reads a state file. If it does not exist, an empty state is returned (first synchronisation).
+func LoadState(path string) (*State, error) {
+	s := &State{Objects: make(map[string]Baseline), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read sync state: %v", err)
+	}
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sync state %s: %v", path, err)
+	}
+	if s.Objects == nil {
+		s.Objects = make(map[string]Baseline)
+	}
+	return s, nil
+}
+
+// Save writes the state file.
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(s.path, data, 0o664)
+	if err != nil {
+		return fmt.Errorf("could not write sync state: %v", err)
+	}
+	return nil
+}
+
+// Path returns the path of the state file.
+func (s *State) Path() string {
+	return s.path
+}
+
+// Record stores the hashes of an object after a step, empty hashes remove the object from the state.
+func (s *State) Record(key, pblHash, srcHash string) {
+	if pblHash == "" && srcHash == "" {
+		delete(s.Objects, key)
+		return
+	}
+	s.Objects[key] = Baseline{Pbl: pblHash, Src: srcHash}
+}
+
+// Plan returns the step for every object of the libraries (pbl) and the source folder (src), both keyed by Key.
+// The steps are sorted by name.
+func (s *State) Plan(pbl, src map[string]Version) []Step {
+	keys := make(map[string]bool)
+	for k := range pbl {
+		keys[k] = true
+	}
+	for k := range src {
+		keys[k] = true
+	}
+	for k := range s.Objects {
+		keys[k] = true
+	}
+
+	var steps []Step
+	for key := range keys {
+		p, inPbl := pbl[key]
+		f, inSrc := src[key]
+		base, known := s.Objects[key]
+		step := Step{Key: key, Name: p.Name}
+		if !inPbl {
+			step.Name = f.Name
+		}
+		switch {
+		case !inPbl && !inSrc:
+			// deleted on both sides, only the state is cleaned up
+			step.Name = key
+			step.Action = Unchanged
+		case inPbl && inSrc && p.Hash == f.Hash:
+			step.Action = Unchanged
+		case !known:
+			switch {
+			case !inSrc:
+				step.Action = Export
+			case !inPbl:
+				step.Action = Import
+			default:
+				step.Action = Conflict
+				step.Reason = "exists in both with different sources and was never synchronised"
+			}
+		default:
+			pblChanged := !inPbl || p.Hash != base.Pbl
+			srcChanged := !inSrc || f.Hash != base.Src
+			switch {
+			case pblChanged && srcChanged:
+				step.Action = Conflict
+				step.Reason = conflictReason(inPbl, inSrc)
+			case pblChanged && inPbl:
+				step.Action = Export
+			case pblChanged:
+				step.Action = DeleteSrc
+			case srcChanged && inSrc:
+				step.Action = Import
+			case srcChanged:
+				step.Action = DeletePbl
+			default:
+				// unchanged since the last synchronisation, but the sources differ (e.g. reformatted by ORCA)
+				step.Action = Unchanged
+			}
+		}
+		steps = append(steps, step)
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].Key < steps[j].Key
+	})
+	return steps
+}
+
+func conflictReason(inPbl, inSrc bool) string {
+	switch {
+	case !inPbl:
+		return "deleted in the library and changed in the source folder"
+	case !inSrc:
+		return "changed in the library and deleted in the source folder"
+	}
+	return "changed in the library and in the source folder"
+}
@@ -0,0 +1,92 @@
+package srcsync
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	state, err := LoadState(filepath.Join(t.TempDir(), "src.sync.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"same", "pbl_changed", "src_changed", "both_changed", "pbl_deleted", "src_deleted", "both_deleted", "formatted"} {
+		state.Record(Key("inf1.pbl", name+".sru"), "p_"+name, "s_"+name)
+	}
+
+	v := func(name, hash string) Version {
+		return Version{Name: "inf1.pbl/" + name + ".sru", Hash: hash}
+	}
+	pbl := map[string]Version{
+		"inf1.pbl/same.sru":         v("same", "x"),
+		"inf1.pbl/pbl_changed.sru":  v("pbl_changed", "new"),
+		"inf1.pbl/src_changed.sru":  v("src_changed", "p_src_changed"),
+		"inf1.pbl/both_changed.sru": v("both_changed", "new1"),
+		"inf1.pbl/src_deleted.sru":  v("src_deleted", "p_src_deleted"),
+		"inf1.pbl/formatted.sru":    v("formatted", "p_formatted"),
+		"inf1.pbl/new_pbl.sru":      v("new_pbl", "n"),
+		"inf1.pbl/new_both.sru":     v("new_both", "n1"),
+	}
+	src := map[string]Version{
+		"inf1.pbl/same.sru":         v("same", "x"),
+		"inf1.pbl/pbl_changed.sru":  v("pbl_changed", "s_pbl_changed"),
+		"inf1.pbl/src_changed.sru":  v("src_changed", "new"),
+		"inf1.pbl/both_changed.sru": v("both_changed", "new2"),
+		"inf1.pbl/pbl_deleted.sru":  v("pbl_deleted", "s_pbl_deleted"),
+		"inf1.pbl/formatted.sru":    v("formatted", "s_formatted"),
+		"inf1.pbl/new_src.sru":      v("new_src", "n"),
+		"inf1.pbl/new_both.sru":     v("new_both", "n2"),
+	}
+	want := map[string]string{
+		"same":         Unchanged,
+		"pbl_changed":  Export,
+		"src_changed":  Import,
+		"both_changed": Conflict,
+		"pbl_deleted":  DeleteSrc,
+		"src_deleted":  DeletePbl,
+		"both_deleted": Unchanged,
+		"formatted":    Unchanged,
+		"new_pbl":      Export,
+		"new_src":      Import,
+		"new_both":     Conflict,
+	}
+
+	steps := state.Plan(pbl, src)
+	if len(steps) != len(want) {
+		t.Fatalf("expected %d steps, got %d: %+v", len(want), len(steps), steps)
+	}
+	for _, step := range steps {
+		name := step.Key[len("inf1.pbl/") : len(step.Key)-len(".sru")]
+		if step.Action != want[name] {
+			t.Errorf("%s: got action %s, want %s", name, step.Action, want[name])
+		}
+		if step.Action == Conflict && step.Reason == "" {
+			t.Errorf("%s: conflict without reason", name)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	path := StatePath(filepath.Join(t.TempDir(), "src"))
+	if filepath.Base(path) != "src.sync.json" {
+		t.Errorf("unexpected state path %s", path)
+	}
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Record(Key("inf1.pbl", "u_a.sru"), "p", "s")
+	state.Record(Key("inf1.pbl", "u_b.sru"), "p", "s")
+	state.Record(Key("inf1.pbl", "u_b.sru"), "", "")
+	if err = state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Objects) != 1 || loaded.Objects["inf1.pbl/u_a.sru"] != (Baseline{Pbl: "p", Src: "s"}) {
+		t.Errorf("unexpected state %+v", loaded.Objects)
+	}
+}
@@ -0,0 +1,146 @@
+// Package textdiff compares and merges sources line by line.
+//
+// The line matching uses the algorithm of Eugene W. Myers ("An O(ND) Difference Algorithm and Its Variations"),
+// the three-way merge is the classic diff3 algorithm on top of it.
+package textdiff
+
+import "strings"
+
+// Op is the kind of an edit.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Edit is a single line of a diff. Lines keep their line break.
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// Lines splits s into lines, the line breaks are kept.
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the edits transforming a into b.
+func Diff(a, b []string) []Edit {
+	m := matches(a, b)
+	var edits []Edit
+	j := 0
+	for i := range a {
+		if m[i] < 0 {
+			edits = append(edits, Edit{Delete, a[i]})
+			continue
+		}
+		for ; j < m[i]; j++ {
+			edits = append(edits, Edit{Insert, b[j]})
+		}
+		edits = append(edits, Edit{Equal, a[i]})
+		j++
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Insert, b[j]})
+	}
+	return edits
+}
+
+// matches returns for every line of a the index of the matching line in b or -1. The matches form a longest
+// common subsequence, i.e. the indices are strictly increasing.
+func matches(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+	// common prefix and suffix are cheap and make the diff of large, mostly equal sources fast
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		m[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		m[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+	for _, p := range myers(a[pre:len(a)-suf], b[pre:len(b)-suf]) {
+		m[pre+p[0]] = pre + p[1]
+	}
+	return m
+}
+
+// myers returns the pairs of matching lines of a shortest edit script.
+func myers(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	max := n + m
+	if n == 0 || m == 0 {
+		return nil
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds v[-d..d] after step d, it is needed to backtrack the path
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, d, n, m)
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, d, x, y int) [][2]int {
+	var pairs [][2]int
+	for ; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			pairs = append(pairs, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		pairs = append(pairs, [2]int{x, y})
+	}
+	for i, j := 0, len(pairs)-1; i < j; i, j = i+1, j-1 {
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	return pairs
+}
@@ -0,0 +1,153 @@
+package textdiff
+
+import (
+	"sort"
+	"strings"
+)
+
+// Labels are the names written to the conflict markers.
+type Labels struct {
+	Base   string
+	Mine   string
+	Theirs string
+}
+
+// Merge3 merges the changes from base to mine and from base to theirs. Changes made on only one side are taken,
+// identical changes on both sides are taken once. Overlapping changes are conflicts, they are written with
+// conflict markers (<<<<<<<, |||||||, =======, >>>>>>>). The number of conflicts is returned.
+func Merge3(base, mine, theirs string, labels Labels) (string, int) {
+	o, a, b := Lines(base), Lines(mine), Lines(theirs)
+	ma, mb := matches(o, a), matches(o, b)
+	eol := "\n"
+	if strings.Contains(mine, "\r\n") {
+		eol = "\r\n"
+	}
+
+	var sb strings.Builder
+	conflicts := 0
+	i, j, k := 0, 0, 0
+	for {
+		// lines which are unchanged on both sides
+		for i < len(o) && ma[i] == j && mb[i] == k {
+			sb.WriteString(o[i])
+			i, j, k = i+1, j+1, k+1
+		}
+		// the next line of base which exists on both sides ends the changed chunk
+		o2 := i
+		for o2 < len(o) && (ma[o2] < 0 || mb[o2] < 0) {
+			o2++
+		}
+		a2, b2 := len(a), len(b)
+		if o2 < len(o) {
+			a2, b2 = ma[o2], mb[o2]
+		}
+		if o2 == i && a2 == j && b2 == k {
+			break
+		}
+
+		oc, ac, bc := strings.Join(o[i:o2], ""), strings.Join(a[j:a2], ""), strings.Join(b[k:b2], "")
+		switch {
+		case ac == bc, bc == oc:
+			sb.WriteString(ac)
+		case ac == oc:
+			sb.WriteString(bc)
+		default:
+			conflicts++
+			writeConflictPart(&sb, "<<<<<<< "+labels.Mine, ac, eol)
+			writeConflictPart(&sb, "||||||| "+labels.Base, oc, eol)
+			writeConflictPart(&sb, "=======", bc, eol)
+			sb.WriteString(">>>>>>> " + labels.Theirs + eol)
+		}
+		i, j, k = o2, a2, b2
+	}
+	return sb.String(), conflicts
+}
+
+func writeConflictPart(sb *strings.Builder, marker, text, eol string) {
+	sb.WriteString(marker + eol)
+	sb.WriteString(text)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		sb.WriteString(eol)
+	}
+}
+
+// Action tells what to do with an object of the merged library.
+type Action int
+
+const (
+	Keep     Action = iota // the object of mine is the result
+	Update                 // the object has to be replaced (or created) with Source
+	Remove                 // the object has to be deleted
+	Conflict               // the object could not be merged automatically
+)
+
+// ObjectResult is the merge result of a single object.
+type ObjectResult struct {
+	Name      string // entry name, e.g. w_main.srw
+	Action    Action
+	Source    string // the merged source for Update, the source with conflict markers for Conflict
+	Conflicts int    // number of conflicting chunks
+	Reason    string // description of the conflict
+}
+
+// MergeObjects merges the sources of three libraries. The maps are keyed by the entry name (e.g. w_main.srw),
+// missing keys are objects which do not exist in the library. The names are compared case-insensitive.
+func MergeObjects(base, mine, theirs map[string]string, labels Labels) []ObjectResult {
+	// names maps the lower case name to the name as it is spelled in mine (or theirs or base)
+	names := make(map[string]string)
+	for _, m := range []map[string]string{base, theirs, mine} {
+		for name := range m {
+			names[strings.ToLower(name)] = name
+		}
+	}
+	base, mine, theirs = lowerKeys(base), lowerKeys(mine), lowerKeys(theirs)
+
+	var results []ObjectResult
+	for name, origName := range names {
+		o, inBase := base[name]
+		a, inMine := mine[name]
+		b, inTheirs := theirs[name]
+		res := ObjectResult{Name: origName}
+		switch {
+		case inMine == inTheirs && a == b, inTheirs == inBase && b == o:
+			res.Action = Keep
+		case inMine == inBase && a == o:
+			// only theirs changed
+			res.Action = Update
+			res.Source = b
+			if !inTheirs {
+				res.Action = Remove
+			}
+		case !inMine || !inTheirs:
+			res.Action = Conflict
+			if !inMine {
+				res.Reason = "deleted in " + labels.Mine + ", modified in " + labels.Theirs
+				res.Source = b
+			} else {
+				res.Reason = "modified in " + labels.Mine + ", deleted in " + labels.Theirs
+				res.Source = a
+			}
+		default:
+			// changed on both sides, if the object was added on both sides, the base is empty
+			res.Source, res.Conflicts = Merge3(o, a, b, labels)
+			res.Action = Update
+			if res.Conflicts > 0 {
+				res.Action = Conflict
+				res.Reason = "modified on both sides"
+			}
+		}
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+	return results
+}
+
+func lowerKeys(m map[string]string) map[string]string {
+	l := make(map[string]string, len(m))
+	for k, v := range m {
+		l[strings.ToLower(k)] = v
+	}
+	return l
+}
@@ -0,0 +1,171 @@
+package textdiff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string // edits as +/-/space prefixed lines
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", " a\n b\n c\n"},
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nc\n", "a\nb\nc\n", " a\n+b\n c\n"},
+		{"", "a\n", "+a\n"},
+		{"a\nb\n", "", "-a\n-b\n"},
+		{"a\nb\nc\nd\n", "a\nx\nc\ny\n", " a\n-b\n+x\n c\n-d\n+y\n"},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		for _, e := range Diff(Lines(tt.a), Lines(tt.b)) {
+			sb.WriteString(map[Op]string{Equal: " ", Delete: "-", Insert: "+"}[e.Op] + e.Line)
+		}
+		if sb.String() != tt.want {
+			t.Errorf("Diff(%q, %q) = %q, expected %q", tt.a, tt.b, sb.String(), tt.want)
+		}
+	}
+}
+
+// TestDiffRandom checks that applying the edits to a results in b and that the diff is minimal for small inputs.
+func TestDiffRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for range 200 {
+		a := randomLines(r, r.Intn(30))
+		b := randomLines(r, r.Intn(30))
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range Diff(a, b) {
+			if e.Op != Insert {
+				gotA = append(gotA, e.Line)
+			}
+			if e.Op != Delete {
+				gotB = append(gotB, e.Line)
+			}
+			if e.Op != Equal {
+				changes++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("edits do not transform %q into %q", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLen(a, b); changes != want {
+			t.Fatalf("diff of %q and %q has %d changes, expected %d", a, b, changes, want)
+		}
+	}
+}
+
+func randomLines(r *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+	}
+	return lines
+}
+
+func lcsLen(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestMerge3(t *testing.T) {
+	labels := Labels{"base", "mine", "theirs"}
+	tests := []struct {
+		name               string
+		base, mine, theirs string
+		want               string
+		wantConflicts      int
+	}{
+		{"only mine", "a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n", "a\nB\nc\n", 0},
+		{"only theirs", "a\nb\nc\n", "a\nb\nc\n", "a\nb\nC\n", "a\nb\nC\n", 0},
+		{"both different lines", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same change", "a\nb\n", "a\nx\n", "a\nx\n", "a\nx\n", 0},
+		{"insert and delete", "a\nb\nc\n", "a\nnew\nb\nc\n", "a\nb\n", "a\nnew\nb\n", 0},
+		{"conflict", "a\nb\nc\n", "a\nmine\nc\n", "a\ntheirs\nc\n",
+			"a\n<<<<<<< mine\nmine\n||||||| base\nb\n=======\ntheirs\n>>>>>>> theirs\nc\n", 1},
+		{"conflict crlf without eol", "a\r\nb", "a\r\nx", "a\r\ny",
+			"a\r\n<<<<<<< mine\r\nx\r\n||||||| base\r\nb\r\n=======\r\ny\r\n>>>>>>> theirs\r\n", 1},
+	}
+	for _, tt := range tests {
+		got, conflicts := Merge3(tt.base, tt.mine, tt.theirs, labels)
+		if got != tt.want || conflicts != tt.wantConflicts {
+			t.Errorf("%s: got %q (%d conflicts), expected %q (%d conflicts)", tt.name, got, conflicts, tt.want, tt.wantConflicts)
+		}
+	}
+}
+
+func TestMergeObjects(t *testing.T) {
+	base := map[string]string{"w_main.srw": "a\nb\n", "u_del.sru": "x\n", "u_conflict.sru": "1\n", "f_both.srf": "a\nb\nc\n"}
+	mine := map[string]string{"W_Main.srw": "a\nb\n", "u_conflict.sru": "2\n", "f_both.srf": "A\nb\nc\n", "u_new.sru": "n\n"}
+	theirs := map[string]string{"w_main.srw": "a\nB\n", "u_conflict.sru": "3\n", "f_both.srf": "a\nb\nC\n", "u_new.sru": "n\n"}
+
+	results := MergeObjects(base, mine, theirs, Labels{"base", "mine", "theirs"})
+	want := []struct {
+		name   string
+		action Action
+		source string
+	}{
+		{"f_both.srf", Update, "A\nb\nC\n"},
+		{"u_conflict.sru", Conflict, ""},
+		{"u_del.sru", Keep, ""},
+		{"u_new.sru", Keep, ""},
+		{"W_Main.srw", Update, "a\nB\n"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Name != w.name || r.Action != w.action || (w.source != "" && r.Source != w.source) {
+			t.Errorf("unexpected result %+v, expected %+v", r, w)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13"
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+\ No newline at end of file
+`
+	if got := Unified("a", "b", a, b, 3); got != want {
+		t.Errorf("Unified() = %q, expected %q", got, want)
+	}
+	if got := Unified("a", "b", a, a, 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q, expected empty string", got)
+	}
+	if got := Unified("a", "b", "", "x\r\n", 3); got != "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+x\n" {
+		t.Errorf("Unified() of new text = %q", got)
+	}
+	if ins, del := Stat(a, b); ins != 2 || del != 1 {
+		t.Errorf("Stat() = %d, %d, expected 2, 1", ins, del)
+	}
+}
@@ -0,0 +1,88 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unified returns the differences between a and b in the unified diff format with the given number of context
+// lines. nameA and nameB are written to the ---/+++ header lines. An empty string is returned if a and b are equal.
+func Unified(nameA, nameB, a, b string, context int) string {
+	edits := Diff(Lines(a), Lines(b))
+
+	var sb strings.Builder
+	for start := 0; start < len(edits); {
+		// find the next change
+		for start < len(edits) && edits[start].Op == Equal {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		// a hunk ends if there are more than 2*context equal lines in a row
+		end := start
+		for i := start; i < len(edits); i++ {
+			if edits[i].Op != Equal {
+				end = i + 1
+				continue
+			}
+			if i-end >= 2*context {
+				break
+			}
+		}
+		from, to := max(0, start-context), min(len(edits), end+context)
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		lineA, lineB := 1, 1
+		for _, e := range edits[:from] {
+			if e.Op != Insert {
+				lineA++
+			}
+			if e.Op != Delete {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, e := range edits[from:to] {
+			if e.Op != Insert {
+				countA++
+			}
+			if e.Op != Delete {
+				countB++
+			}
+		}
+		// empty ranges start at the line before, as in GNU diff
+		if countA == 0 {
+			lineA--
+		}
+		if countB == 0 {
+			lineB--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, e := range edits[from:to] {
+			sb.WriteString(map[Op]string{Equal: " ", Delete: "-", Insert: "+"}[e.Op])
+			sb.WriteString(strings.TrimRight(e.Line, "\r\n"))
+			sb.WriteString("\n")
+			if !strings.HasSuffix(e.Line, "\n") {
+				sb.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// Stat returns the number of inserted and deleted lines to get from a to b.
+func Stat(a, b string) (inserted, deleted int) {
+	for _, e := range Diff(Lines(a), Lines(b)) {
+		switch e.Op {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
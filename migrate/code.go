@@ -3,26 +3,31 @@ package migrate
 import (
 	"embed"
 	"fmt"
-	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
 	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/informaticon/lib.go.base.pborca/orca"
 )
 
-// FixSqla17ByteString replaces all occurrances of byte_substr with substr
-func FixSqla17ByteString(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+// FixSqla17ByteString replaces all occurrances of byte_substr with substr. The libraries are scanned in parallel
+// on the sessions of the pool.
+func FixSqla17ByteString(libFolder string, targetName string, sessions *orcapool.Pool[backend.Backend], warnFunc func(string)) error {
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 	regex := regexp.MustCompile(`(?is)([^a-z])byte_substr\(`)
 	var fixedObjNames []string
+	var mu sync.Mutex
 	pblFiles, err := filepath.Glob(filepath.Join(libFolder, "*.pbl"))
 	if err != nil {
 		return fmt.Errorf("could not create list of pbl files for folder %s: %v", libFolder, err)
 	}
-	for _, pblFile := range pblFiles {
+	err = sessions.Run(pblFiles, func(orca backend.Backend, pblFile string) error {
 		objArrs, err := orca.GetObjList(pblFile)
 		if err != nil {
 			return fmt.Errorf("could not create list of objects for pbl %s: %v", pblFile, err)
@@ -30,7 +35,7 @@ func FixSqla17ByteString(libFolder string, targetName string, orca *pborca.Orca,
 
 		for _, objArr := range objArrs {
 			for _, obj := range objArr.GetObjArr() {
-				objSrc, err := orca.GetObjSource(pblFile, obj.Name+pborca.GetObjSuffixFromType(obj.ObjType))
+				objSrc, err := GetObjSource(orca, pblFile, obj.Name+pborca.GetObjSuffixFromType(obj.ObjType))
 				if err != nil {
 					return fmt.Errorf("could not get soure for object %s: %v", obj.Name+pborca.GetObjSuffixFromType(obj.ObjType), err)
 				}
@@ -40,81 +45,87 @@ func FixSqla17ByteString(libFolder string, targetName string, orca *pborca.Orca,
 						warnFunc(fmt.Sprintf("found byte_substr pattern in %s but regex did not match it", obj.Name))
 						continue
 					}
+					mu.Lock()
 					fixedObjNames = append(fixedObjNames, obj.Name)
-					err = orca.SetObjSource(pbtFile, pblFile, obj.Name, []byte(newObjSrc))
+					mu.Unlock()
+					err = SetObjSource(orca, pbtFile, pblFile, obj.Name, []byte(newObjSrc))
 					if err != nil {
 						return fmt.Errorf("could not set source for object %s: %v", obj.Name, err)
 					}
 				}
 			}
 		}
-	}
+		return nil
+	})
+	sort.Strings(fixedObjNames)
 	fmt.Printf("FixSqla17ByteString fixed %d objects: %v\n", len(fixedObjNames), fixedObjNames)
-	return nil
+	return err
 }
 
 // FixSqla17Base replaces SQLA17 checks in base layer of A3.
-func FixSqla17Base(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixSqla17Base(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
-	fixes := []struct {
-		fixName string
-		pblFile string
-		objName string
-		rxCheck *regexp.Regexp // if true, the fix has already been applied
-		rxRepl  *regexp.Regexp
-		replace string
-	}{
+	fixes := []*Patch{
 		{
-			"FIX0.1", "inf1.pbl", "inf1_u_transaction",
-			regexp.MustCompile(`(?is)public[ \t]+function[ \t]+string[ \t]+of_get_version[ \t]*\(\)`),
-			regexp.MustCompile(`(?is)(end[ \t]+prototypes[\r\n\t ]+)(public|protected|private|event)`),
-			"public function string of_get_version ()\r\n${1}public function string of_get_version ();//SQLA17 migration - FIX0.1: Add of_get_version for FIX2\r\n\treturn profilestring(is_inifile, is_datasource, 'version', '')\r\nend function\r\n\r\n${2}",
+			Name: "FIX0.1", Library: "inf1.pbl", Object: "inf1_u_transaction",
+			Check:   `(?is)public[ \t]+function[ \t]+string[ \t]+of_get_version[ \t]*\(\)`,
+			Match:   `(?is)(end[ \t]+prototypes[\r\n\t ]+)(public|protected|private|event)`,
+			Replace: "public function string of_get_version ()\r\n${1}public function string of_get_version ();//SQLA17 migration - FIX0.1: Add of_get_version for FIX2\r\n\treturn profilestring(is_inifile, is_datasource, 'version', '')\r\nend function\r\n\r\n${2}",
 		},
 		{
-			"FIX1", "inf1.pbl", "inf1_u_transaction",
-			regexp.MustCompile(`(?is)//SQLA17 migration - FIX1:`),
-			regexp.MustCompile(`(?is)[\r\n]+(\/\/Version[\n\r\t ]+ls_version[\t =]+of_get_version\(\).*?end if)`),
-			"\r\n//SQLA17 migration - FIX1: deactivate driver check\r\n/*\r\n${1}\r\n*/",
+			Name: "FIX1", Library: "inf1.pbl", Object: "inf1_u_transaction",
+			Check:   `(?is)//SQLA17 migration - FIX1:`,
+			Match:   `(?is)[\r\n]+(\/\/Version[\n\r\t ]+ls_version[\t =]+of_get_version\(\).*?end if)`,
+			Replace: "\r\n//SQLA17 migration - FIX1: deactivate driver check\r\n/*\r\n${1}\r\n*/",
 		},
 		{
-			"FIX2", "inf1.pbl", "inf1_u_transaction",
-			regexp.MustCompile(`(?is)//SQLA17 migration - FIX2:`),
-			regexp.MustCompile(`(?is)(([ \t]+if left\(ls_version,[ \t=]+2\)[ \t=]+'(11|16)'.*?[\r\n]+)+)`),
-			"\r\n\t//SQLA17 migration - FIX2: allow sqla17 driver\r\n\t/*\r\n${1}\t*/\r\n\tchoose case left(of_get_version(), 2)\r\n\t\tcase '11' //SQLA11\r\n\t\t\tas_db += \";commlinks=tcpip{host=\" + string(ls_host) + \"}\"\r\n\t\tcase else //SQLA16, SQLA17, ...\r\n\t\t\tas_db += \";host=\" + ls_host\r\n\tend choose\r\n",
+			Name: "FIX2", Library: "inf1.pbl", Object: "inf1_u_transaction",
+			Check:   `(?is)//SQLA17 migration - FIX2:`,
+			Match:   `(?is)(([ \t]+if left\(ls_version,[ \t=]+2\)[ \t=]+'(11|16)'.*?[\r\n]+)+)`,
+			Replace: "\r\n\t//SQLA17 migration - FIX2: allow sqla17 driver\r\n\t/*\r\n${1}\t*/\r\n\tchoose case left(of_get_version(), 2)\r\n\t\tcase '11' //SQLA11\r\n\t\t\tas_db += \";commlinks=tcpip{host=\" + string(ls_host) + \"}\"\r\n\t\tcase else //SQLA16, SQLA17, ...\r\n\t\t\tas_db += \";host=\" + ls_host\r\n\tend choose\r\n",
 		},
 		{
-			"FIX3", "inf1.pbl", "inf1_u_transaction",
-			regexp.MustCompile(`(?is)(public[ \t]+subroutine[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)//SQLA17 migration - FIX3-proc:`),
-			regexp.MustCompile(`(?is)(public[ \t]+subroutine[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)(.*?)([\t \r\n]+end[ t]+subroutine[\r\n]+)`),
-			"${1}//SQLA17 migration - FIX3-proc: switch to new version check\r\n\r\n/*OLD SOURCE HAS BEEN REMOVED*/\r\n\r\nlong ll_fun_exists\r\nstring ls_error\r\nselect count(*) into :ll_fun_exists from sysprocedure where proc_name = 'dev_check_sqla_versions';\r\n// if function dev_check_sqla_versions does not exist, continue without db version check\r\nif ll_fun_exists > 0 then\r\n\tselect dev_check_sqla_versions() into :ls_error from dummy;\r\n\tif ls_error <> '' then\r\n\t\tthrow(gu_e.iu_as.of_re_database(gu_e.of_new_error().of_push(populateerror(0, ls_error)).of_push('this', this)))\r\n\tend if\r\nend if\r\n\r\n${3}",
+			Name: "FIX3-proc", Library: "inf1.pbl", Object: "inf1_u_transaction", Optional: true,
+			Check:   `(?is)(public[ \t]+subroutine[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)//SQLA17 migration - FIX3-proc:`,
+			Match:   `(?is)(public[ \t]+subroutine[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)(.*?)([\t \r\n]+end[ t]+subroutine[\r\n]+)`,
+			Replace: "${1}//SQLA17 migration - FIX3-proc: switch to new version check\r\n\r\n/*OLD SOURCE HAS BEEN REMOVED*/\r\n\r\nlong ll_fun_exists\r\nstring ls_error\r\nselect count(*) into :ll_fun_exists from sysprocedure where proc_name = 'dev_check_sqla_versions';\r\n// if function dev_check_sqla_versions does not exist, continue without db version check\r\nif ll_fun_exists > 0 then\r\n\tselect dev_check_sqla_versions() into :ls_error from dummy;\r\n\tif ls_error <> '' then\r\n\t\tthrow(gu_e.iu_as.of_re_database(gu_e.of_new_error().of_push(populateerror(0, ls_error)).of_push('this', this)))\r\n\tend if\r\nend if\r\n\r\n${3}",
 		},
 		{
-			"FIX3", "inf1.pbl", "inf1_u_transaction",
-			regexp.MustCompile(`(?is)(public[ \t]+function[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)//SQLA17 migration - FIX3-func:`),
-			regexp.MustCompile(`(?is)(public[ \t]+function[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)(.*?return 1)([\t \r\n]+end[ t]+function[\r\n]+)`),
-			"${1}//SQLA17 migration - FIX3-func: switch to new version check\r\n\r\n/*OLD SOURCE HAS BEEN REMOVED*/\r\n\r\nlong ll_fun_exists\r\nstring ls_error\r\nselect count(*) into :ll_fun_exists from sysprocedure where proc_name = 'dev_check_sqla_versions';\r\n// if function dev_check_sqla_versions does not exist, continue without db version check\r\nif ll_fun_exists > 0 then\r\n\tselect dev_check_sqla_versions() into :ls_error from dummy;\r\n\tif ls_error <> '' then\r\n\t\treturn -1\r\n\tend if\r\nend if\r\nreturn 1\r\n${3}",
+			Name: "FIX3-func", Library: "inf1.pbl", Object: "inf1_u_transaction", Optional: true,
+			Check:   `(?is)(public[ \t]+function[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)//SQLA17 migration - FIX3-func:`,
+			Match:   `(?is)(public[ \t]+function[^;\r\n]*?of_check_version[ \t]*[^;\r\n]+;)(.*?return 1)([\t \r\n]+end[ t]+function[\r\n]+)`,
+			Replace: "${1}//SQLA17 migration - FIX3-func: switch to new version check\r\n\r\n/*OLD SOURCE HAS BEEN REMOVED*/\r\n\r\nlong ll_fun_exists\r\nstring ls_error\r\nselect count(*) into :ll_fun_exists from sysprocedure where proc_name = 'dev_check_sqla_versions';\r\n// if function dev_check_sqla_versions does not exist, continue without db version check\r\nif ll_fun_exists > 0 then\r\n\tselect dev_check_sqla_versions() into :ls_error from dummy;\r\n\tif ls_error <> '' then\r\n\t\treturn -1\r\n\tend if\r\nend if\r\nreturn 1\r\n${3}",
 		},
 		{
-			"FIX4", "jif1.pbl", "jif1_u_jif_master",
-			regexp.MustCompile(`(?is)//SQLA17 migration - FIX4:`),
-			regexp.MustCompile(`(?is)(choose[ \t]+case[ \t]+ls_sa_major_version[ \t\r\n]+case[ \t]+)"[,"167 ]+"([ \t\r\n]+)`),
-			"//SQLA17 migration - FIX4: allow SQLA17\r\n${1}'11', '16', '17'${2}",
+			Name: "FIX4", Library: "jif1.pbl", Object: "jif1_u_jif_master",
+			Check:   `(?is)//SQLA17 migration - FIX4:`,
+			Match:   `(?is)(choose[ \t]+case[ \t]+ls_sa_major_version[ \t\r\n]+case[ \t]+)"[,"167 ]+"([ \t\r\n]+)`,
+			Replace: "//SQLA17 migration - FIX4: allow SQLA17\r\n${1}'11', '16', '17'${2}",
 		},
 	}
 	for _, fix := range fixes {
-		src, err := orca.GetObjSource(filepath.Join(libFolder, fix.pblFile), fix.objName)
+		pblFile := filepath.Join(libFolder, fix.Library)
+		src, err := GetObjSource(orca, pblFile, fix.Object)
 		if err != nil {
-			warnFunc(fmt.Sprintf("skipping fix %s, file %s does not contain an object named %s: %v", fix.fixName, fix.pblFile, fix.objName, err))
+			warnFunc(fmt.Sprintf("skipping fix %s, file %s does not contain an object named %s: %v", fix.Name, fix.Library, fix.Object, err))
 			continue
 		}
-		if fix.rxCheck.MatchString(src) {
-			warnFunc(fmt.Sprintf("skipping fix %s as it already has been applied", fix.fixName))
+		src, status, err := fix.Apply(src)
+		if status == PatchSkipped && fix.applied(src) {
+			warnFunc(fmt.Sprintf("skipping fix %s as it already has been applied", fix.Name))
+			continue
+		}
+		if status == PatchSkipped {
+			// of_check_version is a subroutine or a function, depending on the version of inf1_u_transaction
 			continue
 		}
-		src = fix.rxRepl.ReplaceAllString(src, fix.replace)
-		err = orca.SetObjSource(pbtFile, filepath.Join(libFolder, fix.pblFile), fix.objName, []byte(src))
 		if err != nil {
-			return fmt.Errorf("fix %s for %s failed, could not write source: %v", fix.fixName, fix.objName, err)
+			warnFunc(fmt.Sprintf("skipping fix %s for %s: %v", fix.Name, fix.Object, err))
+			continue
+		}
+		err = SetObjSource(orca, pbtFile, pblFile, fix.Object, []byte(src))
+		if err != nil {
+			return fmt.Errorf("fix %s for %s failed, could not write source: %v", fix.Name, fix.Object, err)
 		}
 	}
 
@@ -122,13 +133,13 @@ func FixSqla17Base(libFolder string, targetName string, orca *pborca.Orca, warnF
 	return nil
 }
 
-func FixArf(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixArf(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pblFile := filepath.Join(libFolder, "arf1.pbl")
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 	objName := "arf1_u_arf_service_lohn"
 	regex := regexp.MustCompile(`(?is)([ \t]*\/\/2020-10-02 Martin Abplanalp, Ticket 19529[^\r\n]+[\r\n\t ]+)(ls_release_liblohn.*?end if)`)
 
-	src, err := orca.GetObjSource(pblFile, objName)
+	src, err := GetObjSource(orca, pblFile, objName)
 	if err != nil {
 		warnFunc(fmt.Sprintf("skipping arf1_u_arf_service_lohn migration (file %s does not contain an object named %s)", pblFile, objName))
 		return nil
@@ -142,20 +153,20 @@ func FixArf(libFolder string, targetName string, orca *pborca.Orca, warnFunc fun
 	}
 	src = regex.ReplaceAllString(src, `${1}/*COMMENTED OUT BY PB2022R3 MIGRATION: ${2}*/`)
 
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		return fmt.Errorf("FixArf failed: %v", err)
 	}
 	return nil
 }
 
-func FixRegistry(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixRegistry(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pblFile := filepath.Join(libFolder, "inf1.pbl")
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 	objName := "inf1_u_registry"
 	regex := regexp.MustCompile(`(?mi)[ ]*string[ ]+is_ie_ole_exes\[\][ ]*=[ ]*\{(.*)\}[ ]*`)
 
-	src, err := orca.GetObjSource(pblFile, objName)
+	src, err := GetObjSource(orca, pblFile, objName)
 	if err != nil {
 		warnFunc(fmt.Sprintf("skipping %s migration, file %s does not contain an object named %s", objName, pblFile, objName))
 		return nil
@@ -175,7 +186,7 @@ func FixRegistry(libFolder string, targetName string, orca *pborca.Orca, warnFun
 	}
 	src = regex.ReplaceAllString(src, `string is_ie_ole_exes[] = {"a3.exe", "pb170.exe", "pb220.exe", "pb250.exe"}`)
 
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		return fmt.Errorf("FixRegistry failed: %v", err)
 	}
@@ -183,7 +194,7 @@ func FixRegistry(libFolder string, targetName string, orca *pborca.Orca, warnFun
 	return nil
 }
 
-func FixHttpClient(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixHttpClient(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 
 	// part 1: fin1_u_fin_bankenstamm
@@ -191,7 +202,7 @@ func FixHttpClient(libFolder string, targetName string, orca *pborca.Orca, warnF
 		pblFile := filepath.Join(libFolder, "fin1.pbl")
 		objName := "fin1_u_fin_bankenstamm"
 		regex := regexp.MustCompile(`(?mi)(lu_client = create httpclient)([\r\n \t]+)(li_ret)`)
-		src, err := orca.GetObjSource(pblFile, objName)
+		src, err := GetObjSource(orca, pblFile, objName)
 		if err != nil {
 			warnFunc(fmt.Sprintf("skipping %s migration, file %s doesn't contain %s", objName, pblFile, objName))
 			return nil
@@ -203,7 +214,7 @@ func FixHttpClient(libFolder string, targetName string, orca *pborca.Orca, warnF
 
 		src = regex.ReplaceAllString(src, `${1}${2}lu_client.anonymousaccess = true${2}${3}`)
 
-		err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+		err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 		if err != nil {
 			return fmt.Errorf("FixHttpClient failed for %s: %v", objName, err)
 		}
@@ -218,7 +229,7 @@ func FixHttpClient(libFolder string, targetName string, orca *pborca.Orca, warnF
 	step2 := func() error {
 		pblFile := filepath.Join(libFolder, "inf1.pbl")
 		objName := "inf1_u_httpclient"
-		src, err := orca.GetObjSource(pblFile, objName)
+		src, err := GetObjSource(orca, pblFile, objName)
 		if err != nil {
 			warnFunc(fmt.Sprintf("skipping %s migration, file %s doesn't contain %s", objName, pblFile, objName))
 			return nil
@@ -231,7 +242,7 @@ func FixHttpClient(libFolder string, targetName string, orca *pborca.Orca, warnF
 
 		regex = regexp.MustCompile(`(?mi)(end forward[\r\n\t ]+global type inf1_u_httpclient from httpclient[ \t]+)`)
 		src = regex.ReplaceAllString(src, `${1}\r\nboolean anonymousaccess = true`)
-		err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+		err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 		if err != nil {
 			return fmt.Errorf("FixHttpClient failed for %s: %v", objName, err)
 		}
@@ -240,15 +251,15 @@ func FixHttpClient(libFolder string, targetName string, orca *pborca.Orca, warnF
 	return step2()
 }
 
-func FixLifProcess(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixLifProcess(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pblFile := filepath.Join(libFolder, "lif1.pbl")
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 
 	objName := "lif1_u_process"
-	src, err := orca.GetObjSource(pblFile, objName)
+	src, err := GetObjSource(orca, pblFile, objName)
 	if err != nil {
 		objName = "inf1_u_process"
-		src, err = orca.GetObjSource(pblFile, objName)
+		src, err = GetObjSource(orca, pblFile, objName)
 		if err != nil {
 			fmt.Printf("skipping %s migration (%v)\n", objName, err)
 			return nil
@@ -275,7 +286,7 @@ func FixLifProcess(libFolder string, targetName string, orca *pborca.Orca, warnF
 	}
 
 	src = regex.ReplaceAllString(src, `	if lower(ls_exe) = "pb170.exe" or lower(ls_exe) = "pb220.exe" or lower(ls_exe) = "pb250.exe" then`)
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		return fmt.Errorf("FixLifProcess failed: %v", err)
 	}
@@ -283,15 +294,15 @@ func FixLifProcess(libFolder string, targetName string, orca *pborca.Orca, warnF
 }
 
 // PB115 migration: Replace _DEBUG with CI_DEBUG...
-func FixLifMetratec(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string), ignoreCompileErr bool) error {
+func FixLifMetratec(libFolder string, targetName string, orca backend.Backend, warnFunc func(string), ignoreCompileErr bool) error {
 	pblFile := filepath.Join(libFolder, "lif1.pbl")
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 
 	objName := "lif1_u_metratec_base"
-	src, err := orca.GetObjSource(pblFile, objName)
+	src, err := GetObjSource(orca, pblFile, objName)
 	if err != nil {
 		objName := "inf1_u_metratec_base"
-		src, err = orca.GetObjSource(pblFile, objName)
+		src, err = GetObjSource(orca, pblFile, objName)
 		if err != nil {
 			return fmt.Errorf("FixLifMetratec failed: %v", err)
 		}
@@ -305,7 +316,7 @@ func FixLifMetratec(libFolder string, targetName string, orca *pborca.Orca, warn
 	}
 
 	src = regex.ReplaceAllString(src, `${1}CI${2}`)
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil && !ignoreCompileErr {
 		return fmt.Errorf("FixLifMetratec failed: %v", err)
 	}
@@ -313,7 +324,7 @@ func FixLifMetratec(libFolder string, targetName string, orca *pborca.Orca, warn
 }
 
 // FixPayrollXmlDecl removes deprecated use of pbdom_processinginstruction
-func FixPayrollXmlDecl(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixPayrollXmlDecl(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 	regex1 := regexp.MustCompile(`(?im)lpbdom_pi.setname\('xml'\)[\r\n\t ]+lpbdom_pi.SetData\('version="1\.0" encoding="UTF-8"'\)[\t ]+`)
 	regex2 := regexp.MustCompile(`(?im)[\r\n\t ]+ipbdom_document.addcontent\(lpbdom_pi\)[\t ]+`)
@@ -321,7 +332,7 @@ func FixPayrollXmlDecl(libFolder string, targetName string, orca *pborca.Orca, w
 		"loh1_u_loh_xml_salary_declaration": filepath.Join(libFolder, "loh1.pbl"),
 		"elm1_u_elm_xml_salary_declaration": filepath.Join(libFolder, "elmg.pbl"),
 	} {
-		src, err := orca.GetObjSource(pblFile, objName)
+		src, err := GetObjSource(orca, pblFile, objName)
 		if err != nil {
 			warnFunc(fmt.Sprintf("skipping %s migration (does not exist in %s)", objName, pblFile))
 			continue
@@ -333,7 +344,7 @@ func FixPayrollXmlDecl(libFolder string, targetName string, orca *pborca.Orca, w
 		src = regex1.ReplaceAllString(src, `ipbdom_document.setxmldeclaration("1.0", "UTF-8", "yes")`)
 		src = regex2.ReplaceAllString(src, ``)
 
-		err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+		err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 		if err != nil {
 			return fmt.Errorf("FixLohXmlDecl failed on %s: %v", objName, err)
 		}
@@ -342,11 +353,11 @@ func FixPayrollXmlDecl(libFolder string, targetName string, orca *pborca.Orca, w
 }
 
 // FixPayrollXmlDecl removes deprecated use of pbdom_processinginstruction
-func FixPayrollXmlEncoding(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func FixPayrollXmlEncoding(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 	pblFile := filepath.Join(libFolder, "loh1.pbl")
 	objName := "loh1_u_loh_xml_pbdom"
-	src, err := orca.GetObjSource(pblFile, objName)
+	src, err := GetObjSource(orca, pblFile, objName)
 	if err != nil {
 		warnFunc(fmt.Sprintf("skipping %s migration (does not exist in %s)", objName, pblFile))
 		return nil
@@ -365,7 +376,7 @@ if upperbound(lpd_obj) >= 1 then
 	end if
 end if
 `)
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		return fmt.Errorf("FixPayrollXmlEncoding failed on %s: %v", objName, err)
 	}
@@ -375,7 +386,7 @@ end if
 //go:embed mirror_objects/*.sr*
 var mirrorFiles embed.FS
 
-func AddMirrorObjects(libFolder string, targetName string, orca *pborca.Orca, warnFunc func(string)) error {
+func AddMirrorObjects(libFolder string, targetName string, orca backend.Backend, warnFunc func(string)) error {
 	pblFile := filepath.Join(libFolder, "inf1.pbl")
 	pbtFile := filepath.Join(libFolder, targetName+".pbt")
 	files, err := mirrorFiles.ReadDir("mirror_objects")
@@ -396,7 +407,7 @@ func AddMirrorObjects(libFolder string, targetName string, orca *pborca.Orca, wa
 			warnFunc(fmt.Sprintf("skipping import of mirror object %s, it already exists", objName))
 			continue
 		}
-		err = orca.SetObjSource(pbtFile, pblFile, objName, objSrc)
+		err = SetObjSource(orca, pbtFile, pblFile, objName, objSrc)
 		if err != nil {
 			return fmt.Errorf("AddMirrorObjects failed: %v", err)
 		}
@@ -406,12 +417,12 @@ func AddMirrorObjects(libFolder string, targetName string, orca *pborca.Orca, wa
 
 // ChangePbdomBuildOptions adds pbdom to the build projects` build list.
 // It also removes the old pbdom from the list
-func ChangePbdomBuildOptions(projLibName string, projName string, pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)) error {
+func ChangePbdomBuildOptions(projLibName string, projName string, pbtData *orca.Pbt, orca backend.Backend, warnFunc func(string)) error {
 	pblFile := filepath.Join(pbtData.BasePath, projLibName)
 	pbtFile := filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt")
 
 	objName := projName
-	src, err := orca.GetObjSource(pblFile, objName+".srj")
+	src, err := GetObjSource(orca, pblFile, objName+".srj")
 	if err != nil {
 		warnFunc(fmt.Sprintf("skipping ChangePbdomBuildOption, as the source of the project file %s could not be found in %s(%v)", objName, projLibName, err))
 		return nil
@@ -424,14 +435,14 @@ func ChangePbdomBuildOptions(projLibName string, projName string, pbtData *orca.
 	}
 
 	src = regex.ReplaceAllString(src, `PBD:pbdom.pbl,,1`)
-	err = orca.SetObjSource(pbtFile, pblFile, objName, []byte(src))
+	err = SetObjSource(orca, pbtFile, pblFile, objName, []byte(src))
 	if err != nil {
 		return fmt.Errorf("ChangePbdomBuildOptions failed: %v", err)
 	}
 	return nil
 }
 
-func FixRuntimeFolder(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string)) error {
+func FixRuntimeFolder(pbtData *orca.Pbt, orca backend.Backend, warnFunc func(string)) error {
 	// In non-a3 projects, there may be no pbdk folder
 	if !utils.FileExists(filepath.Join(pbtData.BasePath, "pbdk")) {
 		return nil
@@ -440,12 +451,12 @@ func FixRuntimeFolder(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string
 	pbtFile := filepath.Join(pbtData.BasePath, pbtData.AppName+".pbt")
 	for _, proj := range pbtData.Projects {
 		pblFile := filepath.Join(pbtData.BasePath, proj.PblFile)
-		src, err := orca.GetObjSource(pblFile, proj.Name+".srj")
+		src, err := GetObjSource(orca, pblFile, proj.Name+".srj")
 		if err != nil {
 			return fmt.Errorf("FixRuntimeFolder failed while getting project source: %v", err)
 		}
 		src = regexp.MustCompile(`(?mi)^(EXE:.*?)[A-Z]:\\[^,\r\n]+[\r\n]+`).ReplaceAllString(src, "$1.\\pbdk\r\n")
-		err = orca.SetObjSource(pbtFile, pblFile, proj.Name, []byte(src))
+		err = SetObjSource(orca, pbtFile, pblFile, proj.Name, []byte(src))
 		if err != nil {
 			return fmt.Errorf("FixRuntimeFolder failed while setting project source: %v", err)
 		}
@@ -458,20 +469,31 @@ func FixRuntimeFolder(pbtData *orca.Pbt, orca *pborca.Orca, warnFunc func(string
 // For example, the Line `@begin Projects\n 0 "1&a3&inf2.pbl";\n@end;`
 // can be replaced with `@begin Projects\n 0 "1&a3&inf1.pbl";\n@end;`
 func FixProjLib(pbtFilePath, projName, oldLib, newLib string) error {
-	pbtData, err := os.ReadFile(pbtFilePath)
+	pbtData, err := readFile(pbtFilePath)
 	if err != nil {
 		return fmt.Errorf("FixProjLib failed: %v", err)
 	}
 	regr := regexp.MustCompile(`(?mi)(@begin Projects[^@]*?&` + projName + `&)` + oldLib + `(";[^@]*?@end;)`)
 	pbtData = regr.ReplaceAll(pbtData, []byte("${1}"+newLib+"${2}"))
-	err = os.WriteFile(pbtFilePath, pbtData, 0o664)
+	err = writeFile(pbtFilePath, pbtData, 0o664)
 	if err != nil {
 		return fmt.Errorf("FixProjLib failed: %v", err)
 	}
 	return nil
 }
 
-// ReplacePayrollPbwFile replaces the pbwFile (to get rid of other targets)
-func ReplacePayrollPbwFile(pbwFilePath string) error {
-	return os.WriteFile(pbwFilePath, getPbFile("a3_lohn.pbw"), 0o664)
+// ReplacePbwFile replaces the pbwFile (to get rid of other targets) with templateFile or, if templateFile is empty,
+// with the embedded file of the same name (e.g. a3_lohn.pbw).
+func ReplacePbwFile(pbwFilePath, templateFile string) error {
+	var data []byte
+	var err error
+	if templateFile == "" {
+		data, err = pbFiles.ReadFile("pb_files/" + filepath.Base(pbwFilePath))
+	} else {
+		data, err = readFile(templateFile)
+	}
+	if err != nil {
+		return fmt.Errorf("ReplacePbwFile failed: %v", err)
+	}
+	return writeFile(pbwFilePath, data, 0o664)
 }
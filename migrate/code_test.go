@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
+)
+
+func TestFixSqla17ByteString(t *testing.T) {
+	dir := t.TempDir()
+	libs := map[string]map[string]string{
+		filepath.Join(dir, "inf1.pbl"): {
+			"f_substr.srf": "ls_x = byte_substr(ls_a, 1, 2)\r\nls_y = Byte_Substr (ls_a, 1)\r\n",
+			"u_base.sru":   "ls_x = mid(ls_a, 1, 2)\r\n",
+		},
+		filepath.Join(dir, "fin1.pbl"): {
+			"u_calc.sru": "if true then ls_x=byte_substr(ls_a,2)\r\n",
+		},
+	}
+	for pbl := range libs {
+		// the libraries are searched in the folder
+		if err := os.WriteFile(pbl, nil, 0o664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake := backend.NewFake(libs)
+	sessions := orcapool.New(2, 0, func() (backend.Backend, error) {
+		return fake, nil
+	}, func(backend.Backend) {})
+
+	var warnings []string
+	err := FixSqla17ByteString(dir, "a3", sessions, func(msg string) {
+		warnings = append(warnings, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pbl, name, want string
+	}{
+		{"inf1.pbl", "f_substr.srf", "ls_x = substr(ls_a, 1, 2)\r\nls_y = Byte_Substr (ls_a, 1)\r\n"},
+		{"inf1.pbl", "u_base.sru", "ls_x = mid(ls_a, 1, 2)\r\n"},
+		{"fin1.pbl", "u_calc.sru", "if true then ls_x=substr(ls_a,2)\r\n"},
+	}
+	for _, tt := range tests {
+		got, _ := fake.Source(filepath.Join(dir, tt.pbl), tt.name)
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if len(fake.Writes()) != 2 {
+		t.Errorf("expected 2 changed objects, got %v", fake.Writes())
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
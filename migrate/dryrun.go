@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/textdiff"
+)
+
+// Kinds of changes recorded in a dry run
+const (
+	ChangeSource = "source" // an object source is changed or created
+	ChangeFile   = "file"   // a file is changed or created
+	ChangeDelete = "delete" // a file is deleted
+	ChangeAction = "action" // an action without a preview, e.g. the migration of the target
+)
+
+// Change is a change a fix would make.
+type Change struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`             // pbl or file
+	Object  string `json:"object,omitempty"` // object name for ChangeSource
+	Message string `json:"message,omitempty"`
+	Diff    string `json:"diff,omitempty"` // unified diff for ChangeSource and ChangeFile
+
+	old, new string
+	added    bool // the file or object did not exist before
+}
+
+// DryRun records the changes of the fixes instead of writing them. Sources and files changed in the dry run are
+// read back with their changed content, so fixes building on each other still see the changes.
+type DryRun struct {
+	Changes []*Change
+
+	sources map[string]*Change // by pbl and object name
+	files   map[string]*Change // by file path
+	mu      sync.Mutex         // fixes may run on several ORCA sessions in parallel (see orcapool)
+}
+
+// dryRun is the active dry run, nil if the changes are written.
+var dryRun *DryRun
+
+// StartDryRun makes all fixes record their changes in the returned DryRun instead of writing them.
+func StartDryRun() *DryRun {
+	dryRun = &DryRun{sources: make(map[string]*Change), files: make(map[string]*Change)}
+	return dryRun
+}
+
+// SkipInDryRun records an action which can not be previewed (e.g. the migration or the build of a target) and
+// returns true if a dry run is active, so the caller must skip it.
+func SkipInDryRun(format string, args ...any) bool {
+	if dryRun == nil {
+		return false
+	}
+	dryRun.mu.Lock()
+	defer dryRun.mu.Unlock()
+	dryRun.Changes = append(dryRun.Changes, &Change{Kind: ChangeAction, Message: fmt.Sprintf(format, args...)})
+	return true
+}
+
+// GetObjSource returns the source of an object. In a dry run, the changed source is returned if the object has
+// been changed before.
+func GetObjSource(o backend.Backend, pblFile, objName string) (string, error) {
+	if dryRun != nil {
+		dryRun.mu.Lock()
+		c, ok := dryRun.sources[sourceKey(pblFile, objName)]
+		var src string
+		if ok {
+			src = c.new
+		}
+		dryRun.mu.Unlock()
+		if ok {
+			return src, nil
+		}
+	}
+	return o.GetObjSource(pblFile, objName)
+}
+
+// SetObjSource imports the source of an object or records the change in a dry run.
+func SetObjSource(o backend.Backend, pbtFile, pblFile, objName string, src []byte) error {
+	if dryRun == nil {
+		return o.SetObjSource(pbtFile, pblFile, objName, src)
+	}
+	dryRun.mu.Lock()
+	defer dryRun.mu.Unlock()
+	key := sourceKey(pblFile, objName)
+	c, ok := dryRun.sources[key]
+	if !ok {
+		c = &Change{Kind: ChangeSource, Path: pblFile, Object: objName}
+		var err error
+		c.old, err = o.GetObjSource(pblFile, objName)
+		c.added = err != nil
+		dryRun.sources[key] = c
+		dryRun.Changes = append(dryRun.Changes, c)
+	}
+	c.new = string(src)
+	return nil
+}
+
+func sourceKey(pblFile, objName string) string {
+	return strings.ToLower(filepath.Clean(pblFile) + "/" + strings.TrimSuffix(objName, filepath.Ext(objName)))
+}
+
+// readFile reads a file, in a dry run with the changes made before.
+func readFile(path string) ([]byte, error) {
+	if dryRun != nil {
+		dryRun.mu.Lock()
+		defer dryRun.mu.Unlock()
+		if c, ok := dryRun.files[strings.ToLower(filepath.Clean(path))]; ok {
+			if c.Kind == ChangeDelete {
+				return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+			}
+			return []byte(c.new), nil
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// writeFile writes a file or records the change in a dry run.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if dryRun == nil {
+		return os.WriteFile(path, data, perm)
+	}
+	dryRun.mu.Lock()
+	defer dryRun.mu.Unlock()
+	c := dryRun.file(path)
+	c.Kind = ChangeFile
+	c.new = string(data)
+	return nil
+}
+
+// removeFile deletes a file or a folder with its content, or records the deletion in a dry run.
+func removeFile(path string) error {
+	if dryRun == nil {
+		return os.RemoveAll(path)
+	}
+	dryRun.mu.Lock()
+	defer dryRun.mu.Unlock()
+	c := dryRun.file(path)
+	if c.added {
+		// the file has been created in the dry run, so nothing changes at all
+		delete(dryRun.files, strings.ToLower(filepath.Clean(path)))
+		for i := range dryRun.Changes {
+			if dryRun.Changes[i] == c {
+				dryRun.Changes = append(dryRun.Changes[:i], dryRun.Changes[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+	c.Kind = ChangeDelete
+	return nil
+}
+
+// file returns the change of a file, it is created if the file has not been changed before. d.mu must be locked.
+func (d *DryRun) file(path string) *Change {
+	key := strings.ToLower(filepath.Clean(path))
+	c, ok := d.files[key]
+	if !ok {
+		c = &Change{Path: path}
+		// folders can not be read but exist
+		old, err := os.ReadFile(path)
+		c.old, c.added = string(old), errors.Is(err, fs.ErrNotExist)
+		d.files[key] = c
+		d.Changes = append(d.Changes, c)
+	}
+	return c
+}
+
+// Finish ends the dry run and computes the diffs of the changes.
+func (d *DryRun) Finish() {
+	if dryRun == d {
+		dryRun = nil
+	}
+	var changes []*Change
+	for _, c := range d.Changes {
+		switch c.Kind {
+		case ChangeSource:
+			if c.old == c.new {
+				continue
+			}
+			name := filepath.Base(c.Path) + "/" + c.Object
+			c.Diff = textdiff.Unified("a/"+name, "b/"+name, c.old, c.new, 3)
+			if c.added {
+				c.Message = "new object"
+			}
+		case ChangeFile:
+			if c.old == c.new {
+				continue
+			}
+			if !isText(c.old) || !isText(c.new) {
+				c.Message = fmt.Sprintf("binary file, %d bytes", len(c.new))
+			} else {
+				name := filepath.ToSlash(c.Path)
+				c.Diff = textdiff.Unified("a/"+name, "b/"+name, c.old, c.new, 3)
+			}
+			if c.added {
+				c.Message = strings.TrimPrefix(c.Message+", new file", ", ")
+			}
+		}
+		changes = append(changes, c)
+	}
+	d.Changes = changes
+}
+
+func isText(s string) bool {
+	return utf8.ValidString(s) && !strings.ContainsRune(s, 0)
+}
+
+// Unified returns the changes as unified diff. Changes without a diff are listed as comment lines.
+func (d *DryRun) Unified() string {
+	var sb strings.Builder
+	for _, c := range d.Changes {
+		switch {
+		case c.Kind == ChangeDelete:
+			fmt.Fprintf(&sb, "# delete %s\n", c.Path)
+		case c.Kind == ChangeAction:
+			fmt.Fprintf(&sb, "# %s\n", c.Message)
+		case c.Diff == "":
+			fmt.Fprintf(&sb, "# %s %s (%s)\n", c.Kind, c.Path, c.Message)
+		default:
+			sb.WriteString(c.Diff)
+		}
+	}
+	return sb.String()
+}
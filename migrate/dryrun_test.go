@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	changed := filepath.Join(dir, "changed.txt")
+	removed := filepath.Join(dir, "removed.txt")
+	for _, f := range []string{changed, removed} {
+		if err := os.WriteFile(f, []byte("a\nb\nc\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	added := filepath.Join(dir, "added.txt")
+	temp := filepath.Join(dir, "temp.txt")
+	folder := filepath.Join(dir, "pbdk", "sub.folder")
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	d := StartDryRun()
+	writeFile(changed, []byte("a\nx\nc\n"), 0o644)
+	if src, _ := readFile(changed); string(src) != "a\nx\nc\n" {
+		t.Errorf("readFile() does not return the changed content: %q", src)
+	}
+	removeFile(removed)
+	if _, err := readFile(removed); !os.IsNotExist(err) {
+		t.Errorf("readFile() of a removed file returns %v", err)
+	}
+	writeFile(added, []byte("new\n"), 0o644)
+	writeFile(temp, []byte("temp\n"), 0o644)
+	removeFile(temp)
+	removeFile(folder)
+	if !SkipInDryRun("migrate %s", "a.pbt") {
+		t.Errorf("SkipInDryRun() returns false in a dry run")
+	}
+	d.Finish()
+
+	if SkipInDryRun("migrate %s", "a.pbt") {
+		t.Errorf("SkipInDryRun() returns true after Finish()")
+	}
+	for _, f := range []string{changed, removed} {
+		if src, _ := os.ReadFile(f); string(src) != "a\nb\nc\n" {
+			t.Errorf("%s has been changed in the dry run: %q", f, src)
+		}
+	}
+	for _, f := range []string{added, temp} {
+		if _, err := os.Stat(f); err == nil {
+			t.Errorf("%s has been created in the dry run", f)
+		}
+	}
+	if _, err := os.Stat(folder); err != nil {
+		t.Errorf("%s has been removed in the dry run", folder)
+	}
+
+	if len(d.Changes) != 5 {
+		t.Fatalf("got %d changes, want 5", len(d.Changes))
+	}
+	wants := []struct{ kind, path, message string }{
+		{ChangeFile, changed, ""},
+		{ChangeDelete, removed, ""},
+		{ChangeFile, added, "new file"},
+		{ChangeDelete, folder, ""},
+		{ChangeAction, "", "migrate a.pbt"},
+	}
+	for i, want := range wants {
+		c := d.Changes[i]
+		if c.Kind != want.kind || c.Path != want.path || c.Message != want.message {
+			t.Errorf("change %d is %s %s (%s), want %s %s (%s)", i, c.Kind, c.Path, c.Message, want.kind, want.path, want.message)
+		}
+	}
+	if diff := d.Changes[0].Diff; !strings.Contains(diff, "-b\n+x\n") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	if u := d.Unified(); !strings.Contains(u, "# delete "+removed+"\n") || !strings.Contains(u, "# migrate a.pbt\n") {
+		t.Errorf("unexpected unified output:\n%s", u)
+	}
+
+	// without a dry run, folders are removed with their content
+	if err := removeFile(filepath.Dir(folder)); err != nil {
+		t.Errorf("removeFile() of a folder failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(folder)); !os.IsNotExist(err) {
+		t.Errorf("%s has not been removed", filepath.Dir(folder))
+	}
+}
+
+func TestDryRunParallel(t *testing.T) {
+	src := "release 22;\r\n" +
+		"column(band=detail id=1 alignment=\"2\" height=\"64\" name=a checkbox.text=\"Aktiv\" checkbox.on=\"Y\" )\r\n"
+	libs := make(map[string]map[string]string)
+	var libList []string
+	for i := 0; i < 32; i++ {
+		pbl := filepath.Join("C:/a3", fmt.Sprintf("lib%d.pbl", i))
+		libList = append(libList, pbl)
+		libs[pbl] = map[string]string{"d_a.srd": src, "d_b.srd": src, "d_c.srd": src}
+	}
+	fake := backend.NewFake(libs)
+	sessions := orcapool.New(4, 0, func() (backend.Backend, error) {
+		return fake, nil
+	}, func(backend.Backend) {})
+
+	d := StartDryRun()
+	err := FixDatawindows(&orca.Pbt{BasePath: "C:/a3", AppName: "a3", LibList: libList}, sessions, DwfixAll, printWarn)
+	d.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Changes) != 96 {
+		t.Errorf("expected 96 changes, got %d", len(d.Changes))
+	}
+	if len(fake.Writes()) != 0 {
+		t.Errorf("the dry run wrote %d objects", len(fake.Writes()))
+	}
+}
@@ -3,9 +3,13 @@ package migrate
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
-	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/datawindow"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
 	"github.com/informaticon/lib.go.base.pborca/orca"
 )
 
@@ -16,23 +20,30 @@ var DwfixAll = []func(string) (bool, string, string){
 }
 var DwfixFinScrollbar = []func(string) (bool, string, string){fixHorizontalScrollbarFin}
 
-// FixDatawindows moves datawindow checkboxes from centered to left aligned
-func FixDatawindows(pbtData *orca.Pbt, o *pborca.Orca, fncs []func(string) (bool, string, string), warnFunc func(string)) error {
+// FixDatawindows moves datawindow checkboxes from centered to left aligned. The libraries are fixed in parallel
+// on the sessions of the pool.
+func FixDatawindows(pbtData *orca.Pbt, sessions *orcapool.Pool[backend.Backend], fncs []func(string) (bool, string, string), warnFunc func(string)) error {
 	var errs []string
-	for _, pbl := range pbtData.LibList {
+	var mu sync.Mutex
+	addErr := func(err string) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	err := sessions.Run(pbtData.LibList, func(o backend.Backend, pbl string) error {
 		objs, err := o.GetObjList(pbl)
 		if err != nil {
-			errs = append(errs, fmt.Sprintf("could not export %s: %v", pbl, err))
-			continue
+			addErr(fmt.Sprintf("could not export %s: %v", pbl, err))
+			return nil
 		}
 		for _, objArr := range objs {
 			for _, obj := range objArr.GetObjArr() {
 				if obj.ObjType != orca.ObjType_DATAWINDOW {
 					continue
 				}
-				src, err := o.GetObjSource(pbl, obj.Name+".srd")
+				src, err := GetObjSource(o, pbl, obj.Name+".srd")
 				if err != nil {
-					errs = append(errs, fmt.Sprintf("could not get source of %s in %s: %v", obj.Name, pbl, err))
+					addErr(fmt.Sprintf("could not get source of %s in %s: %v", obj.Name, pbl, err))
 					continue
 				}
 				msgs := ""
@@ -49,13 +60,17 @@ func FixDatawindows(pbtData *orca.Pbt, o *pborca.Orca, fncs []func(string) (bool
 				}
 				if msgs != "" {
 					fmt.Printf("Fix Dw %s because of %s\n", obj.Name, msgs)
-					err = o.SetObjSource(pbtData.GetPath(), pbl, obj.Name, []byte(src))
+					err = SetObjSource(o, pbtData.GetPath(), pbl, obj.Name, []byte(src))
 					if err != nil {
-						errs = append(errs, fmt.Sprintf("could not write source of %s in %s: %v", obj.Name, pbl, err))
+						addErr(fmt.Sprintf("could not write source of %s in %s: %v", obj.Name, pbl, err))
 					}
 				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err.Error())
 	}
 	if len(errs) > 0 {
 		return fmt.Errorf("one or more error occured in FixDatawindows: %s", errs)
@@ -63,31 +78,43 @@ func FixDatawindows(pbtData *orca.Pbt, o *pborca.Orca, fncs []func(string) (bool
 	return nil
 }
 
-var regexDwCheckBoxAlign = regexp.MustCompile(`(?im)^(column.*alignment=")2(".*checkbox\.text="[^"])`)
-
+// fixCheckboxAlignment left aligns checkbox columns with a text, centered checkboxes look broken in PB2022.
 func fixCheckboxAlignment(src string) (bool, string, string) {
-	if !regexDwCheckBoxAlign.MatchString(src) {
+	dw, err := datawindow.Parse(src)
+	if err != nil {
 		return false, src, "CheckboxAlign"
 	}
-	return true, regexDwCheckBoxAlign.ReplaceAllString(src, `${1}0${2}`), "CheckboxAlign"
+	changed := false
+	for _, col := range dw.Objects("column") {
+		if col.Get("alignment") == "2" && col.Get("checkbox.text") != "" {
+			col.Set("alignment", "0")
+			changed = true
+		}
+	}
+	return changed, dw.String(), "CheckboxAlign"
 }
 
-var (
-	regexDwHorizontalScollbar1 = regexp.MustCompile(`(?im)^(column.* height="(?:[0-7][0-2]|[0-6][0-9])".* edit.autohscroll=yes.* )edit.hscrollbar=yes `)
-	regexDwHorizontalScollbar2 = regexp.MustCompile(`(?im)^(column.* height="(?:[0-7][0-2]|[0-6][0-9])".* )edit.hscrollbar=yes `)
-)
-
+// fixHorizontalScrollbar replaces the horizontal scrollbar of single line edit columns with autohscroll,
+// as the scrollbar hides the content of low columns.
 func fixHorizontalScrollbar(src string) (bool, string, string) {
-	ret := false
-	if regexDwHorizontalScollbar1.MatchString(src) {
-		ret = true
-		src = regexDwHorizontalScollbar1.ReplaceAllString(src, `${1}`)
+	dw, err := datawindow.Parse(src)
+	if err != nil {
+		return false, src, "HorizontalScroll"
 	}
-	if regexDwHorizontalScollbar2.MatchString(src) {
-		ret = true
-		src = regexDwHorizontalScollbar2.ReplaceAllString(src, `${1}edit.autohscroll=yes `)
+	changed := false
+	for _, col := range dw.Objects("column") {
+		height, err := strconv.Atoi(col.Get("height"))
+		if err != nil || height < 10 || height > 72 || col.Get("edit.hscrollbar") != "yes" {
+			continue
+		}
+		if col.Get("edit.autohscroll") == "yes" {
+			col.Remove("edit.hscrollbar")
+		} else {
+			col.Rename("edit.hscrollbar", "edit.autohscroll")
+		}
+		changed = true
 	}
-	return ret, src, "HorizontalScroll"
+	return changed, dw.String(), "HorizontalScroll"
 }
 
 // fixHorizontalScrollbarFin is a subset of FixDatawindows for projects which were already migrated
@@ -0,0 +1,74 @@
+//go:build windows && orca
+
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
+	"github.com/informaticon/dev.win.base.pbmanager/utils"
+	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/lib.go.base.pborca/orca"
+)
+
+// TestFixDatawindowsOrca runs the DataWindow fixes on the libraries of testdata/dwfix with ORCA, run it with
+// go test -tags orca on a machine with PowerBuilder.
+func TestFixDatawindowsOrca(t *testing.T) {
+	pbtData, err := orca.NewPbtFromFile(filepath.Join("testdata/dwfix/dwfix.pbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pbl := range pbtData.LibList {
+		utils.CopyFile(pbl+".vanilla.pbl", pbl)
+		defer os.Remove(pbl)
+	}
+
+	o, err := pborca.NewOrca(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	sessions := orcapool.New(2, 0, func() (backend.Backend, error) {
+		o, err := pborca.NewOrca(22)
+		if err != nil {
+			return nil, err
+		}
+		return o, nil
+	}, func(b backend.Backend) {
+		b.(*pborca.Orca).Close()
+	})
+	err = FixDatawindows(pbtData, sessions, DwfixAll, printWarn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pbl := range pbtData.LibList {
+		objs, err := o.GetObjList(pbl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, objArr := range objs {
+			for _, obj := range objArr.GetObjArr() {
+				if obj.ObjType != orca.ObjType_DATAWINDOW {
+					continue
+				}
+				new, err := o.GetObjSource(pbl, obj.GetName()+".srd")
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := o.GetObjSource(pbl+".want.pbl", obj.GetName()+".srd")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if new != want {
+					t.Errorf("obj %s wasn't changed as expected", obj.GetName())
+				}
+			}
+		}
+	}
+}
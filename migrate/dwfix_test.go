@@ -2,63 +2,89 @@ package migrate
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"testing"
 
-	"github.com/informaticon/dev.win.base.pbmanager/utils"
-	pborca "github.com/informaticon/lib.go.base.pborca"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+	"github.com/informaticon/dev.win.base.pbmanager/internal/orcapool"
 	"github.com/informaticon/lib.go.base.pborca/orca"
 )
 
 func TestFixDatawindows(t *testing.T) {
-	pbtData, err := orca.NewPbtFromFile(filepath.Join("testdata/dwfix/dwfix.pbt"))
-	if err != nil {
-		t.Fatal(err)
-	}
+	src := "release 22;\r\n" +
+		"column(band=detail id=1 alignment=\"2\" height=\"64\" name=a checkbox.text=\"Aktiv\" checkbox.on=\"Y\" )\r\n" +
+		"column(band=detail id=4 alignment=\"0\" height=\"64\" name=d edit.hscrollbar=yes  font.face=\"Arial\" )\r\n"
+	want := "release 22;\r\n" +
+		"column(band=detail id=1 alignment=\"0\" height=\"64\" name=a checkbox.text=\"Aktiv\" checkbox.on=\"Y\" )\r\n" +
+		"column(band=detail id=4 alignment=\"0\" height=\"64\" name=d edit.autohscroll=yes  font.face=\"Arial\" )\r\n"
+	fixed := "release 22;\r\n" +
+		"column(band=detail id=2 alignment=\"2\" height=\"64\" name=b checkbox.text=\"\" checkbox.on=\"Y\" )\r\n"
+	// not a datawindow, must not be touched
+	userObj := "forward\r\nglobal type u_base from nonvisualobject\r\nend type\r\n// alignment=\"2\" checkbox.text=\"x\"\r\n"
 
-	for _, pbl := range pbtData.LibList {
-		utils.CopyFile(pbl+".vanilla.pbl", pbl)
-		defer os.Remove(pbl)
-	}
+	pbtData := &orca.Pbt{BasePath: "C:/a3", AppName: "dwfix", LibList: []string{"C:/a3/dwfix.pbl", "C:/a3/drucken2.pbl"}}
+	fake := backend.NewFake(map[string]map[string]string{
+		"C:/a3/dwfix.pbl":    {"d_fix.srd": src, "d_fixed.srd": fixed, "u_base.sru": userObj},
+		"C:/a3/drucken2.pbl": {"d_print.srd": src},
+	})
+	sessions := orcapool.New(2, 0, func() (backend.Backend, error) {
+		return fake, nil
+	}, func(backend.Backend) {})
 
-	o, err := pborca.NewOrca(22)
+	err := FixDatawindows(pbtData, sessions, DwfixAll, printWarn)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer o.Close()
 
-	err = FixDatawindows(pbtData, o, DwfixAll, printWarn)
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		pbl, name, want string
+	}{
+		{"C:/a3/dwfix.pbl", "d_fix.srd", want},
+		{"C:/a3/dwfix.pbl", "d_fixed.srd", fixed},
+		{"C:/a3/dwfix.pbl", "u_base.sru", userObj},
+		{"C:/a3/drucken2.pbl", "d_print.srd", want},
 	}
-
-	for _, pbl := range pbtData.LibList {
-		objs, err := o.GetObjList(pbl)
-		if err != nil {
-			t.Fatal(err)
-		}
-		for _, objArr := range objs {
-			for _, obj := range objArr.GetObjArr() {
-				if obj.ObjType != orca.ObjType_DATAWINDOW {
-					continue
-				}
-				new, err := o.GetObjSource(pbl, obj.GetName()+".srd")
-				if err != nil {
-					t.Fatal(err)
-				}
-				want, err := o.GetObjSource(pbl+".want.pbl", obj.GetName()+".srd")
-				if err != nil {
-					t.Fatal(err)
-				}
-				if new != want {
-					t.Errorf("obj %s wasn't changed as expected", obj.GetName())
-				}
-			}
+	for _, tt := range tests {
+		got, _ := fake.Source(tt.pbl, tt.name)
+		if got != tt.want {
+			t.Errorf("%s wasn't changed as expected:\n%s", tt.name, got)
 		}
 	}
+	if writes := fake.Writes(); len(writes) != 2 {
+		t.Errorf("expected 2 changed objects, got %d", len(writes))
+	}
 }
 
 func printWarn(message string) {
 	fmt.Println("WARN: ", message)
 }
+
+func TestFixDwColumns(t *testing.T) {
+	src := "release 22;\r\n" +
+		"column(band=detail id=1 alignment=\"2\" height=\"64\" name=a checkbox.text=\"Aktiv\" checkbox.on=\"Y\" )\r\n" +
+		"column(band=detail id=2 alignment=\"2\" height=\"64\" name=b checkbox.text=\"\" checkbox.on=\"Y\" )\r\n" +
+		"column(band=detail id=3 alignment=\"0\" height=\"64\" name=c edit.autohscroll=yes edit.hscrollbar=yes  font.face=\"Arial\" )\r\n" +
+		"column(band=detail id=4 alignment=\"0\" height=\"64\" name=d edit.hscrollbar=yes  font.face=\"Arial\" )\r\n" +
+		"column(band=detail id=5 alignment=\"0\" height=\"304\" name=e edit.hscrollbar=yes  font.face=\"Arial\" )\r\n"
+	want := "release 22;\r\n" +
+		"column(band=detail id=1 alignment=\"0\" height=\"64\" name=a checkbox.text=\"Aktiv\" checkbox.on=\"Y\" )\r\n" +
+		"column(band=detail id=2 alignment=\"2\" height=\"64\" name=b checkbox.text=\"\" checkbox.on=\"Y\" )\r\n" +
+		"column(band=detail id=3 alignment=\"0\" height=\"64\" name=c edit.autohscroll=yes  font.face=\"Arial\" )\r\n" +
+		"column(band=detail id=4 alignment=\"0\" height=\"64\" name=d edit.autohscroll=yes  font.face=\"Arial\" )\r\n" +
+		"column(band=detail id=5 alignment=\"0\" height=\"304\" name=e edit.hscrollbar=yes  font.face=\"Arial\" )\r\n"
+
+	changed, got, _ := fixCheckboxAlignment(src)
+	if !changed {
+		t.Errorf("fixCheckboxAlignment did not change anything")
+	}
+	changed, got, _ = fixHorizontalScrollbar(got)
+	if !changed {
+		t.Errorf("fixHorizontalScrollbar did not change anything")
+	}
+	if got != want {
+		t.Errorf("unexpected result:\n%s", got)
+	}
+	changed, _, _ = fixHorizontalScrollbar(got)
+	if changed {
+		t.Errorf("fixHorizontalScrollbar changed already fixed source")
+	}
+}
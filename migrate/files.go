@@ -11,8 +11,8 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
 	"github.com/informaticon/dev.win.base.pbmanager/utils"
-	pborca "github.com/informaticon/lib.go.base.pborca"
 	"github.com/informaticon/lib.go.base.pborca/orca"
 )
 
@@ -40,16 +40,22 @@ func RemoveFiles(folder string, warnFunc func(string)) error {
 			continue
 		}
 
-		err := os.Remove(filepath.Join(folder, line))
+		err := removeFile(filepath.Join(folder, line))
 		if err != nil {
 			return fmt.Errorf("RemoveFiles failed: %v", err)
 		}
 	}
 
-	err := utils.RemoveGlob(fmt.Sprintf("%s/*.*", filepath.Join(folder, "pbdk")))
+	pbdkFiles, err := filepath.Glob(fmt.Sprintf("%s/*.*", filepath.Join(folder, "pbdk")))
 	if err != nil {
 		return fmt.Errorf("RemoveFiles failed: %v", err)
 	}
+	for _, file := range pbdkFiles {
+		err = removeFile(file)
+		if err != nil {
+			return fmt.Errorf("RemoveFiles failed: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -83,7 +89,7 @@ func FixPbInit(folder string, warnFunc func(string)) error {
 	if !utils.FileExists(file) {
 		return nil
 	}
-	src, err := os.ReadFile(file)
+	src, err := readFile(file)
 	if err != nil {
 		return fmt.Errorf("FixPbInit failed: %v", err)
 	}
@@ -110,7 +116,7 @@ func FixPbInit(folder string, warnFunc func(string)) error {
 	}
 	src = append(src, valAccessibility[2]...)
 
-	err = os.WriteFile(file, src, 0o664)
+	err = writeFile(file, src, 0o664)
 	if err != nil {
 		return fmt.Errorf("FixPbInit failed: %v", err)
 	}
@@ -121,6 +127,9 @@ func InsertNewPbdk(libFolder string) error {
 	if !utils.FileExists(filepath.Join(libFolder, "pbdk")) {
 		return nil
 	}
+	if SkipInDryRun("replace the content of %s with %s", filepath.Join(libFolder, "pbdk"), urlPbdk) {
+		return nil
+	}
 
 	pbdkZipFile, err := utils.GetRessource(urlPbdk)
 	if err != nil {
@@ -171,18 +180,20 @@ func InsertNewPbdk(libFolder string) error {
 
 func InsertNewPbdom(pbt *orca.Pbt) error {
 	libFolder, appName := pbt.BasePath, pbt.AppName
-	pbdomFile, err := utils.GetRessource(urlPbdom)
-	if err != nil {
-		return fmt.Errorf("InsertNewPbdom failed: %v", err)
-	}
 	dstFileName := filepath.Join(libFolder, "pbdom.pbl")
-	err = utils.CopyFile(pbdomFile, dstFileName)
-	if err != nil {
-		return fmt.Errorf("InsertNewPbdom failed: %v", err)
+	if !SkipInDryRun("copy %s to %s", urlPbdom, dstFileName) {
+		pbdomFile, err := utils.GetRessource(urlPbdom)
+		if err != nil {
+			return fmt.Errorf("InsertNewPbdom failed: %v", err)
+		}
+		err = utils.CopyFile(pbdomFile, dstFileName)
+		if err != nil {
+			return fmt.Errorf("InsertNewPbdom failed: %v", err)
+		}
 	}
 
 	pbtFilePath := filepath.Join(libFolder, appName+".pbt")
-	pbtData, err := os.ReadFile(pbtFilePath)
+	pbtData, err := readFile(pbtFilePath)
 	if err != nil {
 		return fmt.Errorf("InsertNewPbdom failed: %v", err)
 	}
@@ -192,7 +203,7 @@ func InsertNewPbdom(pbt *orca.Pbt) error {
 	// add new pbdom
 	pbtData = regexp.MustCompile(`(?mi)^(LibList[ \t]+".*?)";`).ReplaceAll(pbtData, []byte(`$1;pbdom.pbl";`))
 
-	err = os.WriteFile(pbtFilePath, pbtData, 0o664)
+	err = writeFile(pbtFilePath, pbtData, 0o664)
 	if err != nil {
 		return fmt.Errorf("InsertNewPbdom failed: %v", err)
 	}
@@ -207,8 +218,8 @@ func InsertNewPbdom(pbt *orca.Pbt) error {
 }
 
 // InsertExfInPbt adds exf1.pbl to the library list, if it's needed
-func InsertExfInPbt(pbt *orca.Pbt, Orca *pborca.Orca) error {
-	src, err := Orca.GetObjSource(pbt.AppLib, pbt.AppName+".sra")
+func InsertExfInPbt(pbt *orca.Pbt, Orca backend.Backend) error {
+	src, err := GetObjSource(Orca, pbt.AppLib, pbt.AppName+".sra")
 	if err != nil {
 		return nil
 	}
@@ -225,7 +236,7 @@ func InsertExfInPbt(pbt *orca.Pbt, Orca *pborca.Orca) error {
 	pbt.LibList = append(pbt.LibList, filepath.Join(pbt.BasePath, "exf1.pbl"))
 
 	// Fix lib list in pbt file
-	pbtData, err := os.ReadFile(filepath.Join(pbt.BasePath, pbt.AppName+".pbt"))
+	pbtData, err := readFile(filepath.Join(pbt.BasePath, pbt.AppName+".pbt"))
 	if err != nil {
 		return fmt.Errorf("InsertExfInPbt failed: %v", err)
 	}
@@ -233,7 +244,7 @@ func InsertExfInPbt(pbt *orca.Pbt, Orca *pborca.Orca) error {
 	// add new pbdom
 	pbtData = regexp.MustCompile(`(?mi)^(LibList[ \t]+".*?)(;inf3.pbl;.*?")`).ReplaceAll(pbtData, []byte(`${1};exf1.pbl${2}`))
 
-	err = os.WriteFile(filepath.Join(pbt.BasePath, pbt.AppName+".pbt"), pbtData, 0o664)
+	err = writeFile(filepath.Join(pbt.BasePath, pbt.AppName+".pbt"), pbtData, 0o664)
 	if err != nil {
 		return fmt.Errorf("InsertExfInPbt failed: %v", err)
 	}
@@ -0,0 +1,216 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status of a step in the journal
+const (
+	StepRunning = "running" // the step has been started, but did not return (e.g. crash or kill)
+	StepDone    = "done"
+	StepFailed  = "failed"
+)
+
+// JournalStep is a step of an upgrade as recorded in the journal.
+type JournalStep struct {
+	Name           string    `json:"name"`
+	Status         string    `json:"status"`
+	InputChecksum  string    `json:"inputChecksum"`            // checksum of the workspace before the step
+	OutputChecksum string    `json:"outputChecksum,omitempty"` // checksum of the workspace after the step
+	Started        time.Time `json:"started"`
+	Finished       time.Time `json:"finished,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Journal records the finished steps of an upgrade, so an upgrade which failed can be resumed at the failed
+// step. It is stored next to the pbt file and written after every step.
+type Journal struct {
+	Target   string         `json:"target"`
+	Steps    []*JournalStep `json:"steps"`
+	TempLibs []string       `json:"tempLibs,omitempty"` // libraries added temporarily (see Libs3rd)
+
+	path   string
+	inputs func() ([]string, error)
+}
+
+// JournalPath returns the path of the journal of a target, e.g. C:/a3/lib/a3.upgrade.json for C:/a3/lib/a3.pbt.
+func JournalPath(pbtFile string) string {
+	return strings.TrimSuffix(pbtFile, filepath.Ext(pbtFile)) + ".upgrade.json"
+}
+
+// NewJournal starts a new journal for the target, an existing journal is overwritten by the first step.
+// inputs returns the files which make up the workspace (e.g. the pbt and its libraries), their checksum is
+// recorded before and after every step.
+func NewJournal(pbtFile string, inputs func() ([]string, error)) *Journal {
+	return &Journal{Target: pbtFile, path: JournalPath(pbtFile), inputs: inputs}
+}
+
+// LoadJournal reads the journal of the target.
+func LoadJournal(pbtFile string, inputs func() ([]string, error)) (*Journal, error) {
+	path := JournalPath(pbtFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read upgrade journal: %v", err)
+	}
+	j := &Journal{path: path, inputs: inputs}
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse upgrade journal %s: %v", path, err)
+	}
+	return j, nil
+}
+
+// Step returns the recorded step with the given name or nil.
+func (j *Journal) Step(name string) *JournalStep {
+	for _, s := range j.Steps {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Run executes a step and records the result. Steps which are done already are skipped.
+func (j *Journal) Run(name string, step func() error) error {
+	s := j.Step(name)
+	if s != nil && s.Status == StepDone {
+		fmt.Printf("Skipping step %s (done %s)\n", name, s.Finished.Format(time.DateTime))
+		return nil
+	}
+	if s == nil {
+		s = &JournalStep{Name: name}
+		j.Steps = append(j.Steps, s)
+	}
+	checksum, err := j.checksum()
+	if err != nil {
+		return err
+	}
+	*s = JournalStep{Name: name, Status: StepRunning, InputChecksum: checksum, Started: time.Now()}
+	err = j.Save()
+	if err != nil {
+		return err
+	}
+
+	stepErr := step()
+
+	s.Finished = time.Now()
+	s.Status = StepDone
+	if stepErr != nil {
+		s.Status = StepFailed
+		s.Error = stepErr.Error()
+	}
+	s.OutputChecksum, err = j.checksum()
+	if err != nil {
+		return err
+	}
+	err = j.Save()
+	if err != nil {
+		return err
+	}
+	return stepErr
+}
+
+// CheckResume verifies that the workspace has not been changed since the last recorded step, i.e. the upgrade
+// can be resumed. If the last step did not finish (e.g. pbmanager or ORCA crashed), the state of the workspace is
+// unknown and the upgrade is only resumed with force, after a warning.
+func (j *Journal) CheckResume(force bool, warnFunc func(string)) error {
+	if len(j.Steps) == 0 {
+		return nil
+	}
+	last := j.Steps[len(j.Steps)-1]
+	if last.Status == StepRunning {
+		if !force {
+			return fmt.Errorf("step %s did not finish, the state of the workspace is unknown (restore the workspace and rerun the upgrade without --resume, or use --force to resume anyway)", last.Name)
+		}
+		warnFunc(fmt.Sprintf("step %s did not finish, resuming although the state of the workspace is unknown", last.Name))
+		return nil
+	}
+	checksum, err := j.checksum()
+	if err != nil {
+		return err
+	}
+	if checksum != last.OutputChecksum {
+		return fmt.Errorf("the workspace has been changed since step %s, resuming is not possible (rerun the upgrade without --resume)", last.Name)
+	}
+	return nil
+}
+
+// Done returns true if all steps are done.
+func (j *Journal) Done(steps []string) bool {
+	for _, name := range steps {
+		if s := j.Step(name); s == nil || s.Status != StepDone {
+			return false
+		}
+	}
+	return true
+}
+
+// Save writes the journal.
+func (j *Journal) Save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(j.path, data, 0o664)
+	if err != nil {
+		return fmt.Errorf("could not write upgrade journal: %v", err)
+	}
+	return nil
+}
+
+// Status returns the progress of the upgrade, one line per step. Steps which are not recorded are pending.
+func (j *Journal) Status(steps []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Upgrade of %s\n", j.Target)
+	for _, name := range steps {
+		s := j.Step(name)
+		if s == nil {
+			fmt.Fprintf(&sb, "  %-8s %s\n", "pending", name)
+			continue
+		}
+		fmt.Fprintf(&sb, "  %-8s %s", s.Status, name)
+		if !s.Finished.IsZero() {
+			fmt.Fprintf(&sb, " (%s, %s)", s.Finished.Format(time.DateTime), s.Finished.Sub(s.Started).Round(time.Second))
+		}
+		sb.WriteString("\n")
+		if s.Error != "" {
+			fmt.Fprintf(&sb, "           %s\n", strings.ReplaceAll(strings.TrimSpace(s.Error), "\n", "\n           "))
+		}
+	}
+	return sb.String()
+}
+
+// checksum returns a checksum over the names and contents of the input files. Missing files are part of the
+// checksum too.
+func (j *Journal) checksum() (string, error) {
+	files, err := j.inputs()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s\x00", strings.ToLower(filepath.Clean(file)))
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			h.Write([]byte("missing\x00"))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
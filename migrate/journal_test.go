@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	pbtFile := filepath.Join(dir, "a3.pbt")
+	libFile := filepath.Join(dir, "inf1.pbl")
+	for _, f := range []string{pbtFile, libFile} {
+		if err := os.WriteFile(f, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inputs := func() ([]string, error) { return []string{pbtFile, libFile}, nil }
+	steps := []string{"one", "two", "three"}
+
+	var ran []string
+	run := func(j *Journal) error {
+		for _, name := range steps {
+			err := j.Run(name, func() error {
+				ran = append(ran, name)
+				if name == "two" && len(ran) == 2 {
+					os.WriteFile(libFile, []byte("half migrated"), 0o644)
+					return fmt.Errorf("step two failed")
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := run(NewJournal(pbtFile, inputs))
+	if err == nil || err.Error() != "step two failed" {
+		t.Fatalf("expected step two to fail, got %v", err)
+	}
+
+	j, err := LoadJournal(pbtFile, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Done(steps) {
+		t.Errorf("Done() returns true for a failed upgrade")
+	}
+	status := j.Status(steps)
+	for _, want := range []string{"  done     one", "  failed   two", "step two failed", "  pending  three"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("status does not contain %q:\n%s", want, status)
+		}
+	}
+	if err := j.CheckResume(false, nil); err != nil {
+		t.Errorf("CheckResume() failed: %v", err)
+	}
+
+	err = run(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ran, ",") != "one,two,two,three" {
+		t.Errorf("unexpected steps run: %v", ran)
+	}
+	if !j.Done(steps) {
+		t.Errorf("Done() returns false for a finished upgrade")
+	}
+
+	os.WriteFile(libFile, []byte("changed"), 0o644)
+	if err := j.CheckResume(false, nil); err == nil {
+		t.Errorf("CheckResume() does not detect the changed workspace")
+	}
+
+	// a step which did not return leaves the workspace in an unknown state
+	crashed := NewJournal(pbtFile, inputs)
+	crashed.Steps = []*JournalStep{{Name: "one", Status: StepRunning}}
+	if err := crashed.CheckResume(false, nil); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("CheckResume() of a crashed step returns %v, want an error asking for --force", err)
+	}
+	var warnings []string
+	if err := crashed.CheckResume(true, func(s string) { warnings = append(warnings, s) }); err != nil || len(warnings) != 1 {
+		t.Errorf("CheckResume() with force returns %v and warnings %v", err, warnings)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"slices"
 
+	"github.com/informaticon/dev.win.base.pbmanager/internal/pbl"
 	"github.com/informaticon/lib.go.base.pborca/orca"
 )
 
@@ -32,14 +33,18 @@ func (l *Libs3rd) AddMissingLibs(pbtData *orca.Pbt) error {
 			switch file {
 			case "pbdom170.pbl", "pbdom115.pbl":
 				fmt.Printf("  add missing pbl %s\n", filepath.Base(lib))
-				err = os.WriteFile(lib, getPbFile(file), 0o664)
+				err = writeFile(lib, getPbFile(file), 0o664)
 			case "exf1.pbl", "grp1.pbl", "liq1.pbl",
 				"net1.pbl", "str1.pbl", "sfi2.pbl":
-				err = os.WriteFile(lib, getPbFile(file), 0o664)
+				err = writeFile(lib, getPbFile(file), 0o664)
 				fmt.Printf("  temporarly add missing pbl %s\n", filepath.Base(lib))
 				l.copiedFiles = append(l.copiedFiles, lib)
 			default:
-				err = os.WriteFile(lib, getPbFile("empty.pbl"), 0o664)
+				var data []byte
+				data, err = pbl.New("").Marshal()
+				if err == nil {
+					err = writeFile(lib, data, 0o664)
+				}
 				fmt.Printf("  temporarly add empty pbl %s to meet the requirements of the target\n", filepath.Base(lib))
 				l.copiedFiles = append(l.copiedFiles, lib)
 			}
@@ -53,9 +58,20 @@ func (l *Libs3rd) AddMissingLibs(pbtData *orca.Pbt) error {
 	return nil
 }
 
+// CopiedFiles returns the libraries which have been added temporarily.
+func (l *Libs3rd) CopiedFiles() []string {
+	return slices.Clone(l.copiedFiles)
+}
+
+// AddCopiedFiles adds libraries which have been added temporarily before (e.g. by an interrupted upgrade), so
+// CleanupLibs removes them too.
+func (l *Libs3rd) AddCopiedFiles(files ...string) {
+	l.copiedFiles = append(l.copiedFiles, files...)
+}
+
 func (l *Libs3rd) CleanupLibs() error {
 	for len(l.copiedFiles) > 0 {
-		err := os.Remove(l.copiedFiles[len(l.copiedFiles)-1])
+		err := removeFile(l.copiedFiles[len(l.copiedFiles)-1])
 		if err != nil {
 			return fmt.Errorf("CleanupLibs failed: %v", err)
 		}
@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/informaticon/dev.win.base.pbmanager/internal/backend"
+)
+
+// Patch is a declarative source fix: the matches of Match in the source of an object are replaced by Replace.
+// Patches can be compiled in (see FixSqla17Base) or loaded from a patch file (see LoadPatchFile).
+type Patch struct {
+	Name    string `json:"name"`
+	Library string `json:"library"` // pbl file relative to the folder of the target, e.g. inf1.pbl
+	Object  string `json:"object"`  // object name without extension, e.g. inf1_u_transaction
+	Check   string `json:"check"`   // regex, if it matches the source, the patch has already been applied
+	Match   string `json:"match"`   // regex of the text to replace
+	Replace string `json:"replace"` // replacement, ${1} refers to the first group of Match
+	Expect  int    `json:"expect"`  // expected number of matches, 0 means at least one
+	// Optional patches are skipped instead of failing if Match does not match, e.g. for code that only exists
+	// in some versions of an object
+	Optional bool `json:"optional"`
+
+	rxCheck *regexp.Regexp
+	rxMatch *regexp.Regexp
+}
+
+// PatchFile is the content of a patch file, e.g.:
+//
+//	{
+//	  "patches": [
+//	    {
+//	      "name": "FIX4",
+//	      "library": "jif1.pbl",
+//	      "object": "jif1_u_jif_master",
+//	      "check": "(?is)//SQLA17 migration - FIX4:",
+//	      "match": "(?is)(choose[ \\t]+case[ \\t]+ls_sa_major_version[ \\t\\r\\n]+case[ \\t]+)\"[,\"167 ]+\"",
+//	      "replace": "//SQLA17 migration - FIX4: allow SQLA17\r\n${1}'11', '16', '17'",
+//	      "expect": 1
+//	    }
+//	  ]
+//	}
+type PatchFile struct {
+	Patches []*Patch `json:"patches"`
+}
+
+// Status of an applied patch
+const (
+	PatchApplied = "applied"
+	PatchSkipped = "skipped"
+	PatchFailed  = "failed"
+)
+
+// PatchResult is the outcome of a single patch.
+type PatchResult struct {
+	Name    string
+	Status  string // one of PatchApplied, PatchSkipped, PatchFailed
+	Message string
+}
+
+// LoadPatchFile reads and validates a patch file.
+func LoadPatchFile(path string) ([]*Patch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pf PatchFile
+	err = json.Unmarshal(data, &pf)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse patch file %s: %v", path, err)
+	}
+	for i, p := range pf.Patches {
+		err = p.compile()
+		if err != nil {
+			return nil, fmt.Errorf("patch file %s, patch %d: %v", path, i+1, err)
+		}
+	}
+	return pf.Patches, nil
+}
+
+func (p *Patch) compile() (err error) {
+	if p.Name == "" || p.Library == "" || p.Object == "" || p.Match == "" {
+		return fmt.Errorf("name, library, object and match are required")
+	}
+	if p.Expect < 0 {
+		return fmt.Errorf("%s: expect must not be negative", p.Name)
+	}
+	if p.Check != "" {
+		p.rxCheck, err = regexp.Compile(p.Check)
+		if err != nil {
+			return fmt.Errorf("%s: invalid check: %v", p.Name, err)
+		}
+	}
+	p.rxMatch, err = regexp.Compile(p.Match)
+	if err != nil {
+		return fmt.Errorf("%s: invalid match: %v", p.Name, err)
+	}
+	return nil
+}
+
+// Apply applies the patch to src. It returns the new source and PatchApplied, the unchanged source and
+// PatchSkipped if the check matches or an optional patch does not match, or an error if the number of matches
+// is not as expected.
+func (p *Patch) Apply(src string) (string, string, error) {
+	if p.rxMatch == nil {
+		err := p.compile()
+		if err != nil {
+			return src, PatchFailed, err
+		}
+	}
+	if p.applied(src) {
+		return src, PatchSkipped, nil
+	}
+	n := len(p.rxMatch.FindAllStringIndex(src, -1))
+	if n == 0 && p.Optional {
+		return src, PatchSkipped, nil
+	}
+	if n == 0 || (p.Expect > 0 && n != p.Expect) {
+		expected := "at least 1"
+		if p.Expect > 0 {
+			expected = fmt.Sprint(p.Expect)
+		}
+		return src, PatchFailed, fmt.Errorf("found %d matches, expected %s", n, expected)
+	}
+	return p.rxMatch.ReplaceAllString(src, p.Replace), PatchApplied, nil
+}
+
+// applied reports whether the check of the patch matches src.
+func (p *Patch) applied(src string) bool {
+	return p.rxCheck != nil && p.rxCheck.MatchString(src)
+}
+
+// ApplyPatches applies the patches to the objects of the target in libFolder. Patches of the same object are
+// applied one after another and the object is written once. The result of every patch is returned, a failing
+// patch does not stop the others.
+func ApplyPatches(libFolder string, targetName string, patches []*Patch, orca backend.Backend) []PatchResult {
+	pbtFile := filepath.Join(libFolder, targetName+".pbt")
+	results := make([]PatchResult, len(patches))
+
+	// group the patches by object, keeping the order of the first patch of every object
+	type object struct {
+		pblFile string
+		name    string
+		patches []int
+	}
+	var objects []*object
+	byKey := make(map[string]*object)
+	for i, p := range patches {
+		key := p.Library + "/" + p.Object
+		if byKey[key] == nil {
+			byKey[key] = &object{pblFile: filepath.Join(libFolder, p.Library), name: p.Object}
+			objects = append(objects, byKey[key])
+		}
+		byKey[key].patches = append(byKey[key].patches, i)
+	}
+
+	for _, obj := range objects {
+		src, err := GetObjSource(orca, obj.pblFile, obj.name)
+		if err != nil {
+			for _, i := range obj.patches {
+				results[i] = PatchResult{patches[i].Name, PatchFailed,
+					fmt.Sprintf("%s does not contain an object named %s: %v", filepath.Base(obj.pblFile), obj.name, err)}
+			}
+			continue
+		}
+		changed := false
+		for _, i := range obj.patches {
+			newSrc, status, err := patches[i].Apply(src)
+			results[i] = PatchResult{Name: patches[i].Name, Status: status}
+			switch {
+			case err != nil:
+				results[i].Message = fmt.Sprintf("%s: %v", obj.name, err)
+			case status == PatchSkipped && patches[i].applied(src):
+				results[i].Message = "already applied"
+			case status == PatchSkipped:
+				results[i].Message = "no match"
+			default:
+				src = newSrc
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		err = SetObjSource(orca, pbtFile, obj.pblFile, obj.name, []byte(src))
+		if err != nil {
+			for _, i := range obj.patches {
+				if results[i].Status == PatchApplied {
+					results[i].Status = PatchFailed
+					results[i].Message = fmt.Sprintf("could not write source of %s: %v", obj.name, err)
+				}
+			}
+		}
+	}
+	return results
+}
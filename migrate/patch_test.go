@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadPatchFile(t *testing.T) {
+	patches, err := LoadPatchFile("testdata/patches/sqla17.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 2 || patches[0].Name != "FIX4" || patches[0].Expect != 1 || patches[1].Check != "" {
+		t.Fatalf("unexpected patches: %+v", patches)
+	}
+
+	_, err = LoadPatchFile("testdata/patches/invalid.json")
+	if err == nil || !strings.Contains(err.Error(), "broken: invalid match") {
+		t.Errorf("expected invalid match error, got %v", err)
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	patches, err := LoadPatchFile("testdata/patches/sqla17.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	optional := &Patch{Name: "optional", Library: "inf1.pbl", Object: "inf1_u_http", Match: "il_timeout = 30", Optional: true}
+	src := "choose case ls_sa_major_version\r\n\tcase \"11\", \"16\"\r\n\t\treturn 1\r\nend choose\r\n"
+
+	tests := []struct {
+		name       string
+		patch      *Patch
+		src        string
+		wantStatus string
+		wantSrc    string
+	}{
+		{"applied", patches[0], src, PatchApplied,
+			"//SQLA17 migration - FIX4: allow SQLA17\r\nchoose case ls_sa_major_version\r\n\tcase '11', '16', '17'\r\n\t\treturn 1\r\nend choose\r\n"},
+		{"already applied", patches[0], "//SQLA17 migration - FIX4: allow SQLA17\r\n" + src, PatchSkipped, ""},
+		{"no match", patches[1], src, PatchFailed, ""},
+		{"unexpected count", patches[0], src + src, PatchFailed, ""},
+		{"optional no match", optional, src, PatchSkipped, ""},
+		{"all matches", patches[1], "il_timeout = 30\nil_timeout = 30\n", PatchApplied, "il_timeout = 60\nil_timeout = 60\n"},
+	}
+	for _, tt := range tests {
+		got, status, err := tt.patch.Apply(tt.src)
+		if status != tt.wantStatus || (status == PatchFailed) != (err != nil) {
+			t.Errorf("%s: got status %s (%v), expected %s", tt.name, status, err, tt.wantStatus)
+			continue
+		}
+		if tt.wantSrc != "" && got != tt.wantSrc {
+			t.Errorf("%s: got source %q, expected %q", tt.name, got, tt.wantSrc)
+		}
+	}
+}